@@ -19,6 +19,8 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type CollaSetConditionType string
@@ -26,6 +28,14 @@ type CollaSetConditionType string
 const (
 	CollaSetScale  CollaSetConditionType = "Scale"
 	CollaSetUpdate CollaSetConditionType = "Update"
+	CollaSetPaused CollaSetConditionType = "Paused"
+	// CollaSetInPlaceUpdateUnsupported indicates there are Pods not able to be updated in-place with InPlaceOnly policy.
+	CollaSetInPlaceUpdateUnsupported CollaSetConditionType = "InPlaceUpdateUnsupported"
+	// CollaSetProgressing indicates whether the updating of CollaSet is progressing, or fails to progress.
+	CollaSetProgressing CollaSetConditionType = "Progressing"
+	// CollaSetIDAllocation indicates there are not enough instance IDs to allocate, like the ones in conflict with
+	// other CollaSets sharing the same Context.
+	CollaSetIDAllocation CollaSetConditionType = "IDAllocation"
 )
 
 // PersistentVolumeClaimRetentionPolicyType is a string enumeration of the policies that will determine
@@ -44,6 +54,17 @@ const (
 	DeletePersistentVolumeClaimRetentionPolicyType PersistentVolumeClaimRetentionPolicyType = "Delete"
 )
 
+// PodManagementPolicyType is a string enumeration type that enumerates the policies to manage Pods.
+// +kubebuilder:validation:Enum=Parallel;OrderedReady
+type PodManagementPolicyType string
+
+const (
+	// ParallelPodManagementPolicyType operates Pods in parallel, without waiting for each other.
+	ParallelPodManagementPolicyType PodManagementPolicyType = "Parallel"
+	// OrderedReadyPodManagementPolicyType operates Pods one by one in the order of instance ID.
+	OrderedReadyPodManagementPolicyType PodManagementPolicyType = "OrderedReady"
+)
+
 // PodUpdateStrategyType is a string enumeration type that enumerates
 // all possible ways we can update a Pod when updating application
 type PodUpdateStrategyType string
@@ -52,18 +73,21 @@ const (
 	// CollaSetRecreatePodUpdateStrategyType indicates that CollaSet will always update Pod by deleting and recreate it.
 	CollaSetRecreatePodUpdateStrategyType PodUpdateStrategyType = "ReCreate"
 	// CollaSetInPlaceIfPossiblePodUpdateStrategyType indicates thath CollaSet will try to update Pod by in-place update
-	// when it is possible. Recently, only Pod image can be updated in-place. Any other Pod spec change will make the
-	// policy fall back to CollaSetRecreatePodUpdateStrategyType.
+	// when it is possible. Recently, Pod metadata and container image can be updated in-place, and so can container
+	// resources if UpdateStrategy.InPlaceResizeEnabled is set. Any other Pod spec change will make the policy fall back
+	// to CollaSetRecreatePodUpdateStrategyType.
 	CollaSetInPlaceIfPossiblePodUpdateStrategyType PodUpdateStrategyType = "InPlaceIfPossible"
 	// CollaSetInPlaceOnlyPodUpdateStrategyType indicates that CollaSet will always update Pod in-place, instead of
-	// recreating pod. It will encounter an error on original Kubernetes cluster.
+	// recreating pod. Pods with changes not able to be updated in-place are kept in current revision, and reported
+	// by condition InPlaceUpdateUnsupported.
 	CollaSetInPlaceOnlyPodUpdateStrategyType PodUpdateStrategyType = "InPlaceOnly"
 )
 
 // CollaSetSpec defines the desired state of CollaSet
 type CollaSetSpec struct {
 	// Indicates that the scaling and updating is paused and will not be processed by the
-	// CollaSet controller.
+	// CollaSet controller. The status is still refreshed, and the PodOpsLifecycles which
+	// have already begun are still going to be finished.
 	// +optional
 	Paused bool `json:"paused,omitempty"`
 	// Replicas is the desired number of replicas of the given Template.
@@ -73,6 +97,19 @@ type CollaSetSpec struct {
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// MinReadySeconds is the minimum number of seconds for which a newly created or updated Pod
+	// should be ready without any of its container crashing, for it to be considered available.
+	// Defaults to 0 (Pod will be considered available as soon as it is service available)
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+
+	// PodManagementPolicy controls how Pods are created, updated and scaled in. With policy OrderedReady,
+	// Pods are created in the ascending order of instance ID, updated and scaled in in the descending order,
+	// one by one, and each one waits for the previous one to be service available or deleted.
+	// PodManagementPolicy defaults to be Parallel, with which Pods are operated in parallel.
+	// +optional
+	PodManagementPolicy PodManagementPolicyType `json:"podManagementPolicy,omitempty"`
+
 	// Selector is a label query over pods that should match the replica count.
 	// It must match the pod template's labels.
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
@@ -81,12 +118,17 @@ type CollaSetSpec struct {
 	// insufficient replicas are detected. Each pod stamped out by the CollaSet
 	// will fulfill this Template, but have a unique identity from the rest
 	// of the CollaSet.
+	// The variables like ${context.<key>} in env values, label values and annotations are resolved with the data
+	// of key set for the Pod instance ID in ScaleStrategy.InstanceData, or else the data in the instance ID context
+	// in ResourceContext, and ${context.ID} is resolved to the instance ID. The keys Owner, Revision, ScaleIn and
+	// PvcCollaSet are reserved by CollaSet and not allowed to be used. The variables with keys not found are
+	// resolved to empty, and the label values have to be valid after resolved.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
 	Template corev1.PodTemplateSpec `json:"template,omitempty"`
 
 	// VolumeClaimTemplates is a list of claims that pods are allowed to reference.
-	// The StatefulSet controller is responsible for mapping network identities to
+	// The CollaSet controller is responsible for mapping instance IDs to
 	// claims in a way that maintains the identity of a pod. Every claim in
 	// this list must have at least one matching (by name) volumeMount in one
 	// container in the template. A claim in this list takes precedence over
@@ -104,12 +146,75 @@ type CollaSetSpec struct {
 	// +optional
 	ScaleStrategy ScaleStrategy `json:"scaleStrategy,omitempty"`
 
+	// Topology indicates how to spread Pods across the topology domains, like zones.
+	// +optional
+	Topology *CollaSetTopology `json:"topology,omitempty"`
+
 	// Indicate the number of histories to be conserved
 	// If unspecified, defaults to 20
 	// +optional
 	HistoryLimit int32 `json:"historyLimit,omitempty"`
 }
 
+// CollaSetTopology indicates the topology domains to spread Pods across. Each Pod is assigned to a domain,
+// and is required to be scheduled to the nodes in it by node affinity.
+type CollaSetTopology struct {
+	// TopologyKey is the key of node labels, whose values indicate the topology domains. Like topology.kubernetes.io/zone.
+	TopologyKey string `json:"topologyKey"`
+
+	// Domains lists the topology domains to spread Pods across.
+	Domains []CollaSetTopologyDomain `json:"domains"`
+}
+
+// CollaSetTopologyDomain indicates a topology domain and how many Pods are expected in it.
+type CollaSetTopologyDomain struct {
+	// Name is the unique name of this domain.
+	Name string `json:"name"`
+
+	// Values are the values of node label TopologyKey belonging to this domain.
+	Values []string `json:"values"`
+
+	// Replicas indicates the fixed number of Pods in this domain. The domains with fixed number are satisfied first.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Weight indicates the relative weight to share the rest Pods, if Replicas is not indicated. Defaults to 1.
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+
+	// PodTemplatePatch is a strategic merge patch of the Pod template for the Pods in this domain, like a different
+	// node selector, resources, tolerations or image registry mirror. It is applied after the template of revision,
+	// and changing it rolls out a new revision.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
+}
+
+// MaxReleasedIDCooldownSeconds is the longest duration for which the IDs reclaimed in Context are kept from allocation.
+const MaxReleasedIDCooldownSeconds = 86400
+
+// IDAllocationStrategy restricts the instance IDs to allocate. The free IDs satisfying all the restrictions are allocated
+// from the lowest one, unless IDs are listed explicitly.
+type IDAllocationStrategy struct {
+	// IDs indicates the explicit list of IDs allowed to allocate, and they are allocated in the listed order.
+	// +optional
+	IDs []int `json:"ids,omitempty"`
+
+	// MinID indicates the lowest ID allowed to allocate. Defaults to 0.
+	// +optional
+	MinID *int32 `json:"minID,omitempty"`
+
+	// MaxID indicates the highest ID allowed to allocate. There is no upper bound by default.
+	// +optional
+	MaxID *int32 `json:"maxID,omitempty"`
+
+	// ReleasedIDCooldownSeconds indicates the duration for which the IDs recently reclaimed in Context are not
+	// allocated again, so that they are not reused by new Pods too soon. It is not allowed to exceed 86400.
+	// +optional
+	ReleasedIDCooldownSeconds *int32 `json:"releasedIDCooldownSeconds,omitempty"`
+}
+
 type ScaleStrategy struct {
 	// Context indicates the pool from which to allocate Pod instance ID. CollaSets are allowed to share the
 	// same Context. It is not allowed to change.
@@ -117,39 +222,138 @@ type ScaleStrategy struct {
 	// +optional
 	Context string `json:"context,omitempty"`
 
-	// PodToExclude indicates the pods which will be orphaned by CollaSet.
+	// IDAllocationStrategy indicates the restrictions on the instance IDs allocated from Context.
+	// IDs are allocated from the lowest free one by default.
+	// +optional
+	IDAllocationStrategy *IDAllocationStrategy `json:"idAllocationStrategy,omitempty"`
+
+	// PodToExclude indicates the pods which will be orphaned by CollaSet. These Pods are released without
+	// being deleted, and their instance IDs are reclaimed. The replicas will be decreased accordingly,
+	// and the entries will be removed after the Pods are released.
 	// +optional
 	PodToExclude []string `json:"podToExclude,omitempty"`
 
-	// PodToInclude indicates the pods which will be adapted by CollaSet.
+	// PodToInclude indicates the pods which will be adapted by CollaSet. Only the orphan Pods matching
+	// selector are able to be adopted, and they will be allocated with new instance IDs. The replicas will be
+	// increased accordingly, and the entries will be removed after the Pods are adopted.
 	// +optional
 	PodToInclude []string `json:"podToInclude,omitempty"`
 
+	// PodToDelete indicates the pods which will be scaled in by CollaSet, by the Pod names or instance IDs.
+	// These Pods go through the PodOpsLifecycle of scaling in first. Once a Pod is allowed to scale in,
+	// the replicas will be decreased by one and the entry will be removed at the same time.
+	// +optional
+	PodToDelete []string `json:"podToDelete,omitempty"`
+
+	// IDToRelease indicates the instance IDs to release to the other CollaSets sharing the same Context.
+	// The Pods with these IDs go through the PodOpsLifecycle of scaling in first. Once the Pod with an ID is
+	// deleted, the ID is released with its context data and PVCs kept, instead of being reclaimed. The replicas
+	// will be decreased accordingly, and the entries will be removed after the IDs are released.
+	// +optional
+	IDToRelease []int `json:"idToRelease,omitempty"`
+
+	// IDToAdopt indicates the instance IDs released by the other CollaSets sharing the same Context to adopt.
+	// Once an ID is released, it is owned by this CollaSet with its context data and PVCs kept. The replicas will be
+	// increased accordingly, and the entries will be removed after the IDs are adopted.
+	// +optional
+	IDToAdopt []int `json:"idToAdopt,omitempty"`
+
+	// InstanceData indicates the user data of Pod instance IDs, which are used to resolve the template variables
+	// ${context.<key>} in Template. The data of an ID takes precedence over the one in its context in ResourceContext.
+	// The existing Pods are not updated if only the data is changed.
+	// +optional
+	InstanceData []InstanceIDData `json:"instanceData,omitempty"`
+
 	// PersistentVolumeClaimRetentionPolicy describes the lifecycle of PersistentVolumeClaim
-	// created from volumeClaimTemplates. By default, all persistent volume claims are created as needed and
-	// deleted after no pod is using them. This policy allows the lifecycle to be altered, for example
-	// by deleting persistent volume claims when their CollaSet is deleted, or when their pod is scaled down.
+	// created from volumeClaimTemplates. By default, all persistent volume claims are created as needed,
+	// retained when their pods are scaled down and deleted along with CollaSet. This policy allows the lifecycle
+	// to be altered, for example by deleting persistent volume claims when their pod is scaled down.
 	// +optional
 	PersistentVolumeClaimRetentionPolicy *PersistentVolumeClaimRetentionPolicy `json:"persistentVolumeClaimRetentionPolicy,omitempty"`
 
 	// OperationDelaySeconds indicates how many seconds it should delay before operating scale.
 	// +optional
 	OperationDelaySeconds *int32 `json:"operationDelaySeconds,omitempty"`
+
+	// MaxConcurrency indicates the maximum number of Pods operated concurrently during scaling, including
+	// creating and deleting Pods and operating their PodOpsLifecycle. By default, the concurrency is only
+	// limited by the global batch size ceiling of controller manager.
+	// +optional
+	MaxConcurrency *int32 `json:"maxConcurrency,omitempty"`
+
+	// ScaleInPolicies indicates the policies to choose Pods to scale in. Policies are applied in order,
+	// and the latter one is only used when Pods are equal in the former ones. Pods which are terminating or
+	// scaling in are always chosen first, and the default order is used after all the policies.
+	// +optional
+	ScaleInPolicies []ScaleInPolicyType `json:"scaleInPolicies,omitempty"`
+
+	// PodNamingPolicy indicates how to name the Pods created by CollaSet. With policy InstanceID, Pods are named
+	// as <collaset>-<instance ID>, so that the Pod with the same instance ID always has the same name across
+	// recreations. Pods can not be replaced with policy InstanceID, because the replacement has to hold the
+	// same name as the origin one.
+	// PodNamingPolicy defaults to be Default, with which Pods are named with a random suffix.
+	// +optional
+	PodNamingPolicy PodNamingPolicyType `json:"podNamingPolicy,omitempty"`
 }
 
+// InstanceIDData is the user data of a Pod instance ID.
+type InstanceIDData struct {
+	// ID is the Pod instance ID.
+	ID int `json:"id"`
+
+	// Data is the user data of the ID, keyed by the names of template variables. The keys ID, Owner, Revision,
+	// ScaleIn and PvcCollaSet are reserved.
+	// +optional
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// PodNamingPolicyType is a string enumeration type that enumerates the policies to name Pods.
+// +kubebuilder:validation:Enum=Default;InstanceID
+type PodNamingPolicyType string
+
+const (
+	// DefaultPodNamingPolicyType names Pods with the CollaSet name as prefix and a random suffix.
+	DefaultPodNamingPolicyType PodNamingPolicyType = "Default"
+	// InstanceIDPodNamingPolicyType names Pods as <collaset>-<instance ID>.
+	InstanceIDPodNamingPolicyType PodNamingPolicyType = "InstanceID"
+)
+
+// ScaleInPolicyType is a string enumeration type that enumerates the policies to choose Pods to scale in.
+// +kubebuilder:validation:Enum=NotReadyFirst;HighestInstanceIDFirst;SpreadByNode;SpreadByZone;OldestRevisionFirst;DeletionCost
+type ScaleInPolicyType string
+
+const (
+	// NotReadyScaleInPolicyType chooses the Pods which are not ready first.
+	NotReadyScaleInPolicyType ScaleInPolicyType = "NotReadyFirst"
+	// HighestInstanceIDScaleInPolicyType chooses the Pods with higher instance ID first.
+	HighestInstanceIDScaleInPolicyType ScaleInPolicyType = "HighestInstanceIDFirst"
+	// SpreadByNodeScaleInPolicyType chooses the Pods on the node with more Pods of CollaSet first,
+	// in order to keep Pods spread across nodes.
+	SpreadByNodeScaleInPolicyType ScaleInPolicyType = "SpreadByNode"
+	// SpreadByZoneScaleInPolicyType chooses the Pods in the zone with more Pods of CollaSet first,
+	// in order to keep Pods spread across zones. The zone is indicated by the node label topology.kubernetes.io/zone.
+	SpreadByZoneScaleInPolicyType ScaleInPolicyType = "SpreadByZone"
+	// OldestRevisionScaleInPolicyType chooses the Pods with older revision first.
+	OldestRevisionScaleInPolicyType ScaleInPolicyType = "OldestRevisionFirst"
+	// DeletionCostScaleInPolicyType chooses the Pods with lower cost indicated by
+	// the annotation controller.kubernetes.io/pod-deletion-cost first.
+	DeletionCostScaleInPolicyType ScaleInPolicyType = "DeletionCost"
+)
+
 type PersistentVolumeClaimRetentionPolicy struct {
 	// WhenDeleted specifies what happens to PVCs created from CollaSet
 	// VolumeClaimTemplates when the CollaSet is deleted. The default policy
 	// of `Delete` policy causes those PVCs to be deleted.
-	//`Retain` causes PVCs to not be affected by StatefulSet deletion. The
+	// `Retain` causes PVCs to not be affected by CollaSet deletion.
 	// +optional
 	WhenDeleted PersistentVolumeClaimRetentionPolicyType `json:"whenDeleted,omitempty"`
 
-	// WhenScaled specifies what happens to PVCs created from StatefulSet
-	// VolumeClaimTemplates when the StatefulSet is scaled down. The default
+	// WhenScaled specifies what happens to PVCs created from CollaSet
+	// VolumeClaimTemplates when the CollaSet is scaled down. The default
 	// policy of `Retain` causes PVCs to not be affected by a scaledown. The
 	// `Delete` policy causes the associated PVCs for any excess pods above
-	// the replica count to be deleted.
+	// the replica count to be deleted, and their instance IDs will not be
+	// reclaimed until these PVCs are deleted.
 	// +optional
 	WhenScaled PersistentVolumeClaimRetentionPolicyType `json:"whenScaled,omitempty"`
 }
@@ -173,6 +377,46 @@ type RollingUpdateCollaSetStrategy struct {
 	// ByLabel indicates the update progress is controlled by attaching pod label.
 	// +optional
 	ByLabel *ByLabel `json:"byLabel,omitempty"`
+
+	// MaxSurge indicates the maximum number of Pods that can be created above the replicas during updating.
+	// Pods with updated revision are created first, and then Pods with old revision are scaled in
+	// once the new ones are service available. Value can be an absolute number (ex: 5) or
+	// a percentage of replicas (ex: 10%), which is calculated by rounding up.
+	// Defaults to nil, which means Pods are updated without surging.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// MaxUnavailable indicates the maximum number of Pods that can be unavailable during updating.
+	// Pods during PodOpsLifecycle or not service available are counted as unavailable.
+	// Value can be an absolute number (ex: 5) or a percentage of replicas (ex: 10%), which is calculated
+	// by rounding down. It is treated as 1 if it is resolved to 0 and MaxSurge is not indicated.
+	// Defaults to nil, which means there is no limit.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// Batches indicates the update progress is controlled by a plan of batches, which is advanced by CollaSet itself.
+	// A batch is finished when all the Pods expected in it are updated and service available, and the next batch
+	// begins after the pause or the approval of the finished one. The last batch always includes all the rest Pods.
+	// The progress stops when there are failing Pods with updated revision.
+	// It is not allowed to be used together with ByPartition or ByLabel.
+	// +optional
+	Batches []RollingUpdateBatch `json:"batches,omitempty"`
+}
+
+// RollingUpdateBatch indicates a batch of Pods to update.
+type RollingUpdateBatch struct {
+	// Replicas indicates how many Pods are updated in this batch. Value can be an absolute number (ex: 5)
+	// or a percentage of replicas (ex: 10%), which is calculated by rounding up.
+	Replicas intstr.IntOrString `json:"replicas"`
+
+	// PauseSeconds indicates how many seconds to pause after this batch is finished, before the next batch begins.
+	// +optional
+	PauseSeconds *int32 `json:"pauseSeconds,omitempty"`
+
+	// ManualApproval indicates the next batch will not begin until this batch is approved
+	// by the annotation collaset.kusionstack.io/approved-batch with a value not less than the index of this batch.
+	// +optional
+	ManualApproval bool `json:"manualApproval,omitempty"`
 }
 
 type UpdateStrategy struct {
@@ -181,12 +425,153 @@ type UpdateStrategy struct {
 	RollingUpdate *RollingUpdateCollaSetStrategy `json:"rollingUpdate,omitempty"`
 
 	// PodUpdatePolicy indicates the policy by to update pods.
+	// Env referring to Pod labels or annotations through downward API is resolved only when container starts,
+	// so the changes of these labels or annotations are updated in-place only along with image changes. Otherwise,
+	// Pods are recreated, or kept in current revision with policy InPlaceOnly.
 	// +optional
 	PodUpdatePolicy PodUpdateStrategyType `json:"podUpgradePolicy,omitempty"`
 
+	// InPlaceResizeEnabled indicates whether the changes of container resources are updated in-place, which relies
+	// on the in-place Pod resize feature of Kubernetes. Otherwise, Pods with resource changes are recreated, or kept
+	// in current revision with policy InPlaceOnly.
+	// +optional
+	InPlaceResizeEnabled bool `json:"inPlaceResizeEnabled,omitempty"`
+
 	// OperationDelaySeconds indicates how many seconds it should delay before operating update.
 	// +optional
 	OperationDelaySeconds *int32 `json:"operationDelaySeconds,omitempty"`
+
+	// MaxConcurrency indicates the maximum number of Pods operated concurrently during updating, including
+	// updating Pods and operating their PodOpsLifecycle. By default, the concurrency is only limited by the
+	// global batch size ceiling of controller manager.
+	// +optional
+	MaxConcurrency *int32 `json:"maxConcurrency,omitempty"`
+
+	// RollbackTo indicates the ControllerRevision to roll back to. The template of CollaSet will be restored
+	// from this revision, and then this field will be cleared.
+	// +optional
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty"`
+
+	// ProgressDeadlineSeconds indicates the maximum seconds for updating to make no progress before it is considered
+	// to be failed. The failure is surfaced by the condition Progressing with status False.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// FailurePolicy indicates how to judge and handle the failure of updating.
+	// +optional
+	FailurePolicy *UpdateFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// UpdateFailurePolicy indicates how to judge and handle the failure of updating.
+type UpdateFailurePolicy struct {
+	// FailureThreshold indicates the maximum number or percentage of replicas of the Pods with updated revision,
+	// which have finished updating but are not service available. Updating is considered to be failed once it is exceeded.
+	// +optional
+	FailureThreshold *intstr.IntOrString `json:"failureThreshold,omitempty"`
+
+	// Rollback indicates whether to roll back to the current revision automatically once updating fails.
+	// +optional
+	Rollback bool `json:"rollback,omitempty"`
+}
+
+type RollbackConfig struct {
+	// Revision indicates the ControllerRevision to roll back to, by its name or its revision number.
+	Revision intstr.IntOrString `json:"revision"`
+}
+
+// CollaSetRollbackStatus records the last rollback of CollaSet.
+type CollaSetRollbackStatus struct {
+	// FromRevision is the updated revision before rolling back.
+	// +optional
+	FromRevision string `json:"fromRevision,omitempty"`
+
+	// ToRevision is the revision rolled back to.
+	// +optional
+	ToRevision string `json:"toRevision,omitempty"`
+
+	// RollbackTime is the time when CollaSet is rolled back.
+	// +optional
+	RollbackTime metav1.Time `json:"rollbackTime,omitempty"`
+}
+
+// CollaSetTopologyDomainStatus records the Pods in a topology domain.
+type CollaSetTopologyDomainStatus struct {
+	// Name is the name of the topology domain.
+	Name string `json:"name"`
+
+	// DesiredReplicas is the number of Pods expected in this domain.
+	// +optional
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+
+	// Replicas is the number of Pods in this domain.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// AvailableReplicas is the number of available Pods in this domain.
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+}
+
+// CollaSetUpdateProgressStatus records the progress of updating to the updated revision.
+type CollaSetUpdateProgressStatus struct {
+	// Revision is the updated revision which the progress is recorded for.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// UpdatedReplicas is the number of Pods with updated revision when the last progress is made.
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// UpdatedAvailableReplicas is the number of service available Pods with updated revision when the last progress is made.
+	// +optional
+	UpdatedAvailableReplicas int32 `json:"updatedAvailableReplicas,omitempty"`
+
+	// LastProgressTime is the time when the last progress is made.
+	// +optional
+	LastProgressTime metav1.Time `json:"lastProgressTime,omitempty"`
+}
+
+// CollaSetUpdateBatchPhase is a string enumeration type that enumerates the phases of updating by batches.
+type CollaSetUpdateBatchPhase string
+
+const (
+	// CollaSetUpdateBatchUpdating indicates the Pods in the current batch are updating.
+	CollaSetUpdateBatchUpdating CollaSetUpdateBatchPhase = "Updating"
+	// CollaSetUpdateBatchPaused indicates the current batch is finished, and it is paused before the next batch.
+	CollaSetUpdateBatchPaused CollaSetUpdateBatchPhase = "Paused"
+	// CollaSetUpdateBatchWaitingForApproval indicates the current batch is finished, and it is waiting for approval.
+	CollaSetUpdateBatchWaitingForApproval CollaSetUpdateBatchPhase = "WaitingForApproval"
+	// CollaSetUpdateBatchFailed indicates there are failing Pods with updated revision, and the progress stops.
+	CollaSetUpdateBatchFailed CollaSetUpdateBatchPhase = "Failed"
+	// CollaSetUpdateBatchCompleted indicates all the batches are finished.
+	CollaSetUpdateBatchCompleted CollaSetUpdateBatchPhase = "Completed"
+)
+
+// CollaSetUpdateBatchStatus records the progress of updating by batches.
+type CollaSetUpdateBatchStatus struct {
+	// Revision is the updated revision which the batches are applied to.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// Batch is the index of the current batch, starting from 0.
+	// +optional
+	Batch int32 `json:"batch,omitempty"`
+
+	// Partition is how many Pods are expected to be updated when the current batch is finished.
+	// +optional
+	Partition int32 `json:"partition,omitempty"`
+
+	// Phase is the phase of the current batch.
+	// +optional
+	Phase CollaSetUpdateBatchPhase `json:"phase,omitempty"`
+
+	// FinishedTime is the time when the current batch is finished.
+	// +optional
+	FinishedTime *metav1.Time `json:"finishedTime,omitempty"`
+
+	// Message describes the reason why the progress stops.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // CollaSetStatus defines the observed state of CollaSet
@@ -222,6 +607,11 @@ type CollaSetStatus struct {
 	// +optional
 	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
 
+	// Selector is the label selector of CollaSet in string format, which is used by the scale subresource
+	// for HorizontalPodAutoscaler to find the Pods.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
 	// Replicas is the most recently observed number of replicas.
 	// +optional
 	Replicas int32 `json:"replicas,omitempty"`
@@ -243,6 +633,31 @@ type CollaSetStatus struct {
 	// +optional
 	UpdatedAvailableReplicas int32 `json:"updatedAvailableReplicas,omitempty"`
 
+	// UnavailableReplicas indicates the number of Pods which are during PodOpsLifecycle or not service available.
+	// +optional
+	UnavailableReplicas int32 `json:"unavailableReplicas,omitempty"`
+
+	// MaxUnavailableReplicas indicates the number of Pods allowed to be unavailable during updating,
+	// which is resolved from UpdateStrategy.RollingUpdate.MaxUnavailable.
+	// +optional
+	MaxUnavailableReplicas *int32 `json:"maxUnavailableReplicas,omitempty"`
+
+	// LastRollback records the last rollback of CollaSet.
+	// +optional
+	LastRollback *CollaSetRollbackStatus `json:"lastRollback,omitempty"`
+
+	// UpdateBatch records the progress of updating by batches.
+	// +optional
+	UpdateBatch *CollaSetUpdateBatchStatus `json:"updateBatch,omitempty"`
+
+	// UpdateProgress records the progress of updating, which is used to check ProgressDeadlineSeconds.
+	// +optional
+	UpdateProgress *CollaSetUpdateProgressStatus `json:"updateProgress,omitempty"`
+
+	// TopologyDomains records the Pods in each topology domain.
+	// +optional
+	TopologyDomains []CollaSetTopologyDomainStatus `json:"topologyDomains,omitempty"`
+
 	// Represents the latest available observations of a CollaSet's current state.
 	// +optional
 	Conditions []CollaSetCondition `json:"conditions,omitempty"`
@@ -273,6 +688,7 @@ type CollaSetCondition struct {
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:resource:shortName=cls
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:printcolumn:name="DESIRED",type="integer",JSONPath=".spec.replicas",description="The desired number of pods."
 // +kubebuilder:printcolumn:name="CURRENT",type="integer",JSONPath=".status.replicas",description="The number of currently all pods."
 // +kubebuilder:printcolumn:name="AVAILABLE",type="integer",JSONPath=".status.availableReplicas",description="The number of pods available."
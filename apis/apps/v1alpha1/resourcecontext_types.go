@@ -30,17 +30,48 @@ type ContextDetail struct {
 	Data map[string]string `json:"data,omitempty"`
 }
 
+// ResourceContextStatus defines the observed state of ResourceContext
+type ResourceContextStatus struct {
+	// ObservedGeneration is the most recent generation observed for this ResourceContext. It corresponds to the
+	// ResourceContext's generation, which is updated on mutation by the API Server.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Owners indicates the count of IDs owned by each owner, and whether the owner exists.
+	// +optional
+	Owners []ContextOwnerStatus `json:"owners,omitempty"`
+
+	// UnownedIDs indicates the count of IDs without owner, like the ones released and waiting to be adopted.
+	// +optional
+	UnownedIDs int32 `json:"unownedIDs,omitempty"`
+}
+
+type ContextOwnerStatus struct {
+	// Name is the name of owner.
+	Name string `json:"name"`
+
+	// IDs indicates the count of IDs owned by this owner.
+	IDs int32 `json:"ids"`
+
+	// MissingSince indicates the time when the owner is first found not existing. The IDs owned will be reclaimed
+	// after a grace period since then.
+	// +optional
+	MissingSince *metav1.Time `json:"missingSince,omitempty"`
+}
+
 //+kubebuilder:object:root=true
 
 // ResourceContext is the Schema for the resourcecontext API
 // +k8s:openapi-gen=true
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:resource:shortName=rc
+// +kubebuilder:subresource:status
 type ResourceContext struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ResourceContextSpec `json:"spec,omitempty"`
+	Spec   ResourceContextSpec   `json:"spec,omitempty"`
+	Status ResourceContextStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -42,7 +42,21 @@ const (
 )
 
 const (
-	CollaSetUpdateIndicateLabelKey = "collaset.kusionstack.io/update-included"
+	CollaSetUpdateIndicateLabelKey  = "collaset.kusionstack.io/update-included"
+	CollaSetReplaceIndicateLabelKey = "collaset.kusionstack.io/replace-indicate" // used to indicate a Pod to be replaced by a new one
+
+	PodReplacePairOriginNameAnnotationKey = "collaset.kusionstack.io/replace-pair-origin-name" // attached on the new Pod with the name of Pod it replaces
+	PodReplacePairNewNameAnnotationKey    = "collaset.kusionstack.io/replace-pair-new-name"    // attached on the origin Pod with the name of its replacement
+
+	PvcTemplateLabelKey = "collaset.kusionstack.io/pvc-template" // used to attach the name of PVC template on PVC
+	PvcReleasedLabelKey = "collaset.kusionstack.io/pvc-released" // used to attach the name of CollaSet releasing the PVC along with its instance ID
+
+	PodTopologyDomainLabelKey = "collaset.kusionstack.io/topology-domain" // used to attach the name of topology domain assigned on Pod
+
+	CollaSetRollbackToAnnotationKey    = "collaset.kusionstack.io/rollback-to"    // used to roll back CollaSet to a revision by name or number
+	CollaSetApprovedBatchAnnotationKey = "collaset.kusionstack.io/approved-batch" // used to approve the update batches up to the indicated index
+
+	ResourceContextReclaimedIDsAnnotationKey = "resourcecontext.kusionstack.io/reclaimed-ids" // used to record the time when IDs are reclaimed from ResourceContext
 )
 
 var (
@@ -24,7 +24,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -188,6 +188,22 @@ func (in *CollaSetList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CollaSetRollbackStatus) DeepCopyInto(out *CollaSetRollbackStatus) {
+	*out = *in
+	in.RollbackTime.DeepCopyInto(&out.RollbackTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollaSetRollbackStatus.
+func (in *CollaSetRollbackStatus) DeepCopy() *CollaSetRollbackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CollaSetRollbackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CollaSetSpec) DeepCopyInto(out *CollaSetSpec) {
 	*out = *in
@@ -211,6 +227,11 @@ func (in *CollaSetSpec) DeepCopyInto(out *CollaSetSpec) {
 	}
 	in.UpdateStrategy.DeepCopyInto(&out.UpdateStrategy)
 	in.ScaleStrategy.DeepCopyInto(&out.ScaleStrategy)
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(CollaSetTopology)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollaSetSpec.
@@ -231,6 +252,31 @@ func (in *CollaSetStatus) DeepCopyInto(out *CollaSetStatus) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.MaxUnavailableReplicas != nil {
+		in, out := &in.MaxUnavailableReplicas, &out.MaxUnavailableReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LastRollback != nil {
+		in, out := &in.LastRollback, &out.LastRollback
+		*out = new(CollaSetRollbackStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateBatch != nil {
+		in, out := &in.UpdateBatch, &out.UpdateBatch
+		*out = new(CollaSetUpdateBatchStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateProgress != nil {
+		in, out := &in.UpdateProgress, &out.UpdateProgress
+		*out = new(CollaSetUpdateProgressStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologyDomains != nil {
+		in, out := &in.TopologyDomains, &out.TopologyDomains
+		*out = make([]CollaSetTopologyDomainStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]CollaSetCondition, len(*in))
@@ -250,6 +296,113 @@ func (in *CollaSetStatus) DeepCopy() *CollaSetStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CollaSetTopology) DeepCopyInto(out *CollaSetTopology) {
+	*out = *in
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]CollaSetTopologyDomain, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollaSetTopology.
+func (in *CollaSetTopology) DeepCopy() *CollaSetTopology {
+	if in == nil {
+		return nil
+	}
+	out := new(CollaSetTopology)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CollaSetTopologyDomain) DeepCopyInto(out *CollaSetTopologyDomain) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollaSetTopologyDomain.
+func (in *CollaSetTopologyDomain) DeepCopy() *CollaSetTopologyDomain {
+	if in == nil {
+		return nil
+	}
+	out := new(CollaSetTopologyDomain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CollaSetTopologyDomainStatus) DeepCopyInto(out *CollaSetTopologyDomainStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollaSetTopologyDomainStatus.
+func (in *CollaSetTopologyDomainStatus) DeepCopy() *CollaSetTopologyDomainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CollaSetTopologyDomainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CollaSetUpdateBatchStatus) DeepCopyInto(out *CollaSetUpdateBatchStatus) {
+	*out = *in
+	if in.FinishedTime != nil {
+		in, out := &in.FinishedTime, &out.FinishedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollaSetUpdateBatchStatus.
+func (in *CollaSetUpdateBatchStatus) DeepCopy() *CollaSetUpdateBatchStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CollaSetUpdateBatchStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CollaSetUpdateProgressStatus) DeepCopyInto(out *CollaSetUpdateProgressStatus) {
+	*out = *in
+	in.LastProgressTime.DeepCopyInto(&out.LastProgressTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollaSetUpdateProgressStatus.
+func (in *CollaSetUpdateProgressStatus) DeepCopy() *CollaSetUpdateProgressStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CollaSetUpdateProgressStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContextDetail) DeepCopyInto(out *ContextDetail) {
 	*out = *in
@@ -272,6 +425,25 @@ func (in *ContextDetail) DeepCopy() *ContextDetail {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContextOwnerStatus) DeepCopyInto(out *ContextOwnerStatus) {
+	*out = *in
+	if in.MissingSince != nil {
+		in, out := &in.MissingSince, &out.MissingSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContextOwnerStatus.
+func (in *ContextOwnerStatus) DeepCopy() *ContextOwnerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ContextOwnerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Detail) DeepCopyInto(out *Detail) {
 	*out = *in
@@ -297,6 +469,63 @@ func (in *Detail) DeepCopy() *Detail {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IDAllocationStrategy) DeepCopyInto(out *IDAllocationStrategy) {
+	*out = *in
+	if in.IDs != nil {
+		in, out := &in.IDs, &out.IDs
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinID != nil {
+		in, out := &in.MinID, &out.MinID
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxID != nil {
+		in, out := &in.MaxID, &out.MaxID
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReleasedIDCooldownSeconds != nil {
+		in, out := &in.ReleasedIDCooldownSeconds, &out.ReleasedIDCooldownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IDAllocationStrategy.
+func (in *IDAllocationStrategy) DeepCopy() *IDAllocationStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(IDAllocationStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceIDData) DeepCopyInto(out *InstanceIDData) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceIDData.
+func (in *InstanceIDData) DeepCopy() *InstanceIDData {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceIDData)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ItemStatus) DeepCopyInto(out *ItemStatus) {
 	*out = *in
@@ -540,6 +769,7 @@ func (in *ResourceContext) DeepCopyInto(out *ResourceContext) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceContext.
@@ -614,6 +844,28 @@ func (in *ResourceContextSpec) DeepCopy() *ResourceContextSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceContextStatus) DeepCopyInto(out *ResourceContextStatus) {
+	*out = *in
+	if in.Owners != nil {
+		in, out := &in.Owners, &out.Owners
+		*out = make([]ContextOwnerStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceContextStatus.
+func (in *ResourceContextStatus) DeepCopy() *ResourceContextStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceContextStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceParameter) DeepCopyInto(out *ResourceParameter) {
 	*out = *in
@@ -636,6 +888,43 @@ func (in *ResourceParameter) DeepCopy() *ResourceParameter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackConfig) DeepCopyInto(out *RollbackConfig) {
+	*out = *in
+	out.Revision = in.Revision
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackConfig.
+func (in *RollbackConfig) DeepCopy() *RollbackConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollingUpdateBatch) DeepCopyInto(out *RollingUpdateBatch) {
+	*out = *in
+	out.Replicas = in.Replicas
+	if in.PauseSeconds != nil {
+		in, out := &in.PauseSeconds, &out.PauseSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpdateBatch.
+func (in *RollingUpdateBatch) DeepCopy() *RollingUpdateBatch {
+	if in == nil {
+		return nil
+	}
+	out := new(RollingUpdateBatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RollingUpdateCollaSetStrategy) DeepCopyInto(out *RollingUpdateCollaSetStrategy) {
 	*out = *in
@@ -649,6 +938,23 @@ func (in *RollingUpdateCollaSetStrategy) DeepCopyInto(out *RollingUpdateCollaSet
 		*out = new(ByLabel)
 		**out = **in
 	}
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.Batches != nil {
+		in, out := &in.Batches, &out.Batches
+		*out = make([]RollingUpdateBatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpdateCollaSetStrategy.
@@ -684,6 +990,11 @@ func (in *RuleState) DeepCopy() *RuleState {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScaleStrategy) DeepCopyInto(out *ScaleStrategy) {
 	*out = *in
+	if in.IDAllocationStrategy != nil {
+		in, out := &in.IDAllocationStrategy, &out.IDAllocationStrategy
+		*out = new(IDAllocationStrategy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PodToExclude != nil {
 		in, out := &in.PodToExclude, &out.PodToExclude
 		*out = make([]string, len(*in))
@@ -694,6 +1005,28 @@ func (in *ScaleStrategy) DeepCopyInto(out *ScaleStrategy) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PodToDelete != nil {
+		in, out := &in.PodToDelete, &out.PodToDelete
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IDToRelease != nil {
+		in, out := &in.IDToRelease, &out.IDToRelease
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.IDToAdopt != nil {
+		in, out := &in.IDToAdopt, &out.IDToAdopt
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.InstanceData != nil {
+		in, out := &in.InstanceData, &out.InstanceData
+		*out = make([]InstanceIDData, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PersistentVolumeClaimRetentionPolicy != nil {
 		in, out := &in.PersistentVolumeClaimRetentionPolicy, &out.PersistentVolumeClaimRetentionPolicy
 		*out = new(PersistentVolumeClaimRetentionPolicy)
@@ -704,6 +1037,16 @@ func (in *ScaleStrategy) DeepCopyInto(out *ScaleStrategy) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.MaxConcurrency != nil {
+		in, out := &in.MaxConcurrency, &out.MaxConcurrency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleInPolicies != nil {
+		in, out := &in.ScaleInPolicies, &out.ScaleInPolicies
+		*out = make([]ScaleInPolicyType, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleStrategy.
@@ -848,6 +1191,26 @@ func (in *TransitionRuleWebhook) DeepCopy() *TransitionRuleWebhook {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateFailurePolicy) DeepCopyInto(out *UpdateFailurePolicy) {
+	*out = *in
+	if in.FailureThreshold != nil {
+		in, out := &in.FailureThreshold, &out.FailureThreshold
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateFailurePolicy.
+func (in *UpdateFailurePolicy) DeepCopy() *UpdateFailurePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateFailurePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
 	*out = *in
@@ -861,6 +1224,26 @@ func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.MaxConcurrency != nil {
+		in, out := &in.MaxConcurrency, &out.MaxConcurrency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(RollbackConfig)
+		**out = **in
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailurePolicy != nil {
+		in, out := &in.FailurePolicy, &out.FailurePolicy
+		*out = new(UpdateFailurePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateStrategy.
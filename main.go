@@ -35,6 +35,8 @@ import (
 	"kusionstack.io/operating/apis"
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 	"kusionstack.io/operating/pkg/controllers"
+	"kusionstack.io/operating/pkg/controllers/resourcecontext"
+	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
 	"kusionstack.io/operating/pkg/utils/feature"
 	"kusionstack.io/operating/pkg/utils/inject"
 	"kusionstack.io/operating/pkg/webhook"
@@ -70,6 +72,9 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&certDir, "cert-dir", webhookTempCertDir(), "The directory that contains the server key and certificate. If not set, webhook server would look up the server key and certificate in {TempDir}/k8s-webhook-server/serving-certs")
 	flag.StringVar(&dnsName, "dns-name", "kusionstack-controller-manager.kusionstack-system.svc", "The DNS name of the webhook server.")
+	flag.IntVar(&controllerutils.MaxConcurrentReconciles, "max-concurrent-reconciles", controllerutils.MaxConcurrentReconciles, "The maximum number of concurrent reconciles of each controller.")
+	flag.IntVar(&controllerutils.SlowStartMaxBatchSize, "slow-start-max-batch-size", controllerutils.SlowStartMaxBatchSize, "The maximum number of resources operated concurrently in a batch by controllers. Non-positive value means no limit.")
+	flag.DurationVar(&resourcecontext.LeakedIDGracePeriod, "leaked-id-grace-period", resourcecontext.LeakedIDGracePeriod, "The duration to wait before reclaiming the IDs in ResourceContext whose owner no longer exists.")
 
 	klog.InitFlags(nil)
 	defer klog.Flush()
@@ -25,7 +25,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -35,6 +38,7 @@ import (
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
 	"kusionstack.io/operating/pkg/controllers/collaset/podcontrol"
+	"kusionstack.io/operating/pkg/controllers/collaset/pvccontrol"
 	"kusionstack.io/operating/pkg/controllers/collaset/synccontrol"
 	"kusionstack.io/operating/pkg/controllers/collaset/utils"
 	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
@@ -57,6 +61,7 @@ type CollaSetReconciler struct {
 
 	revisionManager *revision.RevisionManager
 	syncControl     synccontrol.Interface
+	pvcControl      pvccontrol.Interface
 }
 
 func Add(mgr ctrl.Manager) error {
@@ -68,17 +73,19 @@ func NewReconciler(mgr ctrl.Manager) reconcile.Reconciler {
 	mixin := mixin.NewReconcilerMixin(controllerName, mgr)
 	collasetutils.InitExpectations(mixin.Client)
 
+	pvcControl := pvccontrol.NewRealPvcControl(mixin.Client)
 	return &CollaSetReconciler{
 		ReconcilerMixin: mixin,
 		revisionManager: revision.NewRevisionManager(mixin.Client, mixin.Scheme, &revisionOwnerAdapter{}),
-		syncControl:     synccontrol.NewRealSyncControl(mixin.Client, mixin.Logger, podcontrol.NewRealPodControl(mixin.Client, mixin.Scheme), mixin.Recorder),
+		syncControl:     synccontrol.NewRealSyncControl(mixin.Client, mixin.Logger, podcontrol.NewRealPodControl(mixin.Client, mixin.Scheme), pvcControl, mixin.Recorder),
+		pvcControl:      pvcControl,
 	}
 }
 
 func AddToMgr(mgr ctrl.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
 	c, err := controller.New(controllerName, mgr, controller.Options{
-		MaxConcurrentReconciles: 5,
+		MaxConcurrentReconciles: controllerutils.MaxConcurrentReconciles,
 		Reconciler:              r,
 	})
 	if err != nil {
@@ -98,9 +105,64 @@ func AddToMgr(mgr ctrl.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// PVCs are not always owned by CollaSet according to PersistentVolumeClaimRetentionPolicy
+	err = c.Watch(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, handler.EnqueueRequestsFromMapFunc(enqueueCollaSetForPvc), &PodPredicate{})
+	if err != nil {
+		return err
+	}
+
+	// the released IDs in the shared ResourceContext are adopted by the CollaSets waiting for them
+	err = c.Watch(&source.Kind{Type: &appsv1alpha1.ResourceContext{}}, handler.EnqueueRequestsFromMapFunc(enqueueCollaSetsToAdoptIDs(mgr.GetClient())))
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func enqueueCollaSetsToAdoptIDs(c client.Client) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		clsList := &appsv1alpha1.CollaSetList{}
+		if err := c.List(context.TODO(), clsList, client.InNamespace(obj.GetNamespace())); err != nil {
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range clsList.Items {
+			cls := &clsList.Items[i]
+			if len(cls.Spec.ScaleStrategy.IDToAdopt) == 0 {
+				continue
+			}
+
+			contextName := cls.Spec.ScaleStrategy.Context
+			if contextName == "" {
+				contextName = cls.Name
+			}
+			if contextName != obj.GetName() {
+				continue
+			}
+
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cls.Namespace, Name: cls.Name}})
+		}
+
+		return requests
+	}
+}
+
+func enqueueCollaSetForPvc(obj client.Object) []reconcile.Request {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return nil
+	}
+
+	name, ok := pvccontrol.ParseCollaSetName(pvc)
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: pvc.Namespace, Name: name}}}
+}
+
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=collasets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=collasets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=collasets/finalizers,verbs=update
@@ -109,7 +171,9 @@ func AddToMgr(mgr ctrl.Manager, r reconcile.Reconciler) error {
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=resourcecontexts/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -154,11 +218,20 @@ func (r *CollaSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, fmt.Errorf("fail to construct revision for CollaSet %s/%s: %s", instance.Namespace, instance.Name, err)
 	}
 
+	// roll back to the indicated revision, and Pods will be updated to it in the following reconciling
+	if rolledBack, err := r.rollback(ctx, instance, updatedRevision, revisions); err != nil || rolledBack {
+		return ctrl.Result{}, err
+	}
+
 	newStatus := &appsv1alpha1.CollaSetStatus{
 		// record collisionCount
 		CollisionCount:  collisionCount,
 		CurrentRevision: currentRevision.Name,
 		UpdatedRevision: updatedRevision.Name,
+		Conditions:      instance.Status.Conditions,
+		LastRollback:    instance.Status.LastRollback,
+		UpdateBatch:     instance.Status.UpdateBatch,
+		UpdateProgress:  instance.Status.UpdateProgress,
 	}
 
 	requeueAfter, newStatus, err := r.DoReconcile(instance, updatedRevision, revisions, newStatus)
@@ -167,19 +240,47 @@ func (r *CollaSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{RequeueAfter: requeueAfter}, fmt.Errorf("fail to update status of CollaSet %s: %s", req, err)
 	}
 
+	// roll back to the current revision automatically if updating fails
+	if rollbackTo := getAutoRollbackTo(instance, currentRevision, updatedRevision, newStatus); err == nil && rollbackTo != nil {
+		toRollback := instance.DeepCopy()
+		toRollback.Spec.UpdateStrategy.RollbackTo = &appsv1alpha1.RollbackConfig{Revision: *rollbackTo}
+		if _, err := r.rollback(ctx, toRollback, updatedRevision, revisions); err != nil {
+			return ctrl.Result{RequeueAfter: requeueAfter}, err
+		}
+	}
+
 	return ctrl.Result{RequeueAfter: requeueAfter}, err
 }
 
 func (r *CollaSetReconciler) DoReconcile(instance *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, revisions []*appsv1.ControllerRevision, newStatus *appsv1alpha1.CollaSetStatus) (time.Duration, *appsv1alpha1.CollaSetStatus, error) {
 	podWrappers, newStatus, requeueAfter, syncErr := r.doSync(instance, updatedRevision, revisions, newStatus)
-	return requeueAfter, calculateStatus(instance, newStatus, updatedRevision, podWrappers, syncErr), syncErr
+	newStatus = calculateStatus(instance, newStatus, updatedRevision, podWrappers, syncErr)
+	if progressRequeueAfter := r.checkProgress(instance, updatedRevision, podWrappers, newStatus); progressRequeueAfter > 0 && (requeueAfter == 0 || progressRequeueAfter < requeueAfter) {
+		requeueAfter = progressRequeueAfter
+	}
+
+	// requeue to recount the Pods and continue updating once they are ready for MinReadySeconds
+	for _, podWrapper := range podWrappers {
+		if _, waitFor := synccontrol.IsPodAvailable(instance, podWrapper.Pod); waitFor > 0 && (requeueAfter == 0 || waitFor < requeueAfter) {
+			requeueAfter = waitFor
+		}
+	}
+
+	return requeueAfter, newStatus, syncErr
 }
 
 // doSync is responsible for reconcile Pods with CollaSet spec.
 // 1. sync Pods to prepare information, especially IDs, for following Scale and Update
 // 2. scale Pods to match the Pod number indicated in `spec.replcas`. if an error thrown out or Pods is not matched recently, update will be skipped.
 // 3. update Pods, to update each Pod to the updated revision indicated by `spec.template`
+// If CollaSet is paused, Scale and Update will only finish the PodOpsLifecycles which have already begun.
 func (r *CollaSetReconciler) doSync(instance *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, revisions []*appsv1.ControllerRevision, newStatus *appsv1alpha1.CollaSetStatus) ([]*collasetutils.PodWrapper, *appsv1alpha1.CollaSetStatus, time.Duration, error) {
+	if instance.Spec.Paused {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetPaused, nil, "Paused", "scaling and updating are paused")
+	} else {
+		collasetutils.RemoveCondition(newStatus, appsv1alpha1.CollaSetPaused)
+	}
+
 	synced, podWrappers, ownedIDs, err := r.syncControl.SyncPods(instance, updatedRevision, newStatus)
 	if err != nil || synced {
 		return podWrappers, newStatus, 0, err
@@ -204,7 +305,7 @@ func calculateStatus(instance *appsv1alpha1.CollaSet, newStatus *appsv1alpha1.Co
 	}
 
 	var scheduledReplicas, readyReplicas, availableReplicas, replicas, updatedReplicas, operatingReplicas,
-		updatedReadyReplicas, updatedAvailableReplicas int32
+		updatedReadyReplicas, updatedAvailableReplicas, unavailableReplicas int32
 
 	for _, podWrapper := range podWrappers {
 		replicas++
@@ -229,12 +330,16 @@ func calculateStatus(instance *appsv1alpha1.CollaSet, newStatus *appsv1alpha1.Co
 			}
 		}
 
-		if controllerutils.IsServiceAvailable(podWrapper.Pod) {
+		if available, _ := synccontrol.IsPodAvailable(instance, podWrapper.Pod); available {
 			availableReplicas++
 			if isUpdated {
 				updatedAvailableReplicas++
 			}
 		}
+
+		if synccontrol.IsPodUnavailable(instance, podWrapper.Pod) {
+			unavailableReplicas++
+		}
 	}
 
 	newStatus.ScheduledReplicas = scheduledReplicas
@@ -245,6 +350,16 @@ func calculateStatus(instance *appsv1alpha1.CollaSet, newStatus *appsv1alpha1.Co
 	newStatus.OperatingReplicas = operatingReplicas
 	newStatus.UpdatedReadyReplicas = updatedReadyReplicas
 	newStatus.UpdatedAvailableReplicas = updatedAvailableReplicas
+	newStatus.UnavailableReplicas = unavailableReplicas
+	if selector, err := metav1.LabelSelectorAsSelector(instance.Spec.Selector); err == nil {
+		newStatus.Selector = selector.String()
+	}
+	newStatus.TopologyDomains = calculateTopologyDomains(instance, podWrappers)
+	newStatus.MaxUnavailableReplicas = nil
+	if maxUnavailable, limited := synccontrol.GetMaxUnavailable(instance); limited {
+		maxUnavailableReplicas := int32(maxUnavailable)
+		newStatus.MaxUnavailableReplicas = &maxUnavailableReplicas
+	}
 
 	if (instance.Spec.Replicas == nil && newStatus.UpdatedReadyReplicas >= 0) ||
 		newStatus.UpdatedReadyReplicas >= *instance.Spec.Replicas {
@@ -254,6 +369,36 @@ func calculateStatus(instance *appsv1alpha1.CollaSet, newStatus *appsv1alpha1.Co
 	return newStatus
 }
 
+// calculateTopologyDomains counts the Pods in each topology domain.
+func calculateTopologyDomains(instance *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper) []appsv1alpha1.CollaSetTopologyDomainStatus {
+	desiredReplicas := synccontrol.GetTopologyDomainReplicas(instance)
+	if desiredReplicas == nil {
+		return nil
+	}
+
+	domains := make([]appsv1alpha1.CollaSetTopologyDomainStatus, len(instance.Spec.Topology.Domains))
+	indexes := map[string]int{}
+	for i, domain := range instance.Spec.Topology.Domains {
+		indexes[domain.Name] = i
+		domains[i].Name = domain.Name
+		domains[i].DesiredReplicas = int32(desiredReplicas[i])
+	}
+
+	for _, podWrapper := range podWrappers {
+		i, exist := indexes[synccontrol.GetPodTopologyDomain(podWrapper.Pod)]
+		if !exist {
+			continue
+		}
+
+		domains[i].Replicas++
+		if available, _ := synccontrol.IsPodAvailable(instance, podWrapper.Pod); available {
+			domains[i].AvailableReplicas++
+		}
+	}
+
+	return domains
+}
+
 func (r *CollaSetReconciler) updateStatus(ctx context.Context, instance *appsv1alpha1.CollaSet, newStatus *appsv1alpha1.CollaSetStatus) error {
 	if equality.Semantic.DeepEqual(instance.Status, newStatus) {
 		return nil
@@ -272,6 +417,11 @@ func (r *CollaSetReconciler) updateStatus(ctx context.Context, instance *appsv1a
 }
 
 func (r *CollaSetReconciler) reclaimResourceContext(cls *appsv1alpha1.CollaSet) error {
+	// delete or retain PVCs according to PersistentVolumeClaimRetentionPolicy
+	if err := r.reclaimPvcs(cls); err != nil {
+		return err
+	}
+
 	// clean the owner IDs from this CollaSet
 	if err := podcontext.UpdateToPodContext(r.Client, cls, nil); err != nil {
 		return err
@@ -279,3 +429,18 @@ func (r *CollaSetReconciler) reclaimResourceContext(cls *appsv1alpha1.CollaSet)
 
 	return controllerutils.RemoveFinalizer(context.TODO(), r.Client, cls, preReclaimFinalizer)
 }
+
+func (r *CollaSetReconciler) reclaimPvcs(cls *appsv1alpha1.CollaSet) error {
+	// the PVCs adopted along with IDs are garbage collected by owner reference, unless retained
+	pvcs, err := r.pvcControl.GetFilteredPvcs(cls, nil)
+	if err != nil {
+		return fmt.Errorf("fail to get PVCs of CollaSet %s/%s: %s", cls.Namespace, cls.Name, err)
+	}
+
+	if pvccontrol.RetainPvcsWhenDeleted(cls) {
+		// release PVCs from CollaSet, so that they will not be garbage collected
+		return r.pvcControl.SyncPvcOwnerRefs(cls, pvcs)
+	}
+
+	return r.pvcControl.DeletePvcs(cls, pvcs)
+}
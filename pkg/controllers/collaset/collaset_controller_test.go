@@ -28,9 +28,14 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -44,6 +49,8 @@ import (
 
 	"kusionstack.io/operating/apis"
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
+	"kusionstack.io/operating/pkg/controllers/collaset/pvccontrol"
 	"kusionstack.io/operating/pkg/controllers/collaset/synccontrol"
 	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
 	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
@@ -179,8 +186,8 @@ var _ = Describe("collaset controller", func() {
 		}, 5*time.Second, 1*time.Second).Should(BeNil())
 	})
 
-	It("update reconcile", func() {
-		testcase := "test-update"
+	It("scale by scale subresource", func() {
+		testcase := "test-scale-subresource"
 		Expect(createNamespace(c, testcase)).Should(BeNil())
 
 		cs := &appsv1alpha1.CollaSet{
@@ -189,7 +196,7 @@ var _ = Describe("collaset controller", func() {
 				Name:      "foo",
 			},
 			Spec: appsv1alpha1.CollaSetSpec{
-				Replicas: int32Pointer(4),
+				Replicas: int32Pointer(2),
 				Selector: &metav1.LabelSelector{
 					MatchLabels: map[string]string{
 						"app": "foo",
@@ -210,9 +217,6 @@ var _ = Describe("collaset controller", func() {
 						},
 					},
 				},
-				UpdateStrategy: appsv1alpha1.UpdateStrategy{
-					OperationDelaySeconds: int32Pointer(1),
-				},
 			},
 		}
 
@@ -221,158 +225,67 @@ var _ = Describe("collaset controller", func() {
 		podList := &corev1.PodList{}
 		Eventually(func() bool {
 			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-			return len(podList.Items) == 4
+			return len(podList.Items) == 2
 		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 2, 2, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		Expect(cs.Status.Selector).Should(BeEquivalentTo("app=foo"))
+
+		// read the scale subresource as HorizontalPodAutoscaler does
+		dynamicClient, err := dynamic.NewForConfig(mgr.GetConfig())
+		Expect(err).Should(BeNil())
+		scaleClient := dynamicClient.Resource(appsv1alpha1.GroupVersion.WithResource("collasets")).Namespace(cs.Namespace)
+		scale, err := scaleClient.Get(context.TODO(), cs.Name, metav1.GetOptions{}, "scale")
+		Expect(err).Should(BeNil())
+		specReplicas, _, _ := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+		statusReplicas, _, _ := unstructured.NestedInt64(scale.Object, "status", "replicas")
+		selector, _, _ := unstructured.NestedString(scale.Object, "status", "selector")
+		Expect(specReplicas).Should(BeEquivalentTo(2))
+		Expect(statusReplicas).Should(BeEquivalentTo(2))
+		Expect(selector).Should(BeEquivalentTo("app=foo"))
+
+		// scale in through the scale subresource
+		Expect(unstructured.SetNestedField(scale.Object, int64(1), "spec", "replicas")).Should(BeNil())
+		_, err = scaleClient.Update(context.TODO(), scale, metav1.UpdateOptions{}, "scale")
+		Expect(err).Should(BeNil())
 		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
-		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 4, 4, 0, 0, 0)).Should(BeNil())
-
-		// test update ByPartition
-		for _, partition := range []int32{0, 1, 2, 3, 4} {
-			observedGeneration := cs.Status.ObservedGeneration
-
-			// update CollaSet image and partition
-			Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
-				cls.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateCollaSetStrategy{
-					ByPartition: &appsv1alpha1.ByPartition{
-						Partition: &partition,
-					},
-				}
-				cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
-
-				return true
-			})).Should(BeNil())
-			Eventually(func() bool {
-				Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
-				return cs.Status.ObservedGeneration != observedGeneration
-			}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(*cs.Spec.Replicas).Should(BeEquivalentTo(1))
 
-			podList := &corev1.PodList{}
+		// the Pod to scale in should go through PodOpsLifecycle
+		var podToScaleIn *corev1.Pod
+		Eventually(func() bool {
 			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
 			for i := range podList.Items {
-				pod := &podList.Items[i]
-				if !podopslifecycle.IsDuringOps(collasetutils.UpdateOpsLifecycleAdapter, pod) {
-					continue
-				}
-
-				if _, allowed := podopslifecycle.AllowOps(collasetutils.UpdateOpsLifecycleAdapter, 0, pod); allowed {
-					continue
-				}
-				// allow Pod to do update
-				Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
-					labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
-					pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+				if podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, &podList.Items[i]) {
+					podToScaleIn = &podList.Items[i]
 					return true
-				})).Should(BeNil())
-			}
-			// check updated pod replicas by CollaSet status
-			Eventually(func() error {
-				return expectedStatusReplicas(c, cs, 0, 0, 0, 4, partition, partition, 0, 0)
-			}, 5*time.Second, 1*time.Second).Should(BeNil())
-
-			// double check updated pod replicas
-			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-			updatedReplicas := 0
-			for _, pod := range podList.Items {
-				if pod.Spec.Containers[0].Image == cs.Spec.Template.Spec.Containers[0].Image {
-					updatedReplicas++
-					Expect(pod.Annotations).ShouldNot(BeNil())
-					Expect(pod.Annotations[appsv1alpha1.LastPodStatusAnnotationKey]).ShouldNot(BeEquivalentTo(""))
-
-					podStatus := &synccontrol.PodStatus{}
-					Expect(json.Unmarshal([]byte(pod.Annotations[appsv1alpha1.LastPodStatusAnnotationKey]), podStatus)).Should(BeNil())
-					Expect(len(podStatus.ContainerStates)).Should(BeEquivalentTo(1))
-					Expect(podStatus.ContainerStates["foo"].LatestImage).Should(BeEquivalentTo("nginx:v2"))
-				}
-			}
-			Expect(updatedReplicas).Should(BeEquivalentTo(partition))
-		}
-
-		// mock Pods updated by kubelet
-		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-		for i := range podList.Items {
-			Expect(updatePodStatusWithRetry(c, podList.Items[i].Namespace, podList.Items[i].Name, func(pod *corev1.Pod) bool {
-				pod.Status.ContainerStatuses = []corev1.ContainerStatus{
-					{
-						Name:    "foo",
-						ImageID: "id:v2",
-					},
 				}
-				return true
-			})).Should(BeNil())
-		}
-
-		Eventually(func() error {
-			return expectedStatusReplicas(c, cs, 0, 0, 0, 4, 4, 0, 0, 0)
-		}, 5*time.Second, 1*time.Second).Should(BeNil())
-
-		// test update ByLabel
-		observedGeneration := cs.Status.ObservedGeneration
-		// update CollaSet image
-		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
-			cls.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateCollaSetStrategy{
-				ByLabel: &appsv1alpha1.ByLabel{},
 			}
-			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v3"
-
+			return false
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(podToScaleIn).ShouldNot(BeNil())
+		Expect(updatePodWithRetry(c, podToScaleIn.Namespace, podToScaleIn.Name, func(pod *corev1.Pod) bool {
+			labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+			pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
 			return true
 		})).Should(BeNil())
-		Eventually(func() bool {
-			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
-			return cs.Status.ObservedGeneration != observedGeneration
-		}, 5*time.Second, 1*time.Second).Should(BeTrue())
 
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 1, 1, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
 		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-		for _, number := range []int32{1, 2, 3, 4} {
-			pod := podList.Items[number-1]
-			// label pod to trigger update
-			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
-				if pod.Labels == nil {
-					pod.Labels = map[string]string{}
-				}
-				pod.Labels[appsv1alpha1.CollaSetUpdateIndicateLabelKey] = "true"
-				return true
-			})).Should(BeNil())
-
-			Eventually(func() error {
-				// check updated pod replicas by CollaSet status
-				return expectedStatusReplicas(c, cs, 0, 0, 0, 4, number, 1, 0, 0)
-			}, 5*time.Second, 1*time.Second).Should(BeNil())
-
-			Expect(updatePodStatusWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
-				pod.Status.ContainerStatuses = []corev1.ContainerStatus{
-					{
-						Name:    "foo",
-						ImageID: "id:v3",
-					},
-				}
-				return true
-			})).Should(BeNil())
-
-			// double check updated pod replicas
-			podList := &corev1.PodList{}
-			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-			updatedReplicas := 0
-			for _, pod := range podList.Items {
-				Eventually(func() bool {
-					tmpPod := &corev1.Pod{}
-					Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, tmpPod)).Should(BeNil())
-					return tmpPod.Labels[appsv1alpha1.CollaSetUpdateIndicateLabelKey] == ""
-				}, 5*time.Second, 1*time.Second).Should(BeTrue())
-				if pod.Spec.Containers[0].Image == cs.Spec.Template.Spec.Containers[0].Image {
-					updatedReplicas++
-				}
-			}
-			Expect(updatedReplicas).Should(BeEquivalentTo(number))
+		Expect(len(podList.Items)).Should(BeEquivalentTo(1))
+		Expect(podList.Items[0].Name).ShouldNot(BeEquivalentTo(podToScaleIn.Name))
 
-			Eventually(func() error {
-				// check updated pod replicas by CollaSet status
-				return expectedStatusReplicas(c, cs, 0, 0, 0, 4, number, 0, 0, 0)
-			}, 5*time.Second, 1*time.Second).Should(BeNil())
-		}
+		scale, err = scaleClient.Get(context.TODO(), cs.Name, metav1.GetOptions{}, "scale")
+		Expect(err).Should(BeNil())
+		statusReplicas, _, _ = unstructured.NestedInt64(scale.Object, "status", "replicas")
+		Expect(statusReplicas).Should(BeEquivalentTo(1))
 	})
 
-	It("update pod policy", func() {
-		testcase := "test-update-pod-policy"
+	It("spread pods by topology", func() {
+		testcase := "test-topology"
 		Expect(createNamespace(c, testcase)).Should(BeNil())
 
 		cs := &appsv1alpha1.CollaSet{
@@ -381,7 +294,7 @@ var _ = Describe("collaset controller", func() {
 				Name:      "foo",
 			},
 			Spec: appsv1alpha1.CollaSetSpec{
-				Replicas: int32Pointer(1),
+				Replicas: int32Pointer(3),
 				Selector: &metav1.LabelSelector{
 					MatchLabels: map[string]string{
 						"app": "foo",
@@ -390,10 +303,7 @@ var _ = Describe("collaset controller", func() {
 				Template: corev1.PodTemplateSpec{
 					ObjectMeta: metav1.ObjectMeta{
 						Labels: map[string]string{
-							"app":   "foo",
-							"test1": "v1",
-							"test2": "v1",
-							"test3": "v1",
+							"app": "foo",
 						},
 					},
 					Spec: corev1.PodSpec{
@@ -405,6 +315,14 @@ var _ = Describe("collaset controller", func() {
 						},
 					},
 				},
+				Topology: &appsv1alpha1.CollaSetTopology{
+					TopologyKey: corev1.LabelTopologyZone,
+					Domains: []appsv1alpha1.CollaSetTopologyDomain{
+						{Name: "zone-a", Values: []string{"a"}},
+						{Name: "zone-b", Values: []string{"b"}},
+						{Name: "zone-c", Values: []string{"c"}},
+					},
+				},
 			},
 		}
 
@@ -413,88 +331,63 @@ var _ = Describe("collaset controller", func() {
 		podList := &corev1.PodList{}
 		Eventually(func() bool {
 			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-			return len(podList.Items) == 1
+			return len(podList.Items) == 3
 		}, 5*time.Second, 1*time.Second).Should(BeTrue())
-		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
-		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 1, 1, 0, 0, 0)).Should(BeNil())
-
-		pod := podList.Items[0]
-		// test attribute updated manually
-		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
-			if pod.Labels == nil {
-				pod.Labels = map[string]string{}
-			}
-			pod.Labels["test1"] = "v2"
-			pod.Labels["manual-added"] = "v1"
-			delete(pod.Labels, "test2")
-			return true
-		})).Should(BeNil())
 
-		observedGeneration := cs.Status.ObservedGeneration
-		// update CollaSet image
-		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
-			cls.Spec.Template.Labels["test1"] = "v3"
-			delete(cls.Spec.Template.Labels, "test3")
-			return true
-		})).Should(BeNil())
-
-		// allow Pod to update
-		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
-			labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
-			pod.Labels[labelOperate] = "true"
-			return true
-		})).Should(BeNil())
+		// each Pod is assigned to a different zone, and required to be scheduled to it
+		domains := sets.String{}
+		for _, pod := range podList.Items {
+			domain := synccontrol.GetPodTopologyDomain(&pod)
+			domains.Insert(domain)
+			Expect(pod.Spec.Affinity).ShouldNot(BeNil())
+			Expect(pod.Spec.Affinity.NodeAffinity).ShouldNot(BeNil())
+			terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+			Expect(len(terms)).Should(BeEquivalentTo(1))
+			Expect(terms[0].MatchExpressions[0].Key).Should(BeEquivalentTo(corev1.LabelTopologyZone))
+			Expect(terms[0].MatchExpressions[0].Values).Should(BeEquivalentTo([]string{strings.TrimPrefix(domain, "zone-")}))
+		}
+		Expect(domains.List()).Should(BeEquivalentTo([]string{"zone-a", "zone-b", "zone-c"}))
 
 		Eventually(func() bool {
 			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
-			return cs.Status.ObservedGeneration != observedGeneration && cs.Status.UpdatedReplicas == 1
+			return len(cs.Status.TopologyDomains) == 3 && cs.Status.TopologyDomains[2].Replicas == 1
 		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		for _, domain := range cs.Status.TopologyDomains {
+			Expect(domain.DesiredReplicas).Should(BeEquivalentTo(1))
+			Expect(domain.Replicas).Should(BeEquivalentTo(1))
+		}
 
-		// check pod label
-		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).Should(BeNil())
-		Expect(pod.Labels).ShouldNot(BeNil())
-		// manual changes should have lower priority than PodTemplate changes
-		Expect(pod.Labels["test1"]).Should(BeEquivalentTo("v3"))
-		Expect(pod.Labels["manual-added"]).Should(BeEquivalentTo("v1"))
-
-		_, exist := pod.Labels["test2"]
-		Expect(exist).Should(BeFalse())
-		_, exist = pod.Labels["test3"]
-		Expect(exist).Should(BeFalse())
-
-		// test Recreate policy
-		observedGeneration = cs.Status.ObservedGeneration
-		// update CollaSet image
+		// scale in the Pod in the domain exceeding its expected number
 		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
-			cls.Spec.Template.Labels["test1"] = "v4"
-			cls.Spec.UpdateStrategy.PodUpdatePolicy = appsv1alpha1.CollaSetRecreatePodUpdateStrategyType
+			cls.Spec.Replicas = int32Pointer(2)
 			return true
 		})).Should(BeNil())
+
 		Eventually(func() bool {
-			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
-			return cs.Status.ObservedGeneration != observedGeneration && cs.Status.UpdatedReplicas == 1
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			for i := range podList.Items {
+				pod := &podList.Items[i]
+				if podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, pod) {
+					Expect(synccontrol.GetPodTopologyDomain(pod)).Should(BeEquivalentTo("zone-c"))
+					Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+						labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+						pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+						return true
+					})).Should(BeNil())
+				}
+			}
+			return len(podList.Items) == 2
 		}, 5*time.Second, 1*time.Second).Should(BeTrue())
 
-		// pod should be recreated
-		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).ShouldNot(BeNil())
-
-		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-		Expect(len(podList.Items)).Should(BeEquivalentTo(1))
-		pod = podList.Items[0]
-		// check pod label
-		Expect(pod.Labels).ShouldNot(BeNil())
-		// manual changes should have lower priority than PodTemplate changes
-		Expect(pod.Labels["test1"]).Should(BeEquivalentTo("v4"))
-		Expect(pod.Labels["test2"]).Should(BeEquivalentTo("v1"))
-
-		_, exist = pod.Labels["manual-added"]
-		Expect(exist).Should(BeFalse())
-		_, exist = pod.Labels["test3"]
-		Expect(exist).Should(BeFalse())
+		domains = sets.String{}
+		for i := range podList.Items {
+			domains.Insert(synccontrol.GetPodTopologyDomain(&podList.Items[i]))
+		}
+		Expect(domains.List()).Should(BeEquivalentTo([]string{"zone-a", "zone-b"}))
 	})
 
-	It("pod recreate with its current revision", func() {
-		testcase := "test-pod-recreate-with-its-revision"
+	It("name pods by instance ID", func() {
+		testcase := "test-naming"
 		Expect(createNamespace(c, testcase)).Should(BeNil())
 
 		cs := &appsv1alpha1.CollaSet{
@@ -524,6 +417,9 @@ var _ = Describe("collaset controller", func() {
 						},
 					},
 				},
+				ScaleStrategy: appsv1alpha1.ScaleStrategy{
+					PodNamingPolicy: appsv1alpha1.InstanceIDPodNamingPolicyType,
+				},
 			},
 		}
 
@@ -534,68 +430,2142 @@ var _ = Describe("collaset controller", func() {
 			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
 			return len(podList.Items) == 2
 		}, 5*time.Second, 1*time.Second).Should(BeTrue())
-		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
-		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 2, 2, 0, 0, 0)).Should(BeNil())
 
-		var partition int32 = 1
-		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
-			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
-			cls.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateCollaSetStrategy{
-				ByPartition: &appsv1alpha1.ByPartition{
-					Partition: &partition,
-				},
-			}
+		names := sets.String{}
+		for _, pod := range podList.Items {
+			names.Insert(pod.Name)
+		}
+		Expect(names.List()).Should(BeEquivalentTo([]string{"foo-0", "foo-1"}))
+
+		// the failed Pod is deleted to release its name, and recreated with the same name
+		failedPod := &corev1.Pod{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: "foo-1"}, failedPod)).Should(BeNil())
+		Expect(updatePodStatusWithRetry(c, cs.Namespace, failedPod.Name, func(pod *corev1.Pod) bool {
+			pod.Status.Phase = corev1.PodFailed
 			return true
-		}))
+		})).Should(BeNil())
 
+		Eventually(func() bool {
+			pod := &corev1.Pod{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: "foo-1"}, pod); err != nil {
+				return false
+			}
+			return pod.UID != failedPod.UID && pod.Status.Phase != corev1.PodFailed
+		}, 10*time.Second, 1*time.Second).Should(BeTrue())
+	})
+
+	It("allocate ids with strategy", func() {
+		testcase := "test-id-allocation"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(3),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				ScaleStrategy: appsv1alpha1.ScaleStrategy{
+					IDAllocationStrategy: &appsv1alpha1.IDAllocationStrategy{
+						IDs: []int{5, 3},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		// only the Pods with listed IDs are created
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		ids := sets.NewInt()
+		for i := range podList.Items {
+			id, err := collasetutils.GetPodInstanceID(&podList.Items[i])
+			Expect(err).Should(BeNil())
+			ids.Insert(id)
+		}
+		Expect(ids.List()).Should(BeEquivalentTo([]int{3, 5}))
+
+		// the shortage of IDs is reported as condition
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			cond := collasetutils.GetCondition(&cs.Status, appsv1alpha1.CollaSetIDAllocation)
+			return cond != nil && cond.Status == corev1.ConditionFalse
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// the condition is removed after more IDs are allowed
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.ScaleStrategy.IDAllocationStrategy.IDs = []int{5, 3, 7}
+			return true
+		})).Should(BeNil())
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return len(podList.Items) == 3 && collasetutils.GetCondition(&cs.Status, appsv1alpha1.CollaSetIDAllocation) == nil
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+	})
+
+	It("create pods in order", func() {
+		testcase := "test-ordered-ready"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas:            int32Pointer(2),
+				PodManagementPolicy: appsv1alpha1.OrderedReadyPodManagementPolicyType,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// the next Pod is not created until the first one is service available
+		Consistently(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 3*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(podList.Items[0].Labels[appsv1alpha1.PodInstanceIDLabelKey]).Should(BeEquivalentTo("0"))
+
+		pod := &podList.Items[0]
+		Expect(updatePodStatusWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+			pod.Status.Conditions = []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Now()},
+			}
+			return true
+		})).Should(BeNil())
+		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+			pod.Labels[appsv1alpha1.PodServiceAvailableLabel] = "true"
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+	})
+
+	It("resolve template variables", func() {
+		testcase := "test-template-variables"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app":   "foo",
+							"shard": "shard-${context.shard}",
+						},
+						Annotations: map[string]string{
+							"instance": "foo-${context.ID}",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+								Env: []corev1.EnvVar{
+									{Name: "ROLE", Value: "${context.role}"},
+								},
+							},
+						},
+					},
+				},
+				ScaleStrategy: appsv1alpha1.ScaleStrategy{
+					InstanceData: []appsv1alpha1.InstanceIDData{
+						{ID: 0, Data: map[string]string{"shard": "0", "role": "leader"}},
+						{ID: 1, Data: map[string]string{"shard": "1", "role": "follower"}},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		roles := map[string]string{"0": "leader", "1": "follower"}
+		for _, pod := range podList.Items {
+			id := pod.Labels[appsv1alpha1.PodInstanceIDLabelKey]
+			Expect(pod.Annotations["instance"]).Should(BeEquivalentTo(fmt.Sprintf("foo-%s", id)))
+			Expect(pod.Labels["shard"]).Should(BeEquivalentTo(fmt.Sprintf("shard-%s", id)))
+			Expect(pod.Spec.Containers[0].Env[0].Value).Should(BeEquivalentTo(roles[id]))
+		}
+	})
+
+	It("update reconcile", func() {
+		testcase := "test-update"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(4),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				UpdateStrategy: appsv1alpha1.UpdateStrategy{
+					OperationDelaySeconds: int32Pointer(1),
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 4
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 4, 4, 0, 0, 0)).Should(BeNil())
+
+		// test update ByPartition
+		for _, partition := range []int32{0, 1, 2, 3, 4} {
+			observedGeneration := cs.Status.ObservedGeneration
+
+			// update CollaSet image and partition
+			Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+				cls.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateCollaSetStrategy{
+					ByPartition: &appsv1alpha1.ByPartition{
+						Partition: &partition,
+					},
+				}
+				cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+
+				return true
+			})).Should(BeNil())
+			Eventually(func() bool {
+				Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+				return cs.Status.ObservedGeneration != observedGeneration
+			}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+			podList := &corev1.PodList{}
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			for i := range podList.Items {
+				pod := &podList.Items[i]
+				if !podopslifecycle.IsDuringOps(collasetutils.UpdateOpsLifecycleAdapter, pod) {
+					continue
+				}
+
+				if _, allowed := podopslifecycle.AllowOps(collasetutils.UpdateOpsLifecycleAdapter, 0, pod); allowed {
+					continue
+				}
+				// allow Pod to do update
+				Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+					labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+					pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+					return true
+				})).Should(BeNil())
+			}
+			// check updated pod replicas by CollaSet status
+			Eventually(func() error {
+				return expectedStatusReplicas(c, cs, 0, 0, 0, 4, partition, partition, 0, 0)
+			}, 5*time.Second, 1*time.Second).Should(BeNil())
+
+			// double check updated pod replicas
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			updatedReplicas := 0
+			for _, pod := range podList.Items {
+				if pod.Spec.Containers[0].Image == cs.Spec.Template.Spec.Containers[0].Image {
+					updatedReplicas++
+					Expect(pod.Annotations).ShouldNot(BeNil())
+					Expect(pod.Annotations[appsv1alpha1.LastPodStatusAnnotationKey]).ShouldNot(BeEquivalentTo(""))
+
+					podStatus := &synccontrol.PodStatus{}
+					Expect(json.Unmarshal([]byte(pod.Annotations[appsv1alpha1.LastPodStatusAnnotationKey]), podStatus)).Should(BeNil())
+					Expect(len(podStatus.ContainerStates)).Should(BeEquivalentTo(1))
+					Expect(podStatus.ContainerStates["foo"].LatestImage).Should(BeEquivalentTo("nginx:v2"))
+				}
+			}
+			Expect(updatedReplicas).Should(BeEquivalentTo(partition))
+		}
+
+		// mock Pods updated by kubelet
+		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		for i := range podList.Items {
+			Expect(updatePodStatusWithRetry(c, podList.Items[i].Namespace, podList.Items[i].Name, func(pod *corev1.Pod) bool {
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+					{
+						Name:    "foo",
+						ImageID: "id:v2",
+					},
+				}
+				return true
+			})).Should(BeNil())
+		}
+
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 4, 4, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+
+		// test update ByLabel
+		observedGeneration := cs.Status.ObservedGeneration
+		// update CollaSet image
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateCollaSetStrategy{
+				ByLabel: &appsv1alpha1.ByLabel{},
+			}
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v3"
+
+			return true
+		})).Should(BeNil())
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Status.ObservedGeneration != observedGeneration
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		for _, number := range []int32{1, 2, 3, 4} {
+			pod := podList.Items[number-1]
+			// label pod to trigger update
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				if pod.Labels == nil {
+					pod.Labels = map[string]string{}
+				}
+				pod.Labels[appsv1alpha1.CollaSetUpdateIndicateLabelKey] = "true"
+				return true
+			})).Should(BeNil())
+
+			Eventually(func() error {
+				// check updated pod replicas by CollaSet status
+				return expectedStatusReplicas(c, cs, 0, 0, 0, 4, number, 1, 0, 0)
+			}, 5*time.Second, 1*time.Second).Should(BeNil())
+
+			Expect(updatePodStatusWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+					{
+						Name:    "foo",
+						ImageID: "id:v3",
+					},
+				}
+				return true
+			})).Should(BeNil())
+
+			// double check updated pod replicas
+			podList := &corev1.PodList{}
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			updatedReplicas := 0
+			for _, pod := range podList.Items {
+				Eventually(func() bool {
+					tmpPod := &corev1.Pod{}
+					Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, tmpPod)).Should(BeNil())
+					return tmpPod.Labels[appsv1alpha1.CollaSetUpdateIndicateLabelKey] == ""
+				}, 5*time.Second, 1*time.Second).Should(BeTrue())
+				if pod.Spec.Containers[0].Image == cs.Spec.Template.Spec.Containers[0].Image {
+					updatedReplicas++
+				}
+			}
+			Expect(updatedReplicas).Should(BeEquivalentTo(number))
+
+			Eventually(func() error {
+				// check updated pod replicas by CollaSet status
+				return expectedStatusReplicas(c, cs, 0, 0, 0, 4, number, 0, 0, 0)
+			}, 5*time.Second, 1*time.Second).Should(BeNil())
+		}
+	})
+
+	It("update pod policy", func() {
+		testcase := "test-update-pod-policy"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(1),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app":   "foo",
+							"test1": "v1",
+							"test2": "v1",
+							"test3": "v1",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 1, 1, 0, 0, 0)).Should(BeNil())
+
+		pod := podList.Items[0]
+		// test attribute updated manually
+		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+			if pod.Labels == nil {
+				pod.Labels = map[string]string{}
+			}
+			pod.Labels["test1"] = "v2"
+			pod.Labels["manual-added"] = "v1"
+			delete(pod.Labels, "test2")
+			return true
+		})).Should(BeNil())
+
+		observedGeneration := cs.Status.ObservedGeneration
+		// update CollaSet image
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Labels["test1"] = "v3"
+			delete(cls.Spec.Template.Labels, "test3")
+			return true
+		})).Should(BeNil())
+
+		// allow Pod to update
+		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+			labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+			pod.Labels[labelOperate] = "true"
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Status.ObservedGeneration != observedGeneration && cs.Status.UpdatedReplicas == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// check pod label
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).Should(BeNil())
+		Expect(pod.Labels).ShouldNot(BeNil())
+		// manual changes should have lower priority than PodTemplate changes
+		Expect(pod.Labels["test1"]).Should(BeEquivalentTo("v3"))
+		Expect(pod.Labels["manual-added"]).Should(BeEquivalentTo("v1"))
+
+		_, exist := pod.Labels["test2"]
+		Expect(exist).Should(BeFalse())
+		_, exist = pod.Labels["test3"]
+		Expect(exist).Should(BeFalse())
+
+		// test Recreate policy
+		observedGeneration = cs.Status.ObservedGeneration
+		// update CollaSet image
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Labels["test1"] = "v4"
+			cls.Spec.UpdateStrategy.PodUpdatePolicy = appsv1alpha1.CollaSetRecreatePodUpdateStrategyType
+			return true
+		})).Should(BeNil())
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Status.ObservedGeneration != observedGeneration && cs.Status.UpdatedReplicas == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// pod should be recreated
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).ShouldNot(BeNil())
+
+		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		Expect(len(podList.Items)).Should(BeEquivalentTo(1))
+		pod = podList.Items[0]
+		// check pod label
+		Expect(pod.Labels).ShouldNot(BeNil())
+		// manual changes should have lower priority than PodTemplate changes
+		Expect(pod.Labels["test1"]).Should(BeEquivalentTo("v4"))
+		Expect(pod.Labels["test2"]).Should(BeEquivalentTo("v1"))
+
+		_, exist = pod.Labels["manual-added"]
+		Expect(exist).Should(BeFalse())
+		_, exist = pod.Labels["test3"]
+		Expect(exist).Should(BeFalse())
+	})
+
+	It("pod recreate with its current revision", func() {
+		testcase := "test-pod-recreate-with-its-revision"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 2, 2, 0, 0, 0)).Should(BeNil())
+
+		var partition int32 = 1
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			cls.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateCollaSetStrategy{
+				ByPartition: &appsv1alpha1.ByPartition{
+					Partition: &partition,
+				},
+			}
+			return true
+		}))
+
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			// allow Pod to update
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+				pod.Labels[labelOperate] = "true"
+				return true
+			})).Should(BeNil())
+		}
+		Eventually(func() error {
+			// check updated pod replicas by CollaSet status
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 2, 1, 1, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+
+		idToRevision := map[int]string{}
+		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		Expect(len(podList.Items)).Should(BeEquivalentTo(2))
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			id, _ := collasetutils.GetPodInstanceID(pod)
+			Expect(id >= 0).Should(BeTrue())
+			revision := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+			Expect(revision).ShouldNot(BeEquivalentTo(""))
+			idToRevision[id] = revision
+		}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+
+		// delete pod
+		for i := range podList.Items {
+			Expect(c.Delete(context.TODO(), &podList.Items[i])).Should(BeNil())
+		}
+
+		Eventually(func() int {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items)
+		}, 5*time.Second, 1*time.Second).Should(BeEquivalentTo(2))
+		Eventually(func() error {
+			// check updated pod replicas by CollaSet status
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 2, 2, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			id, _ := collasetutils.GetPodInstanceID(pod)
+			Expect(id >= 0).Should(BeTrue())
+			revision := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+			Expect(revision).ShouldNot(BeEquivalentTo(""))
+			Expect(idToRevision[id]).Should(BeEquivalentTo(revision))
+		}
+	})
+
+	It("pause scaling and updating", func() {
+		testcase := "test-pause"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Paused:   true,
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		// paused CollaSet should not scale out Pods, but its status is still refreshed
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			cond := collasetutils.GetCondition(&cs.Status, appsv1alpha1.CollaSetPaused)
+			return cond != nil && cond.Status == corev1.ConditionTrue && cs.Status.ObservedGeneration == cs.Generation
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		podList := &corev1.PodList{}
+		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		Expect(len(podList.Items)).Should(BeEquivalentTo(0))
+
+		// resume CollaSet
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Paused = false
+			return true
+		})).Should(BeNil())
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return collasetutils.GetCondition(&cs.Status, appsv1alpha1.CollaSetPaused) == nil
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// paused CollaSet should not begin PodOpsLifecycle to update Pods
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Paused = true
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			return true
+		})).Should(BeNil())
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Status.ObservedGeneration == cs.Generation
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Consistently(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			for i := range podList.Items {
+				if podopslifecycle.IsDuringOps(collasetutils.UpdateOpsLifecycleAdapter, &podList.Items[i]) {
+					return false
+				}
+			}
+			return true
+		}, 3*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 2, 0, 0, 0, 0)).Should(BeNil())
+
+		// paused CollaSet should not begin PodOpsLifecycle to scale in Pods
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Replicas = int32Pointer(1)
+			return true
+		})).Should(BeNil())
+		Consistently(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			for i := range podList.Items {
+				if podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, &podList.Items[i]) {
+					return false
+				}
+			}
+			return len(podList.Items) == 2
+		}, 3*time.Second, 1*time.Second).Should(BeTrue())
+	})
+
+	It("exclude and include pods", func() {
+		testcase := "test-exclude-include"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// exclude one Pod
+		podToExclude := podList.Items[0].Name
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.ScaleStrategy.PodToExclude = []string{podToExclude}
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return *cs.Spec.Replicas == 1 && len(cs.Spec.ScaleStrategy.PodToExclude) == 0
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// excluded Pod is orphaned without being deleted
+		pod := &corev1.Pod{}
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: podToExclude}, pod)).Should(BeNil())
+			return metav1.GetControllerOf(pod) == nil
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(pod.DeletionTimestamp).Should(BeNil())
+		_, exist := pod.Labels[appsv1alpha1.PodInstanceIDLabelKey]
+		Expect(exist).Should(BeFalse())
+
+		// no more Pod is created, and the ID of excluded Pod is reclaimed
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 1, 1, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		Expect(len(podList.Items)).Should(BeEquivalentTo(2))
+		resourceContext := &appsv1alpha1.ResourceContext{}
+		Eventually(func() int {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, resourceContext)).Should(BeNil())
+			return len(resourceContext.Spec.Contexts)
+		}, 5*time.Second, 1*time.Second).Should(BeEquivalentTo(1))
+
+		// include the orphan Pod back
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.ScaleStrategy.PodToInclude = []string{podToExclude}
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return *cs.Spec.Replicas == 2 && len(cs.Spec.ScaleStrategy.PodToInclude) == 0
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// included Pod is adopted and allocated with an ID
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: podToExclude}, pod)).Should(BeNil())
+			if ref := metav1.GetControllerOf(pod); ref == nil || ref.UID != cs.UID {
+				return false
+			}
+
+			_, err := collasetutils.GetPodInstanceID(pod)
+			return err == nil
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 2, 2, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		Expect(len(podList.Items)).Should(BeEquivalentTo(2))
+		podInstanceID := sets.Int{}
+		for i := range podList.Items {
+			id, err := collasetutils.GetPodInstanceID(&podList.Items[i])
+			Expect(err).Should(BeNil())
+			podInstanceID.Insert(id)
+		}
+		Expect(podInstanceID.Len()).Should(BeEquivalentTo(2))
+	})
+
+	It("scale with volume claim templates", func() {
+		testcase := "test-scale-with-pvc"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+								VolumeMounts: []corev1.VolumeMount{
+									{
+										Name:      "data",
+										MountPath: "/data",
+									},
+								},
+							},
+						},
+					},
+				},
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "data",
+						},
+						Spec: corev1.PersistentVolumeClaimSpec{
+							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceStorage: resource.MustParse("1Gi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// each Pod mounts the PVC named after CollaSet, template and its instance ID
+		idToClaim := map[int]string{}
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			id, err := collasetutils.GetPodInstanceID(pod)
+			Expect(err).Should(BeNil())
+
+			claimName := ""
+			for _, volume := range pod.Spec.Volumes {
+				if volume.Name == "data" && volume.PersistentVolumeClaim != nil {
+					claimName = volume.PersistentVolumeClaim.ClaimName
+				}
+			}
+			Expect(claimName).Should(BeEquivalentTo(fmt.Sprintf("foo-data-%d", id)))
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: claimName}, pvc)).Should(BeNil())
+			Expect(pvc.Labels[appsv1alpha1.PodInstanceIDLabelKey]).Should(BeEquivalentTo(fmt.Sprintf("%d", id)))
+			idToClaim[id] = claimName
+		}
+
+		// recreated Pod attaches the PVC of its instance ID again
+		for i := range podList.Items {
+			Expect(c.Delete(context.TODO(), &podList.Items[i])).Should(BeNil())
+		}
+
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			if len(podList.Items) != 2 {
+				return false
+			}
+
+			for i := range podList.Items {
+				if podList.Items[i].DeletionTimestamp != nil {
+					return false
+				}
+			}
+			return true
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			id, err := collasetutils.GetPodInstanceID(pod)
+			Expect(err).Should(BeNil())
+
+			attached := false
+			for _, volume := range pod.Spec.Volumes {
+				if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == idToClaim[id] {
+					attached = true
+				}
+			}
+			Expect(attached).Should(BeTrue())
+		}
+
+		pvcList := &corev1.PersistentVolumeClaimList{}
+		Expect(c.List(context.TODO(), pvcList, client.InNamespace(cs.Namespace))).Should(BeNil())
+		Expect(len(pvcList.Items)).Should(BeEquivalentTo(2))
+	})
+
+	It("scale in with pvc retention policy", func() {
+		testcase := "test-pvc-retention"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "data",
+						},
+						Spec: corev1.PersistentVolumeClaimSpec{
+							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceStorage: resource.MustParse("1Gi"),
+								},
+							},
+						},
+					},
+				},
+				ScaleStrategy: appsv1alpha1.ScaleStrategy{
+					PersistentVolumeClaimRetentionPolicy: &appsv1alpha1.PersistentVolumeClaimRetentionPolicy{
+						WhenDeleted: appsv1alpha1.RetainPersistentVolumeClaimRetentionPolicyType,
+						WhenScaled:  appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType,
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// PVCs retained when CollaSet deleted are not owned by CollaSet
+		pvcList := &corev1.PersistentVolumeClaimList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), pvcList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(pvcList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		for _, pvc := range pvcList.Items {
+			Expect(len(pvc.OwnerReferences)).Should(BeEquivalentTo(0))
+		}
+
+		// PVCs will be owned by CollaSet after WhenDeleted policy changed to Delete
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenDeleted = appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType
+			return true
+		})).Should(BeNil())
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), pvcList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			for _, pvc := range pvcList.Items {
+				if len(pvc.OwnerReferences) != 1 || pvc.OwnerReferences[0].Name != cs.Name {
+					return false
+				}
+			}
+			return true
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// scale in one Pod
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Replicas = int32Pointer(1)
+			return true
+		})).Should(BeNil())
+
+		// mark all pods allowed to operate in PodOpsLifecycle
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+				pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+				return true
+			})).Should(BeNil())
+		}
+
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		id, err := collasetutils.GetPodInstanceID(&podList.Items[0])
+		Expect(err).Should(BeNil())
+		scaledInID := 1 - id
+
+		// PVC of the Pod scaled in should be deleted
+		Eventually(func() bool {
+			pvc := &corev1.PersistentVolumeClaim{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: fmt.Sprintf("foo-data-%d", scaledInID)}, pvc); err != nil {
+				return true
+			}
+
+			if pvc.DeletionTimestamp != nil && len(pvc.Finalizers) > 0 {
+				// there is no controller to remove pvc-protection finalizer in test environment
+				pvc.Finalizers = nil
+				Expect(c.Update(context.TODO(), pvc)).Should(BeNil())
+			}
+			return false
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// the instance ID should be reclaimed after its PVC deleted
+		Eventually(func() bool {
+			resourceContext := &appsv1alpha1.ResourceContext{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, resourceContext)).Should(BeNil())
+			for _, detail := range resourceContext.Spec.Contexts {
+				if detail.ID == scaledInID {
+					return false
+				}
+			}
+			return len(resourceContext.Spec.Contexts) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: fmt.Sprintf("foo-data-%d", id)}, pvc)).Should(BeNil())
+		Expect(pvc.DeletionTimestamp).Should(BeNil())
+	})
+
+	It("update with max surge", func() {
+		testcase := "test-update-with-max-surge"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		maxSurge := intstr.FromInt(1)
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				UpdateStrategy: appsv1alpha1.UpdateStrategy{
+					PodUpdatePolicy: appsv1alpha1.CollaSetRecreatePodUpdateStrategyType,
+					RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+						MaxSurge: &maxSurge,
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+		Expect(expectedStatusReplicas(c, cs, 0, 0, 0, 2, 2, 0, 0, 0)).Should(BeNil())
+		oldPodNames := sets.String{}
+		for i := range podList.Items {
+			oldPodNames.Insert(podList.Items[i].Name)
+		}
+
+		// update CollaSet image
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			return true
+		})).Should(BeNil())
+
+		// one Pod with updated revision should be surged, and old Pods are kept
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 3
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Consistently(func() int {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items)
+		}, 3*time.Second, 1*time.Second).Should(BeEquivalentTo(3))
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			Expect(pod.DeletionTimestamp).Should(BeNil())
+			if !oldPodNames.Has(pod.Name) {
+				Expect(pod.Spec.Containers[0].Image).Should(BeEquivalentTo("nginx:v2"))
+			}
+		}
+
+		// mark new Pods service available, and all old Pods allowed to scale in
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			for i := range podList.Items {
+				pod := &podList.Items[i]
+				if oldPodNames.Has(pod.Name) {
+					// old Pods may have been deleted
+					Expect(client.IgnoreNotFound(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+						labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+						if _, exist := pod.Labels[labelOperate]; exist {
+							return false
+						}
+						pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+						return true
+					}))).Should(BeNil())
+					continue
+				}
+
+				Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+					if _, exist := pod.Labels[appsv1alpha1.PodServiceAvailableLabel]; exist {
+						return false
+					}
+					pod.Labels[appsv1alpha1.PodServiceAvailableLabel] = "true"
+					return true
+				})).Should(BeNil())
+			}
+
+			for i := range podList.Items {
+				if oldPodNames.Has(podList.Items[i].Name) {
+					return false
+				}
+			}
+			return len(podList.Items) == 2
+		}, 30*time.Second, 1*time.Second).Should(BeTrue())
+
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 2, 2, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		for i := range podList.Items {
+			Expect(podList.Items[i].Spec.Containers[0].Image).Should(BeEquivalentTo("nginx:v2"))
+		}
+	})
+
+	It("update with max unavailable", func() {
+		testcase := "test-update-with-max-unavailable"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		maxUnavailable := intstr.FromInt(1)
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(3),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				UpdateStrategy: appsv1alpha1.UpdateStrategy{
+					RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+						MaxUnavailable: &maxUnavailable,
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 3
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// mark all Pods service available
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				pod.Labels[appsv1alpha1.PodServiceAvailableLabel] = "true"
+				return true
+			})).Should(BeNil())
+		}
+		Eventually(func() error {
+			if err := expectedStatusReplicas(c, cs, 0, 0, 3, 3, 3, 0, 0, 3); err != nil {
+				return err
+			}
+			if cs.Status.UnavailableReplicas != 0 {
+				return fmt.Errorf("unavailableReplicas got %d, expected 0", cs.Status.UnavailableReplicas)
+			}
+			if cs.Status.MaxUnavailableReplicas == nil || *cs.Status.MaxUnavailableReplicas != 1 {
+				return fmt.Errorf("maxUnavailableReplicas got %v, expected 1", cs.Status.MaxUnavailableReplicas)
+			}
+			return nil
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+
+		// update CollaSet image, and allow all Pods to update
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			return true
+		})).Should(BeNil())
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+				pod.Labels[labelOperate] = "true"
+				return true
+			})).Should(BeNil())
+		}
+
+		// the updated Pod never finishes updating without kubelet, so only one Pod is allowed to update
+		Eventually(func() int32 {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Status.UpdatedReplicas
+		}, 5*time.Second, 1*time.Second).Should(BeEquivalentTo(1))
+		Consistently(func() error {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			duringOps := 0
+			for i := range podList.Items {
+				if podopslifecycle.IsDuringOps(collasetutils.UpdateOpsLifecycleAdapter, &podList.Items[i]) {
+					duringOps++
+				}
+			}
+			if duringOps != 1 {
+				return fmt.Errorf("expected 1 Pod during updating, got %d", duringOps)
+			}
+
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			if cs.Status.UpdatedReplicas != 1 {
+				return fmt.Errorf("updatedReplicas got %d, expected 1", cs.Status.UpdatedReplicas)
+			}
+			if cs.Status.UnavailableReplicas != 1 {
+				return fmt.Errorf("unavailableReplicas got %d, expected 1", cs.Status.UnavailableReplicas)
+			}
+			return nil
+		}, 3*time.Second, 1*time.Second).Should(BeNil())
+	})
+
+	It("update by batches", func() {
+		testcase := "test-update-by-batches"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(3),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				UpdateStrategy: appsv1alpha1.UpdateStrategy{
+					RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+						Batches: []appsv1alpha1.RollingUpdateBatch{
+							{Replicas: intstr.FromInt(1), ManualApproval: true},
+							{Replicas: intstr.FromString("100%")},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 3
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// update CollaSet image, and allow all Pods to update
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			return true
+		})).Should(BeNil())
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+				pod.Labels[labelOperate] = "true"
+				return true
+			})).Should(BeNil())
+		}
+
+		// only the Pod in the first batch is updated
+		Eventually(func() error {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			if cs.Status.UpdateBatch == nil || cs.Status.UpdateBatch.Revision != cs.Status.UpdatedRevision {
+				return fmt.Errorf("expected update batch status of revision %s, got %v", cs.Status.UpdatedRevision, cs.Status.UpdateBatch)
+			}
+			if cs.Status.UpdateBatch.Batch != 0 || cs.Status.UpdateBatch.Partition != 1 {
+				return fmt.Errorf("expected batch 0 with partition 1, got batch %d with partition %d", cs.Status.UpdateBatch.Batch, cs.Status.UpdateBatch.Partition)
+			}
+			if cs.Status.UpdatedReplicas != 1 {
+				return fmt.Errorf("updatedReplicas got %d, expected 1", cs.Status.UpdatedReplicas)
+			}
+			return nil
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		Consistently(func() int32 {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Status.UpdatedReplicas
+		}, 3*time.Second, 1*time.Second).Should(BeEquivalentTo(1))
+	})
+
+	It("update with in-place only policy", func() {
+		testcase := "test-update-in-place-only"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(1),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				UpdateStrategy: appsv1alpha1.UpdateStrategy{
+					PodUpdatePolicy: appsv1alpha1.CollaSetInPlaceOnlyPodUpdateStrategyType,
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		pod := podList.Items[0]
+		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+			labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+			pod.Labels[labelOperate] = "true"
+			return true
+		})).Should(BeNil())
+
+		// changes of container command are not able to be updated in-place
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Command = []string{"sleep", "3600"}
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			cond := collasetutils.GetCondition(&cs.Status, appsv1alpha1.CollaSetInPlaceUpdateUnsupported)
+			return cond != nil && cond.Status == corev1.ConditionTrue
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// Pod should never be recreated
+		Consistently(func() error {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			if len(podList.Items) != 1 || podList.Items[0].UID != pod.UID || podList.Items[0].DeletionTimestamp != nil {
+				return fmt.Errorf("expected Pod %s not recreated", pod.Name)
+			}
+			if podopslifecycle.IsDuringOps(collasetutils.UpdateOpsLifecycleAdapter, &podList.Items[0]) {
+				return fmt.Errorf("expected Pod %s not begin updating", pod.Name)
+			}
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 1, 0, 0, 0, 0)
+		}, 3*time.Second, 1*time.Second).Should(BeNil())
+
+		// changes of container image are able to be updated in-place
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Command = nil
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() error {
+			if err := expectedStatusReplicas(c, cs, 0, 0, 0, 1, 1, 1, 0, 0); err != nil {
+				return err
+			}
+			if collasetutils.GetCondition(&cs.Status, appsv1alpha1.CollaSetInPlaceUpdateUnsupported) != nil {
+				return fmt.Errorf("expected condition %s removed", appsv1alpha1.CollaSetInPlaceUpdateUnsupported)
+			}
+			return nil
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).Should(BeNil())
+		Expect(pod.Spec.Containers[0].Image).Should(BeEquivalentTo("nginx:v2"))
+	})
+
+	It("rollback to previous revision", func() {
+		testcase := "test-rollback"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(1),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		pod := podList.Items[0]
+		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+			labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+			pod.Labels[labelOperate] = "true"
+			return true
+		})).Should(BeNil())
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 1, 1, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		firstRevision := cs.Status.UpdatedRevision
+
+		// update CollaSet image
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			return true
+		})).Should(BeNil())
+		Eventually(func() string {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).Should(BeNil())
+			return pod.Spec.Containers[0].Image
+		}, 5*time.Second, 1*time.Second).Should(BeEquivalentTo("nginx:v2"))
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+		secondRevision := cs.Status.UpdatedRevision
+		Expect(secondRevision).ShouldNot(BeEquivalentTo(firstRevision))
+
+		// roll back to the first revision by revision number
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.UpdateStrategy.RollbackTo = &appsv1alpha1.RollbackConfig{
+				Revision: intstr.FromInt(1),
+			}
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Spec.UpdateStrategy.RollbackTo == nil && cs.Status.LastRollback != nil && cs.Status.UpdatedRevision == firstRevision
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(cs.Spec.Template.Spec.Containers[0].Image).Should(BeEquivalentTo("nginx:v1"))
+		Expect(cs.Status.LastRollback.FromRevision).Should(BeEquivalentTo(secondRevision))
+		Expect(cs.Status.LastRollback.ToRevision).Should(BeEquivalentTo(firstRevision))
+
+		// Pod should be rolled back in-place
+		Eventually(func() string {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).Should(BeNil())
+			return pod.Spec.Containers[0].Image
+		}, 5*time.Second, 1*time.Second).Should(BeEquivalentTo("nginx:v1"))
+		Expect(pod.Labels[appsv1.ControllerRevisionHashLabelKey]).Should(BeEquivalentTo(firstRevision))
+
+		// roll back by annotation
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			if cls.Annotations == nil {
+				cls.Annotations = map[string]string{}
+			}
+			cls.Annotations[appsv1alpha1.CollaSetRollbackToAnnotationKey] = secondRevision
+			return true
+		})).Should(BeNil())
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			_, exist := cs.Annotations[appsv1alpha1.CollaSetRollbackToAnnotationKey]
+			return !exist && cs.Status.UpdatedRevision == secondRevision && cs.Status.LastRollback.ToRevision == secondRevision
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(cs.Spec.Template.Spec.Containers[0].Image).Should(BeEquivalentTo("nginx:v2"))
+	})
+
+	It("roll back automatically when updating fails", func() {
+		testcase := "test-auto-rollback"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(1),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		pod := podList.Items[0]
+		Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+			labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
+			pod.Labels[labelOperate] = "true"
+			return true
+		})).Should(BeNil())
+		Eventually(func() error {
+			return expectedStatusReplicas(c, cs, 0, 0, 0, 1, 1, 0, 0, 0)
+		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		firstRevision := cs.Status.UpdatedRevision
+
+		// update CollaSet image, and the updated Pod never becomes service available
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Template.Spec.Containers[0].Image = "nginx:v2"
+			cls.Spec.UpdateStrategy.ProgressDeadlineSeconds = int32Pointer(1)
+			cls.Spec.UpdateStrategy.FailurePolicy = &appsv1alpha1.UpdateFailurePolicy{
+				Rollback: true,
+			}
+			return true
+		})).Should(BeNil())
+
+		// CollaSet should be rolled back to the first revision after the progress deadline
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+			return cs.Status.LastRollback != nil && cs.Status.LastRollback.ToRevision == firstRevision && cs.Status.UpdatedRevision == firstRevision
+		}, 10*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(cs.Spec.Template.Spec.Containers[0].Image).Should(BeEquivalentTo("nginx:v1"))
+
+		Eventually(func() string {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &pod)).Should(BeNil())
+			return pod.Spec.Containers[0].Image
+		}, 5*time.Second, 1*time.Second).Should(BeEquivalentTo("nginx:v1"))
+	})
+
+	It("scale in with policies", func() {
+		testcase := "test-scale-in-policies"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(3),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+				ScaleStrategy: appsv1alpha1.ScaleStrategy{
+					ScaleInPolicies: []appsv1alpha1.ScaleInPolicyType{
+						appsv1alpha1.DeletionCostScaleInPolicyType,
+						appsv1alpha1.HighestInstanceIDScaleInPolicyType,
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 3
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// Pod with the lowest deletion cost should be scaled in first, and then the one with the highest instance ID
 		for i := range podList.Items {
 			pod := &podList.Items[i]
-			// allow Pod to update
+			id, err := collasetutils.GetPodInstanceID(pod)
+			Expect(err).Should(BeNil())
+			if id != 0 {
+				continue
+			}
 			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
-				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.UpdateOpsLifecycleAdapter.GetID())
-				pod.Labels[labelOperate] = "true"
+				if pod.Annotations == nil {
+					pod.Annotations = map[string]string{}
+				}
+				pod.Annotations["controller.kubernetes.io/pod-deletion-cost"] = "-100"
 				return true
 			})).Should(BeNil())
 		}
-		Eventually(func() error {
-			// check updated pod replicas by CollaSet status
-			return expectedStatusReplicas(c, cs, 0, 0, 0, 2, 1, 1, 0, 0)
-		}, 5*time.Second, 1*time.Second).Should(BeNil())
 
-		idToRevision := map[int]string{}
-		Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-		Expect(len(podList.Items)).Should(BeEquivalentTo(2))
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.Replicas = int32Pointer(1)
+			return true
+		})).Should(BeNil())
+
+		// mark all pods allowed to operate in PodOpsLifecycle
 		for i := range podList.Items {
 			pod := &podList.Items[i]
-			id, _ := collasetutils.GetPodInstanceID(pod)
-			Expect(id >= 0).Should(BeTrue())
-			revision := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
-			Expect(revision).ShouldNot(BeEquivalentTo(""))
-			idToRevision[id] = revision
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+				pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+				return true
+			})).Should(BeNil())
 		}
-		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
 
-		// delete pod
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		id, err := collasetutils.GetPodInstanceID(&podList.Items[0])
+		Expect(err).Should(BeNil())
+		Expect(id).Should(BeEquivalentTo(1))
+	})
+
+	It("delete indicated pods", func() {
+		testcase := "test-pod-to-delete"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(3),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 3
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// delete one Pod by name and another by instance ID
+		var podToDelete *corev1.Pod
 		for i := range podList.Items {
-			Expect(c.Delete(context.TODO(), &podList.Items[i])).Should(BeNil())
+			id, err := collasetutils.GetPodInstanceID(&podList.Items[i])
+			Expect(err).Should(BeNil())
+			if id == 0 {
+				podToDelete = &podList.Items[i]
+			}
 		}
+		Expect(podToDelete).ShouldNot(BeNil())
+		Expect(updateCollaSetWithRetry(c, cs.Namespace, cs.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.ScaleStrategy.PodToDelete = []string{podToDelete.Name, "2"}
+			return true
+		})).Should(BeNil())
 
+		// Pods should begin PodOpsLifecycle, and replicas is kept until they are allowed to scale in
 		Eventually(func() int {
 			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
-			return len(podList.Items)
+			count := 0
+			for i := range podList.Items {
+				if podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, &podList.Items[i]) {
+					count++
+				}
+			}
+			return count
 		}, 5*time.Second, 1*time.Second).Should(BeEquivalentTo(2))
-		Eventually(func() error {
-			// check updated pod replicas by CollaSet status
-			return expectedStatusReplicas(c, cs, 0, 0, 0, 2, 1, 0, 0, 0)
-		}, 5*time.Second, 1*time.Second).Should(BeNil())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+		Expect(*cs.Spec.Replicas).Should(BeEquivalentTo(3))
+
+		// mark all pods allowed to operate in PodOpsLifecycle
 		for i := range podList.Items {
 			pod := &podList.Items[i]
-			id, _ := collasetutils.GetPodInstanceID(pod)
-			Expect(id >= 0).Should(BeTrue())
-			revision := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
-			Expect(revision).ShouldNot(BeEquivalentTo(""))
-			Expect(idToRevision[id]).Should(BeEquivalentTo(revision))
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+				pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+				return true
+			})).Should(BeNil())
+		}
+
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		id, err := collasetutils.GetPodInstanceID(&podList.Items[0])
+		Expect(err).Should(BeNil())
+		Expect(id).Should(BeEquivalentTo(1))
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs)).Should(BeNil())
+		Expect(*cs.Spec.Replicas).Should(BeEquivalentTo(1))
+		Expect(len(cs.Spec.ScaleStrategy.PodToDelete)).Should(BeEquivalentTo(0))
+	})
+
+	It("migrate instance IDs across collasets", func() {
+		testcase := "test-id-migration"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		newCollaSet := func(name string, replicas int32) *appsv1alpha1.CollaSet {
+			return &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testcase,
+					Name:      name,
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(replicas),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "nginx:v1",
+								},
+							},
+						},
+					},
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "data",
+							},
+							Spec: corev1.PersistentVolumeClaimSpec{
+								AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{
+										corev1.ResourceStorage: resource.MustParse("1Gi"),
+									},
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						Context: "shared", // use the same Context
+					},
+				},
+			}
+		}
+
+		blue := newCollaSet("blue", 2)
+		green := newCollaSet("green", 0)
+		Expect(c.Create(context.TODO(), blue)).Should(BeNil())
+		Expect(c.Create(context.TODO(), green)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(testcase), client.MatchingLabels{"app": "blue"})).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		// blue scales in the Pod with ID 1 and releases the ID, and green adopts it
+		Expect(updateCollaSetWithRetry(c, blue.Namespace, blue.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.ScaleStrategy.IDToRelease = []int{1}
+			return true
+		})).Should(BeNil())
+		Expect(updateCollaSetWithRetry(c, green.Namespace, green.Name, func(cls *appsv1alpha1.CollaSet) bool {
+			cls.Spec.ScaleStrategy.IDToAdopt = []int{1}
+			return true
+		})).Should(BeNil())
+
+		// mark all pods allowed to operate in PodOpsLifecycle
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			Expect(updatePodWithRetry(c, pod.Namespace, pod.Name, func(pod *corev1.Pod) bool {
+				labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+				pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+				return true
+			})).Should(BeNil())
+		}
+
+		// the Pod of green with ID 1 attaches the PVC provisioned by blue
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(testcase), client.MatchingLabels{"app": "green"})).Should(BeNil())
+			if len(podList.Items) != 1 {
+				return false
+			}
+
+			id, err := collasetutils.GetPodInstanceID(&podList.Items[0])
+			Expect(err).Should(BeNil())
+			if id != 1 {
+				return false
+			}
+
+			for _, volume := range podList.Items[0].Spec.Volumes {
+				if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == "blue-data-1" {
+					return true
+				}
+			}
+			return false
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: green.Namespace, Name: green.Name}, green)).Should(BeNil())
+		Expect(*green.Spec.Replicas).Should(BeEquivalentTo(1))
+		Expect(len(green.Spec.ScaleStrategy.IDToAdopt)).Should(BeEquivalentTo(0))
+
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: blue.Namespace, Name: blue.Name}, blue)).Should(BeNil())
+			return *blue.Spec.Replicas == 1 && len(blue.Spec.ScaleStrategy.IDToRelease) == 0
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(c.List(context.TODO(), podList, client.InNamespace(testcase), client.MatchingLabels{"app": "blue"})).Should(BeNil())
+		Expect(len(podList.Items)).Should(BeEquivalentTo(1))
+		Expect(podList.Items[0].Labels[appsv1alpha1.PodInstanceIDLabelKey]).Should(BeEquivalentTo("0"))
+
+		// blue does not scale out a new Pod for the released ID
+		Consistently(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(testcase), client.MatchingLabels{"app": "blue"})).Should(BeNil())
+			return len(podList.Items) == 1
+		}, 3*time.Second, 1*time.Second).Should(BeTrue())
+
+		// the PVC is taken over by green, and the ID is owned by green in the shared ResourceContext
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: testcase, Name: "blue-data-1"}, pvc)).Should(BeNil())
+		Expect(pvc.Labels["app"]).Should(BeEquivalentTo("green"))
+		Expect(pvc.Labels).ShouldNot(HaveKey(appsv1alpha1.PvcReleasedLabelKey))
+
+		resourceContext := &appsv1alpha1.ResourceContext{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: testcase, Name: "shared"}, resourceContext)).Should(BeNil())
+		Expect(len(resourceContext.Spec.Contexts)).Should(BeEquivalentTo(2))
+		for _, detail := range resourceContext.Spec.Contexts {
+			if detail.ID == 1 {
+				Expect(detail.Data[podcontext.OwnerContextKey]).Should(BeEquivalentTo(green.Name))
+				Expect(detail.Data[pvccontrol.PvcCollaSetContextDataKey]).Should(BeEquivalentTo(blue.Name))
+			}
+		}
+	})
+
+	It("replace pod by label", func() {
+		testcase := "test-replace-by-label"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		cs := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: int32Pointer(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "foo",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
 		}
+
+		Expect(c.Create(context.TODO(), cs)).Should(BeNil())
+
+		podList := &corev1.PodList{}
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items) == 2
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+
+		originPod := podList.Items[0]
+		originID, err := collasetutils.GetPodInstanceID(&originPod)
+		Expect(err).Should(BeNil())
+		Expect(updatePodWithRetry(c, originPod.Namespace, originPod.Name, func(pod *corev1.Pod) bool {
+			pod.Labels[appsv1alpha1.CollaSetReplaceIndicateLabelKey] = "true"
+			return true
+		})).Should(BeNil())
+
+		// a new Pod should be created on the same instance ID, and replicas is kept
+		var newPod *corev1.Pod
+		Eventually(func() bool {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			for i := range podList.Items {
+				if podList.Items[i].Annotations[appsv1alpha1.PodReplacePairOriginNameAnnotationKey] == originPod.Name {
+					newPod = &podList.Items[i]
+				}
+			}
+			return len(podList.Items) == 3 && newPod != nil
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		newID, err := collasetutils.GetPodInstanceID(newPod)
+		Expect(err).Should(BeNil())
+		Expect(newID).Should(BeEquivalentTo(originID))
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: originPod.Namespace, Name: originPod.Name}, &originPod)).Should(BeNil())
+		Expect(originPod.Annotations[appsv1alpha1.PodReplacePairNewNameAnnotationKey]).Should(BeEquivalentTo(newPod.Name))
+
+		// the origin Pod is kept until the new one is service available
+		Consistently(func() int {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items)
+		}, 3*time.Second, 1*time.Second).Should(BeEquivalentTo(3))
+		Expect(updatePodWithRetry(c, newPod.Namespace, newPod.Name, func(pod *corev1.Pod) bool {
+			pod.Labels[appsv1alpha1.PodServiceAvailableLabel] = "true"
+			return true
+		})).Should(BeNil())
+
+		// mark the origin Pod allowed to operate in PodOpsLifecycle
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: originPod.Namespace, Name: originPod.Name}, &originPod)).Should(BeNil())
+			return podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, &originPod)
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(updatePodWithRetry(c, originPod.Namespace, originPod.Name, func(pod *corev1.Pod) bool {
+			labelOperate := fmt.Sprintf("%s/%s", appsv1alpha1.PodOperateLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())
+			pod.Labels[labelOperate] = fmt.Sprintf("%d", time.Now().UnixNano())
+			return true
+		})).Should(BeNil())
+
+		Eventually(func() bool {
+			return errors.IsNotFound(c.Get(context.TODO(), types.NamespacedName{Namespace: originPod.Namespace, Name: originPod.Name}, &originPod))
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Consistently(func() int {
+			Expect(c.List(context.TODO(), podList, client.InNamespace(cs.Namespace))).Should(BeNil())
+			return len(podList.Items)
+		}, 3*time.Second, 1*time.Second).Should(BeEquivalentTo(2))
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: newPod.Namespace, Name: newPod.Name}, newPod)).Should(BeNil())
 	})
 })
 
@@ -18,12 +18,19 @@ package podcontext
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
@@ -69,36 +76,185 @@ func AllocateID(c client.Client, instance *appsv1alpha1.CollaSet, defaultRevisio
 	}
 
 	// find new IDs for owner
-	candidateID := 0
-	for len(ownedIDs) < replicas {
-		// find one new ID
-		for {
-			if _, exist := existingIDs[candidateID]; exist {
-				candidateID++
-				continue
+	strategy := instance.Spec.ScaleStrategy.IDAllocationStrategy
+	reclaimedAt := getReclaimedIDs(podContext)
+	now := time.Now()
+	conflictIDs := map[int]string{}
+	allocated := false
+	allocate := func(id int) {
+		if detail, exist := existingIDs[id]; exist {
+			if !detail.Contains(OwnerContextKey, instance.Name) {
+				conflictIDs[id] = detail.Data[OwnerContextKey]
 			}
+			return
+		}
 
-			break
+		if isInCooldown(strategy, reclaimedAt[id], now) {
+			return
 		}
 
 		detail := &appsv1alpha1.ContextDetail{
-			ID: candidateID,
+			ID: id,
 			Data: map[string]string{
 				OwnerContextKey:        instance.Name,
 				RevisionContextDataKey: defaultRevision,
 			},
 		}
-		existingIDs[candidateID] = detail
-		ownedIDs[candidateID] = detail
+		existingIDs[id] = detail
+		ownedIDs[id] = detail
+		allocated = true
+	}
+
+	if strategy != nil && len(strategy.IDs) > 0 {
+		for _, id := range strategy.IDs {
+			if len(ownedIDs) >= replicas {
+				break
+			}
+
+			if isInRange(strategy, id) {
+				allocate(id)
+			}
+		}
+	} else {
+		for id := getMinID(strategy); len(ownedIDs) < replicas && isInRange(strategy, id); id++ {
+			allocate(id)
+		}
+	}
+
+	var err error
+	if allocated {
+		if notFound {
+			err = doCreatePodContext(c, instance, ownedIDs)
+		} else {
+			err = doUpdatePodContext(c, instance, ownedIDs, podContext)
+		}
+	}
+	if err != nil || len(ownedIDs) >= replicas {
+		return ownedIDs, err
+	}
+
+	return ownedIDs, &IDShortageError{Expected: replicas, Allocated: len(ownedIDs), ConflictIDs: conflictIDs}
+}
+
+// IDShortageError indicates there are not enough IDs to allocate for the restrictions of IDAllocationStrategy,
+// and records the IDs allowed but in conflict with other owners sharing the same ResourceContext.
+type IDShortageError struct {
+	Expected    int
+	Allocated   int
+	ConflictIDs map[int]string
+}
+
+func (e *IDShortageError) Error() string {
+	msg := fmt.Sprintf("only %d of %d IDs are allowed to allocate", e.Allocated, e.Expected)
+	if len(e.ConflictIDs) == 0 {
+		return msg
+	}
+
+	var conflicts []string
+	for _, id := range sets.IntKeySet(e.ConflictIDs).List() {
+		owner := e.ConflictIDs[id]
+		if owner == "" {
+			owner = "released"
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%d(%s)", id, owner))
+	}
+
+	return fmt.Sprintf("%s, and IDs in conflict with other owners: %s", msg, strings.Join(conflicts, ", "))
+}
+
+// IsIDShortage indicates whether the error is caused by not enough IDs to allocate.
+func IsIDShortage(err error) bool {
+	_, ok := err.(*IDShortageError)
+	return ok
+}
+
+func getMinID(strategy *appsv1alpha1.IDAllocationStrategy) int {
+	if strategy == nil || strategy.MinID == nil {
+		return 0
+	}
+
+	return int(*strategy.MinID)
+}
+
+func isInRange(strategy *appsv1alpha1.IDAllocationStrategy, id int) bool {
+	if id < getMinID(strategy) {
+		return false
+	}
+
+	return strategy == nil || strategy.MaxID == nil || id <= int(*strategy.MaxID)
+}
+
+func isInCooldown(strategy *appsv1alpha1.IDAllocationStrategy, reclaimedAt *time.Time, now time.Time) bool {
+	if strategy == nil || strategy.ReleasedIDCooldownSeconds == nil || reclaimedAt == nil {
+		return false
+	}
+
+	return now.Before(reclaimedAt.Add(time.Duration(*strategy.ReleasedIDCooldownSeconds) * time.Second))
+}
+
+// RecordReclaimedIDs records the time when the IDs are reclaimed from ResourceContext, so that they are kept from
+// allocation during the cooldown. The records expire after the longest cooldown, or once the IDs are allocated again.
+func RecordReclaimedIDs(podContext *appsv1alpha1.ResourceContext, ids []int) {
+	reclaimedAt := getReclaimedIDs(podContext)
+	now := time.Now()
+	for _, id := range ids {
+		reclaimedAt[id] = &now
+	}
+
+	for _, detail := range podContext.Spec.Contexts {
+		delete(reclaimedAt, detail.ID)
+	}
+
+	records := map[string]string{}
+	for id, at := range reclaimedAt {
+		if now.Sub(*at) > appsv1alpha1.MaxReleasedIDCooldownSeconds*time.Second {
+			continue
+		}
+		records[strconv.Itoa(id)] = at.UTC().Format(time.RFC3339)
+	}
+
+	if len(records) == 0 {
+		delete(podContext.Annotations, appsv1alpha1.ResourceContextReclaimedIDsAnnotationKey)
+		return
+	}
+
+	value, _ := json.Marshal(records)
+	if podContext.Annotations == nil {
+		podContext.Annotations = map[string]string{}
+	}
+	podContext.Annotations[appsv1alpha1.ResourceContextReclaimedIDsAnnotationKey] = string(value)
+}
+
+func getReclaimedIDs(podContext *appsv1alpha1.ResourceContext) map[int]*time.Time {
+	reclaimedAt := map[int]*time.Time{}
+	value, exist := podContext.Annotations[appsv1alpha1.ResourceContextReclaimedIDsAnnotationKey]
+	if !exist {
+		return reclaimedAt
 	}
 
-	if notFound {
-		return ownedIDs, doCreatePodContext(c, instance, ownedIDs)
+	records := map[string]string{}
+	if err := json.Unmarshal([]byte(value), &records); err != nil {
+		return reclaimedAt
+	}
+
+	for key, at := range records {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			continue
+		}
+		reclaimedAt[id] = &t
 	}
 
-	return ownedIDs, doUpdatePodContext(c, instance, ownedIDs, podContext)
+	return reclaimedAt
 }
 
+// UpdateToPodContext updates the contexts owned by the instance in ResourceContext to ownedIDs, and reclaims the IDs
+// no longer owned. The entries of the other owners sharing the same ResourceContext are left untouched.
 func UpdateToPodContext(c client.Client, instance *appsv1alpha1.CollaSet, ownedIDs map[int]*appsv1alpha1.ContextDetail) error {
 	contextName := getContextName(instance)
 	podContext := &appsv1alpha1.ResourceContext{}
@@ -112,13 +268,65 @@ func UpdateToPodContext(c client.Client, instance *appsv1alpha1.CollaSet, ownedI
 		}
 
 		if err := doCreatePodContext(c, instance, ownedIDs); err != nil {
+			if errors.IsConflict(err) {
+				return err
+			}
 			return fmt.Errorf("fail to create ResourceContext %s/%s after not found: %s", instance.Namespace, contextName, err)
 		}
+		return nil
 	}
 
 	return doUpdatePodContext(c, instance, ownedIDs, podContext)
 }
 
+// AdoptIDs takes over the indicated instance IDs released by the other owners sharing the same ResourceContext, with
+// their context data kept. The IDs still owned by the others are left to adopt later. It returns the contexts of the
+// indicated IDs which are owned by the instance after adoption.
+func AdoptIDs(c client.Client, instance *appsv1alpha1.CollaSet, ids []int, revision string) (map[int]*appsv1alpha1.ContextDetail, error) {
+	contextName := getContextName(instance)
+	podContext := &appsv1alpha1.ResourceContext{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: contextName}, podContext); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("fail to find ResourceContext %s/%s: %s", instance.Namespace, contextName, err)
+	}
+
+	idSet := sets.NewInt(ids...)
+	adoptedIDs := map[int]*appsv1alpha1.ContextDetail{}
+	changes := map[int]*appsv1alpha1.ContextDetail{}
+	for i := range podContext.Spec.Contexts {
+		detail := podContext.Spec.Contexts[i].DeepCopy()
+		if !idSet.Has(detail.ID) {
+			continue
+		}
+
+		if !detail.Contains(OwnerContextKey, instance.Name) {
+			if owner := detail.Data[OwnerContextKey]; owner != "" {
+				// still owned by another one
+				continue
+			}
+
+			detail.Put(OwnerContextKey, instance.Name)
+			detail.Put(RevisionContextDataKey, revision)
+			changes[detail.ID] = detail
+		}
+
+		adoptedIDs[detail.ID] = detail
+	}
+
+	if len(changes) == 0 {
+		return adoptedIDs, nil
+	}
+
+	if err := patchPodContext(c, instance, podContext, changes, nil); err != nil {
+		return nil, err
+	}
+
+	return adoptedIDs, nil
+}
+
 func doCreatePodContext(c client.Client, instance *appsv1alpha1.CollaSet, ownerIDs map[int]*appsv1alpha1.ContextDetail) error {
 	contextName := getContextName(instance)
 	podContext := &appsv1alpha1.ResourceContext{
@@ -137,43 +345,105 @@ func doCreatePodContext(c client.Client, instance *appsv1alpha1.CollaSet, ownerI
 		i++
 	}
 
-	return c.Create(context.TODO(), podContext)
+	// keep context detail in order by ID
+	sort.Sort(ContextDetailsByOrder(podContext.Spec.Contexts))
+	if err := c.Create(context.TODO(), podContext); err != nil {
+		if errors.IsAlreadyExists(err) {
+			// created by another owner sharing the same ResourceContext meanwhile, so retry with the latest one
+			return errors.NewConflict(resourceContextGroupResource, contextName, err)
+		}
+		return err
+	}
+
+	return nil
 }
 
+// doUpdatePodContext patches the entries owned by the instance to ownedIDs, and removes the ones no longer owned.
+// An ID in ownedIDs is skipped if its entry is owned by another one or released, since it is only taken over
+// through AdoptIDs.
 func doUpdatePodContext(c client.Client, instance client.Object, ownedIDs map[int]*appsv1alpha1.ContextDetail, podContext *appsv1alpha1.ResourceContext) error {
-	// store all IDs crossing all workload
-	existingIDs := map[int]*appsv1alpha1.ContextDetail{}
-	for k, detail := range ownedIDs {
-		existingIDs[k] = detail
-	}
-
+	currentIDs := map[int]*appsv1alpha1.ContextDetail{}
+	changes := map[int]*appsv1alpha1.ContextDetail{}
+	var reclaimedIDs []int
 	for i := range podContext.Spec.Contexts {
-		detail := podContext.Spec.Contexts[i]
+		detail := &podContext.Spec.Contexts[i]
+		currentIDs[detail.ID] = detail
 		if detail.Contains(OwnerContextKey, instance.GetName()) {
+			if _, owned := ownedIDs[detail.ID]; !owned {
+				changes[detail.ID] = nil
+				reclaimedIDs = append(reclaimedIDs, detail.ID)
+			}
+		}
+	}
+
+	for id, detail := range ownedIDs {
+		current, exist := currentIDs[id]
+		if !exist {
+			changes[id] = detail
 			continue
 		}
 
-		existingIDs[detail.ID] = &detail
+		if current.Contains(OwnerContextKey, instance.GetName()) && !equality.Semantic.DeepEqual(current, detail) {
+			changes[id] = detail
+		}
 	}
 
-	podContext.Spec.Contexts = make([]appsv1alpha1.ContextDetail, len(existingIDs))
-
-	idx := 0
-	for _, contextDetail := range existingIDs {
-		podContext.Spec.Contexts[idx] = *contextDetail
-		idx++
+	if len(changes) == 0 {
+		return nil
 	}
 
-	// keep context detail in order by ID
-	sort.Sort(ContextDetailsByOrder(podContext.Spec.Contexts))
-	err := c.Update(context.TODO(), podContext)
+	return patchPodContext(c, instance, podContext, changes, reclaimedIDs)
+}
+
+var resourceContextGroupResource = schema.GroupResource{Group: appsv1alpha1.GroupVersion.Group, Resource: "resourcecontexts"}
+
+// patchPodContext applies the changes of context entries owned by the instance to ResourceContext, and expects the
+// update of ResourceContext for the instance.
+func patchPodContext(c client.Client, instance client.Object, podContext *appsv1alpha1.ResourceContext, changes map[int]*appsv1alpha1.ContextDetail, reclaimedIDs []int) error {
+	patched, err := PatchContexts(c, podContext, changes, reclaimedIDs)
 	if err != nil {
-		if err := utils.ActiveExpectations.ExpectUpdate(instance, expectations.ResourceContext, podContext.Name, podContext.ResourceVersion); err != nil {
-			return err
+		return err
+	}
+
+	return utils.ActiveExpectations.ExpectUpdate(instance, expectations.ResourceContext, patched.Name, patched.ResourceVersion)
+}
+
+// PatchContexts applies the changes of context entries to ResourceContext by merge patch, in which a nil entry
+// indicates removal, and records the reclaimed IDs. The changes are merged into the contexts observed, so the entries
+// of the other owners sharing the same ResourceContext are kept as they are. The patch is guarded by the resource
+// version observed, and fails with conflict if the ResourceContext has been changed meanwhile, so that the caller
+// retries with the latest one. It returns the patched ResourceContext, and leaves the observed one unchanged.
+func PatchContexts(c client.Client, podContext *appsv1alpha1.ResourceContext, changes map[int]*appsv1alpha1.ContextDetail, reclaimedIDs []int) (*appsv1alpha1.ResourceContext, error) {
+	patched := podContext.DeepCopy()
+	patched.Spec.Contexts = mergeContexts(podContext.Spec.Contexts, changes)
+	if len(reclaimedIDs) > 0 {
+		RecordReclaimedIDs(patched, reclaimedIDs)
+	}
+
+	if err := c.Patch(context.TODO(), patched, client.MergeFromWithOptions(podContext, client.MergeFromWithOptimisticLock{})); err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+func mergeContexts(contexts []appsv1alpha1.ContextDetail, changes map[int]*appsv1alpha1.ContextDetail) []appsv1alpha1.ContextDetail {
+	merged := []appsv1alpha1.ContextDetail{}
+	for _, detail := range contexts {
+		if _, changed := changes[detail.ID]; !changed {
+			merged = append(merged, detail)
 		}
 	}
 
-	return err
+	for _, detail := range changes {
+		if detail != nil {
+			merged = append(merged, *detail)
+		}
+	}
+
+	// keep context detail in order by ID
+	sort.Sort(ContextDetailsByOrder(merged))
+	return merged
 }
 
 func getContextName(instance *appsv1alpha1.CollaSet) string {
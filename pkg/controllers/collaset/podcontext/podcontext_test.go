@@ -17,17 +17,29 @@ limitations under the License.
 package podcontext
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/utils"
 )
 
 func init() {
@@ -43,6 +55,7 @@ var _ = Describe("ResourceContext allocation", func() {
 
 	It("allocate ID", func() {
 		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		utils.InitExpectations(c)
 		namespace := "test"
 
 		instance1 := &appsv1alpha1.CollaSet{
@@ -107,8 +120,325 @@ var _ = Describe("ResourceContext allocation", func() {
 		}
 	})
 
+	It("allocate ID with strategy", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		utils.InitExpectations(c)
+		namespace := "test"
+
+		newInstance := func(name string, strategy *appsv1alpha1.IDAllocationStrategy) *appsv1alpha1.CollaSet {
+			return &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      name,
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						Context:              "foo", // use the same Context
+						IDAllocationStrategy: strategy,
+					},
+				},
+			}
+		}
+
+		// IDs are allocated within range
+		instance1 := newInstance("foo1", &appsv1alpha1.IDAllocationStrategy{MinID: int32Pointer(2), MaxID: int32Pointer(5)})
+		ownedIDs, err := AllocateID(c, instance1, "", 3)
+		Expect(err).Should(BeNil())
+		Expect(sets.IntKeySet(ownedIDs).List()).Should(BeEquivalentTo([]int{2, 3, 4}))
+
+		// IDs are allocated from the explicit list, and the ones in conflict are reported
+		instance2 := newInstance("foo2", &appsv1alpha1.IDAllocationStrategy{IDs: []int{4, 7, 5}})
+		ownedIDs, err = AllocateID(c, instance2, "", 3)
+		Expect(IsIDShortage(err)).Should(BeTrue())
+		Expect(err.Error()).Should(ContainSubstring("4(foo1)"))
+		Expect(sets.IntKeySet(ownedIDs).List()).Should(BeEquivalentTo([]int{5, 7}))
+
+		// IDs reclaimed recently are not allocated during cooldown
+		ownedIDs, err = AllocateID(c, instance1, "", 3)
+		Expect(err).Should(BeNil())
+		delete(ownedIDs, 3)
+		Expect(UpdateToPodContext(c, instance1, ownedIDs)).Should(BeNil())
+
+		instance3 := newInstance("foo3", &appsv1alpha1.IDAllocationStrategy{MaxID: int32Pointer(3), ReleasedIDCooldownSeconds: int32Pointer(60)})
+		ownedIDs, err = AllocateID(c, instance3, "", 3)
+		Expect(IsIDShortage(err)).Should(BeTrue())
+		Expect(sets.IntKeySet(ownedIDs).List()).Should(BeEquivalentTo([]int{0, 1}))
+
+		instance4 := newInstance("foo4", nil)
+		ownedIDs, err = AllocateID(c, instance4, "", 1)
+		Expect(err).Should(BeNil())
+		Expect(sets.IntKeySet(ownedIDs).List()).Should(BeEquivalentTo([]int{3}))
+	})
+
+	It("update ID concurrently", func() {
+		c := &atomicWriteClient{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+		utils.InitExpectations(c)
+		namespace := "test"
+		count, replicas := 20, 5
+		backoff := wait.Backoff{Steps: 200, Duration: time.Millisecond, Factor: 1.0, Jitter: 1.0}
+
+		instances := make([]*appsv1alpha1.CollaSet, count)
+		for i := range instances {
+			instances[i] = &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      fmt.Sprintf("foo%d", i),
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						Context: "foo", // use the same Context
+					},
+				},
+			}
+		}
+
+		runConcurrently := func(fn func(idx int, instance *appsv1alpha1.CollaSet) error) {
+			var wg sync.WaitGroup
+			errs := make([]error, count)
+			for i := range instances {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = retry.RetryOnConflict(backoff, func() error {
+						return fn(i, instances[i])
+					})
+				}(i)
+			}
+			wg.Wait()
+
+			for _, err := range errs {
+				Expect(err).Should(BeNil())
+			}
+		}
+
+		checkContext := func(expectedIDs func(idx int) int, expectedRevision func(idx int) string) {
+			podContext := &appsv1alpha1.ResourceContext{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "foo"}, podContext)).Should(BeNil())
+
+			ownedCount := map[string]int{}
+			for i, detail := range podContext.Spec.Contexts {
+				if i > 0 {
+					// IDs are unique and kept in order
+					Expect(detail.ID).Should(BeNumerically(">", podContext.Spec.Contexts[i-1].ID))
+				}
+				ownedCount[detail.Data[OwnerContextKey]]++
+			}
+
+			for i, instance := range instances {
+				Expect(ownedCount[instance.Name]).Should(BeEquivalentTo(expectedIDs(i)))
+				for _, detail := range podContext.Spec.Contexts {
+					if detail.Contains(OwnerContextKey, instance.Name) {
+						Expect(detail.Data[RevisionContextDataKey]).Should(BeEquivalentTo(expectedRevision(i)))
+					}
+				}
+			}
+			Expect(len(ownedCount)).Should(BeEquivalentTo(count))
+		}
+
+		// all the owners allocate IDs from the same ResourceContext at the same time
+		runConcurrently(func(idx int, instance *appsv1alpha1.CollaSet) error {
+			_, err := AllocateID(c, instance, "v1", replicas)
+			return err
+		})
+		checkContext(func(int) int { return replicas }, func(int) string { return "v1" })
+
+		// half of the owners scale in, and the others update their revisions at the same time
+		runConcurrently(func(idx int, instance *appsv1alpha1.CollaSet) error {
+			ownedIDs, err := AllocateID(c, instance, "v1", replicas)
+			if err != nil {
+				return err
+			}
+
+			if idx%2 == 0 {
+				for _, id := range sets.IntKeySet(ownedIDs).List()[:2] {
+					delete(ownedIDs, id)
+				}
+			} else {
+				for id := range ownedIDs {
+					detail := ownedIDs[id].DeepCopy()
+					detail.Put(RevisionContextDataKey, "v2")
+					ownedIDs[id] = detail
+				}
+			}
+			return UpdateToPodContext(c, instance, ownedIDs)
+		})
+		checkContext(func(idx int) int {
+			if idx%2 == 0 {
+				return replicas - 2
+			}
+			return replicas
+		}, func(idx int) string {
+			if idx%2 == 0 {
+				return "v1"
+			}
+			return "v2"
+		})
+
+		podContext := &appsv1alpha1.ResourceContext{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "foo"}, podContext)).Should(BeNil())
+		Expect(getReclaimedIDs(podContext)).Should(HaveLen(count))
+	})
+
+	It("update ID from stale reads", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		utils.InitExpectations(c)
+		namespace := "test"
+
+		instances := make([]*appsv1alpha1.CollaSet, 3)
+		for i := range instances {
+			instances[i] = &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      fmt.Sprintf("foo%d", i),
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						Context: "foo", // use the same Context
+					},
+				},
+			}
+
+			ownedIDs, err := AllocateID(c, instances[i], "v1", 2)
+			Expect(err).Should(BeNil())
+			Expect(ownedIDs).Should(HaveLen(2))
+		}
+
+		getContext := func() *appsv1alpha1.ResourceContext {
+			podContext := &appsv1alpha1.ResourceContext{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "foo"}, podContext)).Should(BeNil())
+			return podContext
+		}
+
+		getOwnedIDs := func(podContext *appsv1alpha1.ResourceContext, owner string) map[int]*appsv1alpha1.ContextDetail {
+			ownedIDs := map[int]*appsv1alpha1.ContextDetail{}
+			for i := range podContext.Spec.Contexts {
+				if detail := podContext.Spec.Contexts[i].DeepCopy(); detail.Contains(OwnerContextKey, owner) {
+					ownedIDs[detail.ID] = detail
+				}
+			}
+			return ownedIDs
+		}
+
+		// foo0 scales out with ID 6, foo1 scales in ID 2, and foo2 updates the revisions of IDs 4 and 5
+		mutations := []func(ownedIDs map[int]*appsv1alpha1.ContextDetail){
+			func(ownedIDs map[int]*appsv1alpha1.ContextDetail) {
+				ownedIDs[6] = &appsv1alpha1.ContextDetail{ID: 6, Data: map[string]string{OwnerContextKey: "foo0", RevisionContextDataKey: "v1"}}
+			},
+			func(ownedIDs map[int]*appsv1alpha1.ContextDetail) {
+				delete(ownedIDs, 2)
+			},
+			func(ownedIDs map[int]*appsv1alpha1.ContextDetail) {
+				for _, detail := range ownedIDs {
+					detail.Put(RevisionContextDataKey, "v2")
+				}
+			},
+		}
+
+		// all the owners read the ResourceContext before any of them patches it
+		staleContexts := make([]*appsv1alpha1.ResourceContext, len(instances))
+		for i := range instances {
+			staleContexts[i] = getContext()
+		}
+
+		conflicts := 0
+		for i, instance := range instances {
+			podContext := staleContexts[i]
+			Expect(retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				ownedIDs := getOwnedIDs(podContext, instance.Name)
+				mutations[i](ownedIDs)
+				err := doUpdatePodContext(c, instance, ownedIDs, podContext)
+				if errors.IsConflict(err) {
+					conflicts++
+					podContext = getContext()
+				}
+				return err
+			})).Should(BeNil())
+		}
+		// the owners patching after the first one are guarded by the resource version they read
+		Expect(conflicts).Should(BeNumerically(">", 0))
+
+		// no entry of any owner is lost or overwritten
+		podContext := getContext()
+		Expect(sort.IsSorted(ContextDetailsByOrder(podContext.Spec.Contexts))).Should(BeTrue())
+		Expect(sets.IntKeySet(getOwnedIDs(podContext, "foo0")).List()).Should(Equal([]int{0, 1, 6}))
+		Expect(sets.IntKeySet(getOwnedIDs(podContext, "foo1")).List()).Should(Equal([]int{3}))
+		foo2IDs := getOwnedIDs(podContext, "foo2")
+		Expect(sets.IntKeySet(foo2IDs).List()).Should(Equal([]int{4, 5}))
+		for _, detail := range foo2IDs {
+			Expect(detail.Data[RevisionContextDataKey]).Should(BeEquivalentTo("v2"))
+		}
+		Expect(getReclaimedIDs(podContext)).Should(HaveKey(2))
+	})
+
+	It("return patch errors other than conflict unchanged", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		utils.InitExpectations(c)
+		instance := &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "test",
+				Name:      "foo",
+			},
+		}
+		_, err := AllocateID(c, instance, "v1", 1)
+		Expect(err).Should(BeNil())
+
+		podContext := &appsv1alpha1.ResourceContext{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "test", Name: "foo"}, podContext)).Should(BeNil())
+		ownedIDs := map[int]*appsv1alpha1.ContextDetail{
+			0: podContext.Spec.Contexts[0].DeepCopy(),
+			1: {ID: 1, Data: map[string]string{OwnerContextKey: "foo", RevisionContextDataKey: "v1"}},
+		}
+
+		patchErr := fmt.Errorf("connection refused")
+		err = doUpdatePodContext(&patchErrorClient{Client: c, err: patchErr}, instance, ownedIDs, podContext)
+		Expect(err).Should(Equal(patchErr))
+
+		Expect(c.Delete(context.TODO(), podContext)).Should(BeNil())
+		err = doUpdatePodContext(c, instance, ownedIDs, podContext)
+		Expect(errors.IsNotFound(err)).Should(BeTrue())
+	})
+
 })
 
+// atomicWriteClient makes the writes of fake client atomic as the apiserver does, since the fake one checks the
+// resource version and stores the object in separate steps.
+type atomicWriteClient struct {
+	client.Client
+	lock sync.Mutex
+}
+
+func (c *atomicWriteClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *atomicWriteClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *atomicWriteClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// patchErrorClient fails the patches with the indicated error.
+type patchErrorClient struct {
+	client.Client
+	err error
+}
+
+func (c *patchErrorClient) Patch(_ context.Context, _ client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	return c.err
+}
+
+func int32Pointer(val int32) *int32 {
+	return &val
+}
+
 func TestPodContext(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "ResourceContext Test Suite")
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collaset
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/synccontrol"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
+)
+
+const (
+	progressingReasonUpdating                 = "Updating"
+	progressingReasonUpdateCompleted          = "UpdateCompleted"
+	progressingReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	progressingReasonFailureThresholdExceeded = "FailureThresholdExceeded"
+)
+
+func isProgressChecked(cls *appsv1alpha1.CollaSet) bool {
+	return cls.Spec.UpdateStrategy.ProgressDeadlineSeconds != nil || cls.Spec.UpdateStrategy.FailurePolicy != nil
+}
+
+// isUpdatePending indicates whether updating is held on purpose, during which no progress is expected.
+func isUpdatePending(cls *appsv1alpha1.CollaSet, newStatus *appsv1alpha1.CollaSetStatus) bool {
+	if cls.Spec.Paused {
+		return true
+	}
+
+	return newStatus.UpdateBatch != nil && (newStatus.UpdateBatch.Phase == appsv1alpha1.CollaSetUpdateBatchPaused ||
+		newStatus.UpdateBatch.Phase == appsv1alpha1.CollaSetUpdateBatchWaitingForApproval)
+}
+
+// checkProgress records the progress of updating in status, and sets the condition Progressing to False if updating
+// makes no progress in ProgressDeadlineSeconds, or there are more Pods with updated revision not available than
+// FailurePolicy.FailureThreshold. It returns the duration to requeue for checking the deadline.
+func (r *CollaSetReconciler) checkProgress(cls *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, podWrappers []*collasetutils.PodWrapper, newStatus *appsv1alpha1.CollaSetStatus) time.Duration {
+	if !isProgressChecked(cls) {
+		newStatus.UpdateProgress = nil
+		collasetutils.RemoveCondition(newStatus, appsv1alpha1.CollaSetProgressing)
+		return 0
+	}
+
+	now := metav1.Now()
+	progress := newStatus.UpdateProgress
+	if progress == nil || progress.Revision != updatedRevision.Name {
+		progress = &appsv1alpha1.CollaSetUpdateProgressStatus{
+			Revision:         updatedRevision.Name,
+			LastProgressTime: now,
+		}
+	} else {
+		progress = progress.DeepCopy()
+	}
+
+	// the progress is made once more Pods are updated or become available
+	if progress.UpdatedReplicas != newStatus.UpdatedReplicas || progress.UpdatedAvailableReplicas != newStatus.UpdatedAvailableReplicas ||
+		isUpdatePending(cls, newStatus) {
+		progress.UpdatedReplicas = newStatus.UpdatedReplicas
+		progress.UpdatedAvailableReplicas = newStatus.UpdatedAvailableReplicas
+		progress.LastProgressTime = now
+	}
+	newStatus.UpdateProgress = progress
+
+	if newStatus.CurrentRevision == updatedRevision.Name {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetProgressing, nil, progressingReasonUpdateCompleted,
+			fmt.Sprintf("Pods are updated to revision %s", updatedRevision.Name))
+		return 0
+	}
+
+	if failureThreshold, limited := getFailureThreshold(cls); limited {
+		var failedPods []string
+		for _, podWrapper := range podWrappers {
+			if podWrapper.DeletionTimestamp != nil || !controllerutils.IsPodUpdatedRevision(podWrapper.Pod, updatedRevision.Name) {
+				continue
+			}
+
+			if available, _ := synccontrol.IsPodAvailable(cls, podWrapper.Pod); !available && !podopslifecycle.IsDuringOps(collasetutils.UpdateOpsLifecycleAdapter, podWrapper) {
+				failedPods = append(failedPods, podWrapper.Name)
+			}
+		}
+
+		if len(failedPods) > failureThreshold {
+			r.markProgressFailed(cls, newStatus, progressingReasonFailureThresholdExceeded,
+				fmt.Sprintf("%d Pod(s) with revision %s are not available, exceeding threshold %d, like Pod %s", len(failedPods), updatedRevision.Name, failureThreshold, failedPods[0]))
+			return 0
+		}
+	}
+
+	if cls.Spec.UpdateStrategy.ProgressDeadlineSeconds != nil && !isUpdatePending(cls, newStatus) {
+		deadline := time.Duration(*cls.Spec.UpdateStrategy.ProgressDeadlineSeconds) * time.Second
+		elapsed := now.Sub(progress.LastProgressTime.Time)
+		if elapsed >= deadline {
+			r.markProgressFailed(cls, newStatus, progressingReasonProgressDeadlineExceeded,
+				fmt.Sprintf("updating to revision %s makes no progress in %d seconds", updatedRevision.Name, *cls.Spec.UpdateStrategy.ProgressDeadlineSeconds))
+			return 0
+		}
+
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetProgressing, nil, progressingReasonUpdating,
+			fmt.Sprintf("Pods are updating to revision %s", updatedRevision.Name))
+		return deadline - elapsed
+	}
+
+	collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetProgressing, nil, progressingReasonUpdating,
+		fmt.Sprintf("Pods are updating to revision %s", updatedRevision.Name))
+	return 0
+}
+
+func (r *CollaSetReconciler) markProgressFailed(cls *appsv1alpha1.CollaSet, newStatus *appsv1alpha1.CollaSetStatus, reason, message string) {
+	if cond := collasetutils.GetCondition(newStatus, appsv1alpha1.CollaSetProgressing); cond == nil || cond.Status != corev1.ConditionFalse {
+		r.Recorder.Event(cls, corev1.EventTypeWarning, reason, message)
+	}
+
+	collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetProgressing, fmt.Errorf("%s", message), reason, message)
+}
+
+// getFailureThreshold returns the maximum number of Pods with updated revision allowed to be not available.
+func getFailureThreshold(cls *appsv1alpha1.CollaSet) (int, bool) {
+	failurePolicy := cls.Spec.UpdateStrategy.FailurePolicy
+	if failurePolicy == nil || failurePolicy.FailureThreshold == nil {
+		return 0, false
+	}
+
+	replicas := 0
+	if cls.Spec.Replicas != nil {
+		replicas = int(*cls.Spec.Replicas)
+	}
+
+	failureThreshold, err := intstr.GetScaledValueFromIntOrPercent(failurePolicy.FailureThreshold, replicas, true)
+	if err != nil {
+		return 0, false
+	}
+
+	return failureThreshold, true
+}
+
+// getAutoRollbackTo returns the revision to roll back to automatically, if updating fails and FailurePolicy allows.
+func getAutoRollbackTo(cls *appsv1alpha1.CollaSet, currentRevision, updatedRevision *appsv1.ControllerRevision, newStatus *appsv1alpha1.CollaSetStatus) *intstr.IntOrString {
+	failurePolicy := cls.Spec.UpdateStrategy.FailurePolicy
+	if failurePolicy == nil || !failurePolicy.Rollback || currentRevision.Name == updatedRevision.Name ||
+		newStatus.CurrentRevision == updatedRevision.Name {
+		return nil
+	}
+
+	cond := collasetutils.GetCondition(newStatus, appsv1alpha1.CollaSetProgressing)
+	if cond == nil || cond.Status != corev1.ConditionFalse {
+		return nil
+	}
+
+	rollbackTo := intstr.FromString(currentRevision.Name)
+	return &rollbackTo
+}
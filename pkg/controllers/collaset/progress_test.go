@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collaset
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	"kusionstack.io/operating/pkg/utils/mixin"
+)
+
+func TestCheckProgressFailureThreshold(t *testing.T) {
+	newPod := func(name string, readySince time.Duration) *collasetutils.PodWrapper {
+		return &collasetutils.PodWrapper{
+			Pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					Labels: map[string]string{
+						appsv1.ControllerRevisionHashLabelKey: "rev-2",
+						appsv1alpha1.PodServiceAvailableLabel: "true",
+					},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:               corev1.PodReady,
+							Status:             corev1.ConditionTrue,
+							LastTransitionTime: metav1.NewTime(time.Now().Add(-readySince)),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		minReadySeconds int32
+		readySince      time.Duration
+		failed          bool
+	}{
+		"available-without-min-ready-seconds": {
+			readySince: time.Second,
+		},
+		"available-after-min-ready-seconds": {
+			minReadySeconds: 10,
+			readySince:      time.Minute,
+		},
+		"not-available-within-min-ready-seconds": {
+			minReadySeconds: 10,
+			readySince:      time.Second,
+			failed:          true,
+		},
+	}
+
+	for name, tc := range testCases {
+		failureThreshold := intstr.FromInt(1)
+		cls := &appsv1alpha1.CollaSet{
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas:        int32Pointer(3),
+				MinReadySeconds: tc.minReadySeconds,
+				UpdateStrategy: appsv1alpha1.UpdateStrategy{
+					FailurePolicy: &appsv1alpha1.UpdateFailurePolicy{
+						FailureThreshold: &failureThreshold,
+					},
+				},
+			},
+		}
+		updatedRevision := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: "rev-2"}}
+		newStatus := &appsv1alpha1.CollaSetStatus{CurrentRevision: "rev-1"}
+		var podWrappers []*collasetutils.PodWrapper
+		for i := 0; i < 3; i++ {
+			podWrappers = append(podWrappers, newPod(fmt.Sprintf("foo-%d", i), tc.readySince))
+		}
+
+		r := &CollaSetReconciler{ReconcilerMixin: &mixin.ReconcilerMixin{Recorder: record.NewFakeRecorder(10)}}
+		r.checkProgress(cls, updatedRevision, podWrappers, newStatus)
+
+		cond := collasetutils.GetCondition(newStatus, appsv1alpha1.CollaSetProgressing)
+		if cond == nil {
+			t.Fatalf("case %s: expected condition Progressing", name)
+		}
+		if failed := cond.Status == corev1.ConditionFalse; failed != tc.failed {
+			t.Fatalf("case %s: expected failed %t, got %t: %s", name, tc.failed, failed, cond.Message)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pvccontrol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/utils"
+)
+
+// PvcCollaSetContextDataKey is the context data key of the name of CollaSet whose PVC names the instance ID uses.
+// It is kept along with the instance ID migrated across CollaSets, so that the adopting CollaSet reuses the PVCs.
+const PvcCollaSetContextDataKey = "PvcCollaSet"
+
+type Interface interface {
+	GetFilteredPvcs(cls *appsv1alpha1.CollaSet, ownedIDs map[int]*appsv1alpha1.ContextDetail) ([]*corev1.PersistentVolumeClaim, error)
+	CreatePodPvcs(cls *appsv1alpha1.CollaSet, contextDetail *appsv1alpha1.ContextDetail) error
+	DeletePvcs(cls *appsv1alpha1.CollaSet, pvcs []*corev1.PersistentVolumeClaim) error
+	SyncPvcOwnerRefs(cls *appsv1alpha1.CollaSet, pvcs []*corev1.PersistentVolumeClaim) error
+	ReleasePvcs(cls *appsv1alpha1.CollaSet, pvcs []*corev1.PersistentVolumeClaim) error
+	AdoptPvcs(cls *appsv1alpha1.CollaSet, contextDetail *appsv1alpha1.ContextDetail) error
+}
+
+func NewRealPvcControl(client client.Client) Interface {
+	return &RealPvcControl{
+		client: client,
+	}
+}
+
+type RealPvcControl struct {
+	client client.Client
+}
+
+// GetFilteredPvcs returns the PVCs provisioned from the VolumeClaimTemplates of CollaSet, including the ones
+// adopted along with the instance IDs owned. The PVCs released along with their instance IDs are skipped.
+func (pc *RealPvcControl) GetFilteredPvcs(cls *appsv1alpha1.CollaSet, ownedIDs map[int]*appsv1alpha1.ContextDetail) ([]*corev1.PersistentVolumeClaim, error) {
+	if len(cls.Spec.VolumeClaimTemplates) == 0 {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(cls.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse selector of CollaSet %s/%s: %s", cls.Namespace, cls.Name, err)
+	}
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := pc.client.List(context.TODO(), pvcList, &client.ListOptions{Namespace: cls.Namespace, LabelSelector: selector}); err != nil {
+		return nil, err
+	}
+
+	var filteredPvcs []*corev1.PersistentVolumeClaim
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if _, _, owned := ParsePvcName(cls, pvc, ownedIDs); owned {
+			filteredPvcs = append(filteredPvcs, pvc)
+		}
+	}
+
+	return filteredPvcs, nil
+}
+
+// CreatePodPvcs provisions the PVCs for the Pod with the indicated instance ID context. The existing PVCs with the same
+// name will be reused, so that the Pod recreated with the same ID is able to attach its previous volumes.
+func (pc *RealPvcControl) CreatePodPvcs(cls *appsv1alpha1.CollaSet, contextDetail *appsv1alpha1.ContextDetail) error {
+	for i := range cls.Spec.VolumeClaimTemplates {
+		pvcTmp := &cls.Spec.VolumeClaimTemplates[i]
+		claimName := BuildContextPvcName(cls, pvcTmp.Name, contextDetail)
+
+		existing := &corev1.PersistentVolumeClaim{}
+		err := pc.client.Get(context.TODO(), types.NamespacedName{Namespace: cls.Namespace, Name: claimName}, existing)
+		if err == nil {
+			if existing.DeletionTimestamp != nil {
+				return fmt.Errorf("PVC %s/%s is terminating", cls.Namespace, claimName)
+			}
+
+			continue
+		}
+
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("fail to get PVC %s/%s: %s", cls.Namespace, claimName, err)
+		}
+
+		pvc := newPvcFrom(cls, pvcTmp, contextDetail)
+		if err := pc.client.Create(context.TODO(), pvc); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("fail to create PVC %s/%s: %s", pvc.Namespace, pvc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// DeletePvcs deletes the indicated PVCs, and the ones which are already terminating are skipped.
+func (pc *RealPvcControl) DeletePvcs(cls *appsv1alpha1.CollaSet, pvcs []*corev1.PersistentVolumeClaim) error {
+	for _, pvc := range pvcs {
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+
+		if err := pc.client.Delete(context.TODO(), pvc); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("fail to delete PVC %s/%s: %s", pvc.Namespace, pvc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// SyncPvcOwnerRefs flips the owner references of PVCs according to the WhenDeleted policy.
+// PVCs owned by CollaSet are garbage collected along with it, otherwise they are retained after CollaSet deleted.
+func (pc *RealPvcControl) SyncPvcOwnerRefs(cls *appsv1alpha1.CollaSet, pvcs []*corev1.PersistentVolumeClaim) error {
+	retained := RetainPvcsWhenDeleted(cls)
+	for _, pvc := range pvcs {
+		if pvc.DeletionTimestamp != nil || isOwnedBy(pvc, cls) != retained {
+			continue
+		}
+
+		pvc = pvc.DeepCopy()
+		if retained {
+			pvc.OwnerReferences = removeOwnerRef(pvc.OwnerReferences, cls)
+		} else {
+			pvc.OwnerReferences = append(pvc.OwnerReferences, *metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet")))
+		}
+
+		if err := pc.client.Update(context.TODO(), pvc); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("fail to update owner references of PVC %s/%s: %s", pvc.Namespace, pvc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ReleasePvcs marks the PVCs as released along with their instance ID, and removes the owner reference of CollaSet,
+// so that they are retained for the CollaSet adopting the ID.
+func (pc *RealPvcControl) ReleasePvcs(cls *appsv1alpha1.CollaSet, pvcs []*corev1.PersistentVolumeClaim) error {
+	for _, pvc := range pvcs {
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+
+		pvc = pvc.DeepCopy()
+		if pvc.Labels == nil {
+			pvc.Labels = map[string]string{}
+		}
+		pvc.Labels[appsv1alpha1.PvcReleasedLabelKey] = cls.Name
+		pvc.OwnerReferences = removeOwnerRef(pvc.OwnerReferences, cls)
+
+		if err := pc.client.Update(context.TODO(), pvc); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("fail to release PVC %s/%s: %s", pvc.Namespace, pvc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// AdoptPvcs takes over the released PVCs of the adopted instance ID context, by attaching the selector labels
+// and owner reference of CollaSet. The PVCs not found will be provisioned when the Pod with the ID is created.
+func (pc *RealPvcControl) AdoptPvcs(cls *appsv1alpha1.CollaSet, contextDetail *appsv1alpha1.ContextDetail) error {
+	for i := range cls.Spec.VolumeClaimTemplates {
+		claimName := BuildContextPvcName(cls, cls.Spec.VolumeClaimTemplates[i].Name, contextDetail)
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := pc.client.Get(context.TODO(), types.NamespacedName{Namespace: cls.Namespace, Name: claimName}, pvc); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+
+			return fmt.Errorf("fail to get PVC %s/%s: %s", cls.Namespace, claimName, err)
+		}
+
+		if _, released := pvc.Labels[appsv1alpha1.PvcReleasedLabelKey]; !released {
+			continue
+		}
+
+		delete(pvc.Labels, appsv1alpha1.PvcReleasedLabelKey)
+		if cls.Spec.Selector != nil {
+			for k, v := range cls.Spec.Selector.MatchLabels {
+				pvc.Labels[k] = v
+			}
+		}
+		if !RetainPvcsWhenDeleted(cls) && !isOwnedBy(pvc, cls) {
+			pvc.OwnerReferences = append(pvc.OwnerReferences, *metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet")))
+		}
+
+		if err := pc.client.Update(context.TODO(), pvc); err != nil {
+			return fmt.Errorf("fail to adopt PVC %s/%s: %s", pvc.Namespace, pvc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func newPvcFrom(cls *appsv1alpha1.CollaSet, pvcTmp *corev1.PersistentVolumeClaim, contextDetail *appsv1alpha1.ContextDetail) *corev1.PersistentVolumeClaim {
+	id := contextDetail.ID
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   cls.Namespace,
+			Name:        BuildContextPvcName(cls, pvcTmp.Name, contextDetail),
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Spec: *pvcTmp.Spec.DeepCopy(),
+	}
+
+	for k, v := range pvcTmp.Labels {
+		pvc.Labels[k] = v
+	}
+	for k, v := range pvcTmp.Annotations {
+		pvc.Annotations[k] = v
+	}
+
+	// PVCs are selected by the selector of CollaSet, the same as Pods
+	if cls.Spec.Selector != nil {
+		for k, v := range cls.Spec.Selector.MatchLabels {
+			pvc.Labels[k] = v
+		}
+	}
+	pvc.Labels[appsv1alpha1.PodInstanceIDLabelKey] = fmt.Sprintf("%d", id)
+	pvc.Labels[appsv1alpha1.PvcTemplateLabelKey] = pvcTmp.Name
+	utils.ControllByKusionStack(pvc)
+
+	// PVC is owned by CollaSet, and will be deleted along with CollaSet, unless it is retained.
+	if !RetainPvcsWhenDeleted(cls) {
+		pvc.OwnerReferences = append(pvc.OwnerReferences, *metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet")))
+	}
+
+	return pvc
+}
+
+// AttachPodPvcs mounts the PVCs provisioned for the indicated instance ID context to the Pod.
+// A claim from VolumeClaimTemplates takes precedence over any volumes in the Pod template with the same name.
+func AttachPodPvcs(cls *appsv1alpha1.CollaSet, pod *corev1.Pod, contextDetail *appsv1alpha1.ContextDetail) {
+	for i := range cls.Spec.VolumeClaimTemplates {
+		pvcTmp := &cls.Spec.VolumeClaimTemplates[i]
+		volume := corev1.Volume{
+			Name: pvcTmp.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: BuildContextPvcName(cls, pvcTmp.Name, contextDetail),
+				},
+			},
+		}
+
+		replaced := false
+		for j := range pod.Spec.Volumes {
+			if pod.Spec.Volumes[j].Name == volume.Name {
+				pod.Spec.Volumes[j] = volume
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+		}
+	}
+}
+
+// BuildPvcName returns the name of PVC provisioned from the indicated template for the indicated instance ID.
+func BuildPvcName(cls *appsv1alpha1.CollaSet, templateName string, id int) string {
+	return fmt.Sprintf("%s-%s-%d", cls.Name, templateName, id)
+}
+
+// BuildContextPvcName returns the name of PVC provisioned from the indicated template for the indicated instance ID
+// context. The ID migrated from another CollaSet keeps using the PVC names of that CollaSet.
+func BuildContextPvcName(cls *appsv1alpha1.CollaSet, templateName string, contextDetail *appsv1alpha1.ContextDetail) string {
+	if collaSetName, exist := contextDetail.Data[PvcCollaSetContextDataKey]; exist && collaSetName != "" {
+		return fmt.Sprintf("%s-%s-%d", collaSetName, templateName, contextDetail.ID)
+	}
+
+	return BuildPvcName(cls, templateName, contextDetail.ID)
+}
+
+// ParsePvcName returns the template name and instance ID of PVC, and whether the PVC is provisioned by this CollaSet
+// or adopted along with one of the owned instance IDs. The PVCs released along with their instance IDs are excluded.
+func ParsePvcName(cls *appsv1alpha1.CollaSet, pvc *corev1.PersistentVolumeClaim, ownedIDs map[int]*appsv1alpha1.ContextDetail) (string, int, bool) {
+	if pvc.Labels == nil {
+		return "", -1, false
+	}
+
+	if _, released := pvc.Labels[appsv1alpha1.PvcReleasedLabelKey]; released {
+		return "", -1, false
+	}
+
+	templateName, exist := pvc.Labels[appsv1alpha1.PvcTemplateLabelKey]
+	if !exist {
+		return "", -1, false
+	}
+
+	var id int
+	if _, err := fmt.Sscanf(pvc.Labels[appsv1alpha1.PodInstanceIDLabelKey], "%d", &id); err != nil {
+		return "", -1, false
+	}
+
+	if pvc.Name != BuildPvcName(cls, templateName, id) {
+		contextDetail, owned := ownedIDs[id]
+		if !owned || pvc.Name != BuildContextPvcName(cls, templateName, contextDetail) {
+			return "", -1, false
+		}
+	}
+
+	return templateName, id, true
+}
+
+// ParseCollaSetName returns the name of CollaSet which provisions the PVC.
+func ParseCollaSetName(pvc *corev1.PersistentVolumeClaim) (string, bool) {
+	if pvc.Labels == nil {
+		return "", false
+	}
+
+	templateName, exist := pvc.Labels[appsv1alpha1.PvcTemplateLabelKey]
+	if !exist {
+		return "", false
+	}
+
+	id, exist := pvc.Labels[appsv1alpha1.PodInstanceIDLabelKey]
+	if !exist {
+		return "", false
+	}
+
+	suffix := fmt.Sprintf("-%s-%s", templateName, id)
+	if !strings.HasSuffix(pvc.Name, suffix) || len(pvc.Name) == len(suffix) {
+		return "", false
+	}
+
+	return strings.TrimSuffix(pvc.Name, suffix), true
+}
+
+// RetainPvcsWhenDeleted indicates whether PVCs should be retained after CollaSet deleted. It defaults to false.
+func RetainPvcsWhenDeleted(cls *appsv1alpha1.CollaSet) bool {
+	policy := cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy
+	return policy != nil && policy.WhenDeleted == appsv1alpha1.RetainPersistentVolumeClaimRetentionPolicyType
+}
+
+// RetainPvcsWhenScaled indicates whether PVCs should be retained after their Pods scaled in. It defaults to true.
+func RetainPvcsWhenScaled(cls *appsv1alpha1.CollaSet) bool {
+	policy := cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy
+	return policy == nil || policy.WhenScaled != appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType
+}
+
+func isOwnedBy(pvc *corev1.PersistentVolumeClaim, cls *appsv1alpha1.CollaSet) bool {
+	for _, ref := range pvc.OwnerReferences {
+		if ref.UID == cls.UID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeOwnerRef(refs []metav1.OwnerReference, cls *appsv1alpha1.CollaSet) []metav1.OwnerReference {
+	var newRefs []metav1.OwnerReference
+	for _, ref := range refs {
+		if ref.UID == cls.UID {
+			continue
+		}
+		newRefs = append(newRefs, ref)
+	}
+
+	return newRefs
+}
+
+// IsStateful indicates whether the Pods of CollaSet have PVCs provisioned from VolumeClaimTemplates.
+func IsStateful(cls *appsv1alpha1.CollaSet) bool {
+	return len(cls.Spec.VolumeClaimTemplates) > 0
+}
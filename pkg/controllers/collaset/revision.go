@@ -47,6 +47,31 @@ func getCollaSetPatch(cls *appsalphav1.CollaSet) ([]byte, error) {
 		specCopy["volumeClaimTemplates"] = spec["volumeClaimTemplates"]
 	}
 
+	// record the Pod template patches of topology domains, so that changing them rolls out a new revision
+	if topology, exist := spec["topology"].(map[string]interface{}); exist {
+		var domainPatches []interface{}
+		domains, _ := topology["domains"].([]interface{})
+		for _, item := range domains {
+			domain, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if podTemplatePatch, exist := domain["podTemplatePatch"]; exist {
+				domainPatches = append(domainPatches, map[string]interface{}{
+					"name":             domain["name"],
+					"podTemplatePatch": podTemplatePatch,
+				})
+			}
+		}
+
+		if len(domainPatches) > 0 {
+			specCopy["topology"] = map[string]interface{}{
+				"domains": domainPatches,
+			}
+		}
+	}
+
 	objCopy["spec"] = specCopy
 	patch, err := json.Marshal(objCopy)
 	return patch, err
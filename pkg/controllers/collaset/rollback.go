@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collaset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+// collaSetRevisionData is the content stored in ControllerRevision of CollaSet. See getCollaSetPatch.
+type collaSetRevisionData struct {
+	Spec struct {
+		Template             corev1.PodTemplateSpec         `json:"template"`
+		VolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
+		Topology             *struct {
+			Domains []appsv1alpha1.CollaSetTopologyDomain `json:"domains,omitempty"`
+		} `json:"topology,omitempty"`
+	} `json:"spec"`
+}
+
+// restoreTopologyDomainPatches restores the Pod template patches of topology domains by name from the revision data.
+// The domains not recorded in the revision have no patch.
+func restoreTopologyDomainPatches(cls *appsv1alpha1.CollaSet, data *collaSetRevisionData) {
+	if cls.Spec.Topology == nil {
+		return
+	}
+
+	patches := map[string]*runtime.RawExtension{}
+	if data.Spec.Topology != nil {
+		for _, domain := range data.Spec.Topology.Domains {
+			patches[domain.Name] = domain.PodTemplatePatch
+		}
+	}
+
+	for i := range cls.Spec.Topology.Domains {
+		cls.Spec.Topology.Domains[i].PodTemplatePatch = patches[cls.Spec.Topology.Domains[i].Name]
+	}
+}
+
+// getRollbackTo returns the revision which CollaSet is indicated to roll back to,
+// by spec.updateStrategy.rollbackTo or annotation.
+func getRollbackTo(cls *appsv1alpha1.CollaSet) *intstr.IntOrString {
+	if cls.Spec.UpdateStrategy.RollbackTo != nil {
+		return &cls.Spec.UpdateStrategy.RollbackTo.Revision
+	}
+
+	if cls.Annotations != nil {
+		if value, exist := cls.Annotations[appsv1alpha1.CollaSetRollbackToAnnotationKey]; exist {
+			revision := intstr.Parse(value)
+			return &revision
+		}
+	}
+
+	return nil
+}
+
+// findRollbackRevision finds the revision by its name or revision number.
+func findRollbackRevision(rollbackTo *intstr.IntOrString, revisions []*appsv1.ControllerRevision) *appsv1.ControllerRevision {
+	for _, revision := range revisions {
+		if rollbackTo.Type == intstr.Int && revision.Revision == int64(rollbackTo.IntVal) {
+			return revision
+		}
+
+		if rollbackTo.Type == intstr.String && revision.Name == rollbackTo.StrVal {
+			return revision
+		}
+	}
+
+	return nil
+}
+
+// rollback restores the template of CollaSet from the revision indicated to roll back to, and clears the indication.
+// The Pods will be updated to the restored revision in the following reconciling, as the same as a normal update.
+// It returns true if CollaSet is updated.
+func (r *CollaSetReconciler) rollback(ctx context.Context, cls *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, revisions []*appsv1.ControllerRevision) (bool, error) {
+	rollbackTo := getRollbackTo(cls)
+	if rollbackTo == nil {
+		return false, nil
+	}
+
+	target := findRollbackRevision(rollbackTo, revisions)
+	if target == nil {
+		r.Recorder.Eventf(cls, corev1.EventTypeWarning, "RollbackRevisionNotFound", "revision %s to roll back to is not found", rollbackTo.String())
+	}
+
+	var data *collaSetRevisionData
+	if target != nil && target.Name != updatedRevision.Name {
+		data = &collaSetRevisionData{}
+		if err := json.Unmarshal(target.Data.Raw, data); err != nil {
+			return false, fmt.Errorf("fail to parse revision %s to roll back to: %s", target.Name, err)
+		}
+	}
+
+	latest, err := collasetutils.UpdateCollaSetWithRetry(r.Client, cls, func(cls *appsv1alpha1.CollaSet) {
+		cls.Spec.UpdateStrategy.RollbackTo = nil
+		delete(cls.Annotations, appsv1alpha1.CollaSetRollbackToAnnotationKey)
+		if data != nil {
+			cls.Spec.Template = data.Spec.Template
+			cls.Spec.VolumeClaimTemplates = data.Spec.VolumeClaimTemplates
+			restoreTopologyDomainPatches(cls, data)
+		}
+	})
+	if err != nil {
+		return false, fmt.Errorf("fail to roll back CollaSet %s/%s: %s", cls.Namespace, cls.Name, err)
+	}
+
+	if data == nil {
+		// nothing to roll back, like the revision is not found or it is the updated one
+		return true, nil
+	}
+
+	r.Recorder.Eventf(cls, corev1.EventTypeNormal, "Rollback", "roll back from revision %s to revision %s", updatedRevision.Name, target.Name)
+
+	// record the rollback in status
+	newStatus := latest.Status.DeepCopy()
+	newStatus.LastRollback = &appsv1alpha1.CollaSetRollbackStatus{
+		FromRevision: updatedRevision.Name,
+		ToRevision:   target.Name,
+		RollbackTime: metav1.Now(),
+	}
+
+	return true, r.updateStatus(ctx, latest, newStatus)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+)
+
+var podFailingWaitingReasons = map[string]struct{}{
+	"CrashLoopBackOff":           {},
+	"ImagePullBackOff":           {},
+	"ErrImagePull":               {},
+	"InvalidImageName":           {},
+	"CreateContainerConfigError": {},
+}
+
+func isUpdatingByBatches(cls *appsv1alpha1.CollaSet) bool {
+	return cls.Spec.UpdateStrategy.RollingUpdate != nil && len(cls.Spec.UpdateStrategy.RollingUpdate.Batches) > 0
+}
+
+// getBatchPartition returns how many Pods are expected to be updated when the indicated batch is finished.
+// The last batch always includes all the rest Pods.
+func getBatchPartition(cls *appsv1alpha1.CollaSet, batch int) int {
+	replicas := int(realValue(cls.Spec.Replicas))
+	batches := cls.Spec.UpdateStrategy.RollingUpdate.Batches
+	if batch >= len(batches)-1 {
+		return replicas
+	}
+
+	partition := 0
+	for i := 0; i <= batch; i++ {
+		count, err := intstr.GetScaledValueFromIntOrPercent(&batches[i].Replicas, replicas, true)
+		if err != nil || count < 0 {
+			continue
+		}
+		partition += count
+	}
+
+	return integerMin(partition, replicas)
+}
+
+// IsPodFailing indicates whether the Pod is failed, or has containers not able to run.
+func IsPodFailing(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodFailed {
+		return true
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting == nil {
+			continue
+		}
+		if _, exist := podFailingWaitingReasons[status.State.Waiting.Reason]; exist {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isBatchApproved indicates whether the batch is approved by annotation.
+func isBatchApproved(cls *appsv1alpha1.CollaSet, batch int) bool {
+	if cls.Annotations == nil {
+		return false
+	}
+
+	value, exist := cls.Annotations[appsv1alpha1.CollaSetApprovedBatchAnnotationKey]
+	if !exist {
+		return false
+	}
+
+	approved, err := strconv.Atoi(value)
+	return err == nil && approved >= batch
+}
+
+// progressBatches advances the batches of updating with the Pods updated, and records the progress in status.
+// It returns the duration to requeue if the current batch is paused.
+func (sc *RealSyncControl) progressBatches(cls *appsv1alpha1.CollaSet, podInfos []*PodUpdateInfo, updatedRevision *appsv1.ControllerRevision, newStatus *appsv1alpha1.CollaSetStatus) time.Duration {
+	batches := cls.Spec.UpdateStrategy.RollingUpdate.Batches
+
+	// begin from the first batch for a new revision
+	batchStatus := &appsv1alpha1.CollaSetUpdateBatchStatus{
+		Revision: updatedRevision.Name,
+		Phase:    appsv1alpha1.CollaSetUpdateBatchUpdating,
+	}
+	if newStatus.UpdateBatch != nil && newStatus.UpdateBatch.Revision == updatedRevision.Name {
+		batchStatus = newStatus.UpdateBatch.DeepCopy()
+	}
+	if int(batchStatus.Batch) >= len(batches) {
+		batchStatus.Batch = int32(len(batches) - 1)
+	}
+	batchStatus.Partition = int32(getBatchPartition(cls, int(batchStatus.Batch)))
+	newStatus.UpdateBatch = batchStatus
+
+	finishedCount := 0
+	oldRevisionCount := 0
+	var failingPods []string
+	for _, podInfo := range podInfos {
+		if podInfo.DeletionTimestamp != nil {
+			continue
+		}
+
+		if !podInfo.IsUpdatedRevision {
+			oldRevisionCount++
+			continue
+		}
+
+		if IsPodFailing(podInfo.Pod) {
+			failingPods = append(failingPods, podInfo.Name)
+			continue
+		}
+
+		if available, _ := IsPodAvailable(cls, podInfo.Pod); !podInfo.isDuringOps && available {
+			finishedCount++
+		}
+	}
+
+	// stop the progress if there are failing Pods
+	if len(failingPods) > 0 {
+		sort.Strings(failingPods)
+		message := fmt.Sprintf("%d Pod(s) with revision %s are failing in batch %d, like Pod %s", len(failingPods), updatedRevision.Name, batchStatus.Batch, failingPods[0])
+		if batchStatus.Phase != appsv1alpha1.CollaSetUpdateBatchFailed {
+			sc.recorder.Eventf(cls, corev1.EventTypeWarning, "UpdateBatchFailed", message)
+		}
+		batchStatus.Phase = appsv1alpha1.CollaSetUpdateBatchFailed
+		batchStatus.Message = message
+		return 0
+	}
+	batchStatus.Message = ""
+
+	// nothing is left to update
+	if oldRevisionCount == 0 {
+		batchStatus.Phase = appsv1alpha1.CollaSetUpdateBatchCompleted
+		batchStatus.FinishedTime = nil
+		return 0
+	}
+
+	if finishedCount < int(batchStatus.Partition) {
+		batchStatus.Phase = appsv1alpha1.CollaSetUpdateBatchUpdating
+		batchStatus.FinishedTime = nil
+		return 0
+	}
+
+	// the current batch is finished
+	if int(batchStatus.Batch) >= len(batches)-1 {
+		batchStatus.Phase = appsv1alpha1.CollaSetUpdateBatchCompleted
+		return 0
+	}
+
+	if batchStatus.FinishedTime == nil {
+		now := metav1.Now()
+		batchStatus.FinishedTime = &now
+	}
+
+	batch := batches[batchStatus.Batch]
+	if batch.ManualApproval && !isBatchApproved(cls, int(batchStatus.Batch)) {
+		batchStatus.Phase = appsv1alpha1.CollaSetUpdateBatchWaitingForApproval
+		return 0
+	}
+
+	if pause := time.Duration(realValue(batch.PauseSeconds)) * time.Second; pause > 0 {
+		if elapsed := time.Since(batchStatus.FinishedTime.Time); elapsed < pause {
+			batchStatus.Phase = appsv1alpha1.CollaSetUpdateBatchPaused
+			return pause - elapsed
+		}
+	}
+
+	// begin the next batch
+	batchStatus.Batch++
+	batchStatus.Partition = int32(getBatchPartition(cls, int(batchStatus.Batch)))
+	batchStatus.Phase = appsv1alpha1.CollaSetUpdateBatchUpdating
+	batchStatus.FinishedTime = nil
+	sc.recorder.Eventf(cls, corev1.EventTypeNormal, "UpdateBatch", "begin to update batch %d with %d Pod(s) expected to be updated", batchStatus.Batch, batchStatus.Partition)
+
+	return 0
+}
+
+// decidePodToUpdateByBatches decides the Pods to update in the current batch. If the progress stops for
+// failing Pods, only the ones which have been updated or begun updating are kept.
+func decidePodToUpdateByBatches(cls *appsv1alpha1.CollaSet, podInfos []*PodUpdateInfo, batchStatus *appsv1alpha1.CollaSetUpdateBatchStatus) []*PodUpdateInfo {
+	ordered := orderByDefault(podInfos)
+	sort.Sort(ordered)
+
+	partition := getBatchPartition(cls, 0)
+	if batchStatus != nil {
+		partition = int(batchStatus.Partition)
+		if batchStatus.Phase == appsv1alpha1.CollaSetUpdateBatchFailed {
+			partition = 0
+			for _, podInfo := range podInfos {
+				if podInfo.IsUpdatedRevision || podInfo.isDuringOps {
+					partition++
+				}
+			}
+		}
+	}
+
+	if partition > len(podInfos) {
+		partition = len(podInfos)
+	}
+	return podInfos[:partition]
+}
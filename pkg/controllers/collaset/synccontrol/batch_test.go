@@ -0,0 +1,192 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func TestProgressBatches(t *testing.T) {
+	updatedRevision := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: "foo-v2"}}
+
+	newCollaSet := func(annotations map[string]string) *appsv1alpha1.CollaSet {
+		replicas := int32(10)
+		pauseSeconds := int32(60)
+		return &appsv1alpha1.CollaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: annotations,
+			},
+			Spec: appsv1alpha1.CollaSetSpec{
+				Replicas: &replicas,
+				UpdateStrategy: appsv1alpha1.UpdateStrategy{
+					RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+						Batches: []appsv1alpha1.RollingUpdateBatch{
+							{Replicas: intstr.FromInt(1), ManualApproval: true},
+							{Replicas: intstr.FromString("40%"), PauseSeconds: &pauseSeconds},
+							{Replicas: intstr.FromString("50%")},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// newPodInfos returns 10 Pods, and the first updated ones are service available
+	newPodInfos := func(updated int, mutateFn func(pod *corev1.Pod)) []*PodUpdateInfo {
+		var podInfos []*PodUpdateInfo
+		for i := 0; i < 10; i++ {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   fmt.Sprintf("foo-%d", i),
+					Labels: map[string]string{},
+				},
+			}
+			if i < updated {
+				pod.Labels[appsv1alpha1.PodServiceAvailableLabel] = "true"
+				if i == 0 && mutateFn != nil {
+					mutateFn(pod)
+				}
+			}
+			podInfos = append(podInfos, &PodUpdateInfo{
+				PodWrapper:        &collasetutils.PodWrapper{Pod: pod, ID: i},
+				IsUpdatedRevision: i < updated,
+			})
+		}
+		return podInfos
+	}
+
+	testCases := map[string]struct {
+		cls           *appsv1alpha1.CollaSet
+		podInfos      []*PodUpdateInfo
+		lastStatus    *appsv1alpha1.CollaSetUpdateBatchStatus
+		expectedBatch int32
+		expectedPart  int32
+		expectedPhase appsv1alpha1.CollaSetUpdateBatchPhase
+		requeue       bool
+	}{
+		"begin-first-batch": {
+			cls:           newCollaSet(nil),
+			podInfos:      newPodInfos(0, nil),
+			expectedBatch: 0,
+			expectedPart:  1,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchUpdating,
+		},
+		"wait-for-approval": {
+			cls:           newCollaSet(nil),
+			podInfos:      newPodInfos(1, nil),
+			expectedBatch: 0,
+			expectedPart:  1,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchWaitingForApproval,
+		},
+		"approved": {
+			cls:           newCollaSet(map[string]string{appsv1alpha1.CollaSetApprovedBatchAnnotationKey: "0"}),
+			podInfos:      newPodInfos(1, nil),
+			expectedBatch: 1,
+			expectedPart:  5,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchUpdating,
+		},
+		"paused": {
+			cls:      newCollaSet(nil),
+			podInfos: newPodInfos(5, nil),
+			lastStatus: &appsv1alpha1.CollaSetUpdateBatchStatus{
+				Revision: updatedRevision.Name,
+				Batch:    1,
+				Phase:    appsv1alpha1.CollaSetUpdateBatchUpdating,
+			},
+			expectedBatch: 1,
+			expectedPart:  5,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchPaused,
+			requeue:       true,
+		},
+		"pause-expired": {
+			cls:      newCollaSet(nil),
+			podInfos: newPodInfos(5, nil),
+			lastStatus: &appsv1alpha1.CollaSetUpdateBatchStatus{
+				Revision:     updatedRevision.Name,
+				Batch:        1,
+				Phase:        appsv1alpha1.CollaSetUpdateBatchPaused,
+				FinishedTime: &metav1.Time{Time: time.Now().Add(-2 * time.Minute)},
+			},
+			expectedBatch: 2,
+			expectedPart:  10,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchUpdating,
+		},
+		"failing": {
+			cls: newCollaSet(nil),
+			podInfos: newPodInfos(1, func(pod *corev1.Pod) {
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+					{
+						Name: "foo",
+						State: corev1.ContainerState{
+							Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+						},
+					},
+				}
+			}),
+			expectedBatch: 0,
+			expectedPart:  1,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchFailed,
+		},
+		"new-revision": {
+			cls:      newCollaSet(nil),
+			podInfos: newPodInfos(0, nil),
+			lastStatus: &appsv1alpha1.CollaSetUpdateBatchStatus{
+				Revision: "foo-v1",
+				Batch:    2,
+				Phase:    appsv1alpha1.CollaSetUpdateBatchCompleted,
+			},
+			expectedBatch: 0,
+			expectedPart:  1,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchUpdating,
+		},
+		"completed": {
+			cls:           newCollaSet(nil),
+			podInfos:      newPodInfos(10, nil),
+			expectedBatch: 0,
+			expectedPart:  1,
+			expectedPhase: appsv1alpha1.CollaSetUpdateBatchCompleted,
+		},
+	}
+
+	sc := &RealSyncControl{recorder: record.NewFakeRecorder(100)}
+	for name, tc := range testCases {
+		newStatus := &appsv1alpha1.CollaSetStatus{UpdateBatch: tc.lastStatus}
+		requeueAfter := sc.progressBatches(tc.cls, tc.podInfos, updatedRevision, newStatus)
+
+		batchStatus := newStatus.UpdateBatch
+		if batchStatus.Batch != tc.expectedBatch || batchStatus.Partition != tc.expectedPart || batchStatus.Phase != tc.expectedPhase {
+			t.Fatalf("case %s: expected batch %d, partition %d and phase %s, got %d, %d and %s",
+				name, tc.expectedBatch, tc.expectedPart, tc.expectedPhase, batchStatus.Batch, batchStatus.Partition, batchStatus.Phase)
+		}
+
+		if (requeueAfter > 0) != tc.requeue {
+			t.Fatalf("case %s: expected requeue %t, got %s", name, tc.requeue, requeueAfter)
+		}
+	}
+}
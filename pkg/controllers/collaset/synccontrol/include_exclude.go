@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontrol"
+	"kusionstack.io/operating/pkg/controllers/collaset/pvccontrol"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/expectations"
+	commonutils "kusionstack.io/operating/pkg/utils"
+)
+
+// dealIncludeExcludePods releases the Pods indicated by ScaleStrategy.PodToExclude, and adopts the orphan Pods
+// indicated by ScaleStrategy.PodToInclude. Pods are released or adopted without being deleted or recreated.
+// Then the replicas of CollaSet is recomputed, and the handled entries are cleared from ScaleStrategy.
+// It returns the Pods owned by CollaSet after including and excluding, and the instance IDs of the released Pods.
+func (sc *RealSyncControl) dealIncludeExcludePods(cls *appsv1alpha1.CollaSet, pods []*corev1.Pod) ([]*corev1.Pod, []int, error) {
+	if len(cls.Spec.ScaleStrategy.PodToExclude) == 0 && len(cls.Spec.ScaleStrategy.PodToInclude) == 0 {
+		return pods, nil, nil
+	}
+
+	toExclude := sets.NewString(cls.Spec.ScaleStrategy.PodToExclude...)
+	toInclude := sets.NewString(cls.Spec.ScaleStrategy.PodToInclude...)
+	handledExclude := sets.String{}
+	handledInclude := sets.String{}
+	replicasDelta := 0
+
+	var releasedIDs []int
+	var filteredPods []*corev1.Pod
+	ownedPodNames := sets.String{}
+	for _, pod := range pods {
+		ownedPodNames.Insert(pod.Name)
+		if !toExclude.Has(pod.Name) {
+			filteredPods = append(filteredPods, pod)
+			continue
+		}
+
+		if pvccontrol.IsStateful(cls) {
+			// the PVCs are bound with instance ID, which is not able to be released along with Pod
+			sc.recorder.Eventf(cls, corev1.EventTypeWarning, "ExcludePod", "Pod %s/%s is not allowed to exclude from CollaSet with volumeClaimTemplates", pod.Namespace, pod.Name)
+			filteredPods = append(filteredPods, pod)
+			continue
+		}
+
+		// release Pod from CollaSet, and its instance ID is going to be reclaimed.
+		id, _ := collasetutils.GetPodInstanceID(pod)
+		releasedPod := pod.DeepCopy()
+		releasedPod.OwnerReferences = removeControllerRef(releasedPod.OwnerReferences, cls)
+		delete(releasedPod.Labels, appsv1alpha1.PodInstanceIDLabelKey)
+		if err := sc.podControl.UpdatePod(releasedPod); err != nil {
+			return nil, nil, fmt.Errorf("fail to exclude Pod %s/%s: %s", pod.Namespace, pod.Name, err)
+		}
+
+		sc.recorder.Eventf(cls, corev1.EventTypeNormal, "ExcludePod", "succeed to exclude Pod %s/%s", pod.Namespace, pod.Name)
+		if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, releasedPod.Name, releasedPod.ResourceVersion); err != nil {
+			return nil, nil, err
+		}
+
+		if id >= 0 {
+			releasedIDs = append(releasedIDs, id)
+		}
+		handledExclude.Insert(pod.Name)
+		replicasDelta--
+	}
+
+	// the Pods which are not owned by CollaSet are nothing to exclude
+	for _, podName := range toExclude.List() {
+		if !ownedPodNames.Has(podName) {
+			handledExclude.Insert(podName)
+		}
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(cls.Spec.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to parse selector of CollaSet: %s", err)
+	}
+
+	for _, podName := range toInclude.List() {
+		if ownedPodNames.Has(podName) {
+			handledInclude.Insert(podName)
+			continue
+		}
+
+		pod := &corev1.Pod{}
+		if err := sc.client.Get(context.TODO(), types.NamespacedName{Namespace: cls.Namespace, Name: podName}, pod); err != nil {
+			if errors.IsNotFound(err) {
+				handledInclude.Insert(podName)
+				continue
+			}
+
+			return nil, nil, fmt.Errorf("fail to get Pod %s/%s to include: %s", cls.Namespace, podName, err)
+		}
+
+		// wait for the Pod to be released by its current controller
+		if metav1.GetControllerOf(pod) != nil {
+			continue
+		}
+
+		if pod.DeletionTimestamp != nil || podcontrol.IsPodInactive(pod) || !selector.Matches(labels.Set(pod.Labels)) {
+			sc.recorder.Eventf(cls, corev1.EventTypeWarning, "IncludePod", "Pod %s/%s is not allowed to include: it is inactive or not matched with selector", pod.Namespace, pod.Name)
+			handledInclude.Insert(podName)
+			continue
+		}
+
+		// adopt the orphan Pod, and its instance ID will be allocated when syncing.
+		adoptedPod := pod.DeepCopy()
+		adoptedPod.OwnerReferences = append(adoptedPod.OwnerReferences, *metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet")))
+		commonutils.ControllByKusionStack(adoptedPod)
+		if err := sc.podControl.UpdatePod(adoptedPod); err != nil {
+			return nil, nil, fmt.Errorf("fail to include Pod %s/%s: %s", pod.Namespace, pod.Name, err)
+		}
+
+		sc.recorder.Eventf(cls, corev1.EventTypeNormal, "IncludePod", "succeed to include Pod %s/%s", pod.Namespace, pod.Name)
+		if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, adoptedPod.Name, adoptedPod.ResourceVersion); err != nil {
+			return nil, nil, err
+		}
+
+		filteredPods = append(filteredPods, adoptedPod)
+		handledInclude.Insert(podName)
+		replicasDelta++
+	}
+
+	if handledExclude.Len() == 0 && handledInclude.Len() == 0 {
+		return filteredPods, releasedIDs, nil
+	}
+
+	// recompute replicas and clear the handled entries
+	if err := sc.updateReplicas(cls, func(cls *appsv1alpha1.CollaSet) {
+		addReplicas(cls, replicasDelta)
+		cls.Spec.ScaleStrategy.PodToExclude = filterOutNames(cls.Spec.ScaleStrategy.PodToExclude, handledExclude)
+		cls.Spec.ScaleStrategy.PodToInclude = filterOutNames(cls.Spec.ScaleStrategy.PodToInclude, handledInclude)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("fail to recompute replicas after including and excluding Pods: %s", err)
+	}
+
+	return filteredPods, releasedIDs, nil
+}
+
+// allocateIDForPods assigns the Pods, which have no available instance ID or share the same ID with others,
+// with the IDs owned by CollaSet but not in use.
+func (sc *RealSyncControl) allocateIDForPods(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper, ownedIDs map[int]*appsv1alpha1.ContextDetail) error {
+	// the new Pod in replacing shares the same ID with the one it replaces
+	replacePairs := collectReplacePairs(podWrappers)
+	usedIDs := sets.Int{}
+	for _, newPod := range replacePairs {
+		usedIDs.Insert(newPod.ID)
+	}
+	var podsToAllocate []*collasetutils.PodWrapper
+	for _, podWrapper := range filterOutReplacePairs(podWrappers, replacePairs) {
+		if _, owned := ownedIDs[podWrapper.ID]; !owned || usedIDs.Has(podWrapper.ID) {
+			podsToAllocate = append(podsToAllocate, podWrapper)
+			continue
+		}
+
+		usedIDs.Insert(podWrapper.ID)
+	}
+
+	if len(podsToAllocate) == 0 {
+		return nil
+	}
+
+	var availableIDs []int
+	for id, contextDetail := range ownedIDs {
+		if usedIDs.Has(id) || contextDetail.Contains(ScaleInContextDataKey, "true") {
+			continue
+		}
+		availableIDs = append(availableIDs, id)
+	}
+	sortedIDs := sets.NewInt(availableIDs...).List()
+
+	for i, podWrapper := range podsToAllocate {
+		if i >= len(sortedIDs) {
+			// the rest Pods will be left without ID, and are expected to be scaled in
+			break
+		}
+
+		id := sortedIDs[i]
+		pod := podWrapper.Pod.DeepCopy()
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[appsv1alpha1.PodInstanceIDLabelKey] = fmt.Sprintf("%d", id)
+		if err := sc.podControl.UpdatePod(pod); err != nil {
+			return fmt.Errorf("fail to allocate instance ID %d to Pod %s/%s: %s", id, pod.Namespace, pod.Name, err)
+		}
+
+		if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, pod.Name, pod.ResourceVersion); err != nil {
+			return err
+		}
+
+		podWrapper.Pod = pod
+		podWrapper.ID = id
+		podWrapper.ContextDetail = ownedIDs[id]
+	}
+
+	return nil
+}
+
+func removeControllerRef(refs []metav1.OwnerReference, cls *appsv1alpha1.CollaSet) []metav1.OwnerReference {
+	var newRefs []metav1.OwnerReference
+	for _, ref := range refs {
+		if ref.UID == cls.UID && ref.Controller != nil && *ref.Controller {
+			continue
+		}
+		newRefs = append(newRefs, ref)
+	}
+
+	return newRefs
+}
+
+func filterOutNames(names []string, handled sets.String) []string {
+	var filtered []string
+	for _, name := range names {
+		if handled.Has(name) {
+			continue
+		}
+		filtered = append(filtered, name)
+	}
+
+	return filtered
+}
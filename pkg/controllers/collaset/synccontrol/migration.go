@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
+	"kusionstack.io/operating/pkg/controllers/collaset/pvccontrol"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+// dealIDToAdopt adopts the instance IDs indicated by ScaleStrategy.IDToAdopt, once they are released by the other
+// CollaSets sharing the same ResourceContext. The adopted IDs keep their context data, and their released PVCs are
+// taken over. Then the replicas of CollaSet is increased accordingly, and the adopted entries are cleared.
+func (sc *RealSyncControl) dealIDToAdopt(cls *appsv1alpha1.CollaSet, revision string) error {
+	if len(cls.Spec.ScaleStrategy.IDToAdopt) == 0 {
+		return nil
+	}
+
+	var adoptedIDs map[int]*appsv1alpha1.ContextDetail
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		adoptedIDs, err = podcontext.AdoptIDs(sc.client, cls, cls.Spec.ScaleStrategy.IDToAdopt, revision)
+		return err
+	}); err != nil {
+		return fmt.Errorf("fail to adopt IDs in ResourceContext: %s", err)
+	}
+
+	if len(adoptedIDs) == 0 {
+		return nil
+	}
+
+	handledAdopt := sets.Int{}
+	for id, contextDetail := range adoptedIDs {
+		if err := sc.pvcControl.AdoptPvcs(cls, contextDetail); err != nil {
+			return fmt.Errorf("fail to adopt PVCs of ID %d: %s", id, err)
+		}
+
+		handledAdopt.Insert(id)
+	}
+
+	sc.recorder.Eventf(cls, corev1.EventTypeNormal, "IDAdopted", "succeed to adopt IDs %v", handledAdopt.List())
+	return sc.updateMigratedIDs(cls, handledAdopt.Len(), nil, handledAdopt)
+}
+
+// isPodToRelease indicates whether the Pod holds an instance ID indicated by ScaleStrategy.IDToRelease.
+func isPodToRelease(cls *appsv1alpha1.CollaSet, podWrapper *collasetutils.PodWrapper) bool {
+	if podWrapper.ID < 0 {
+		return false
+	}
+
+	for _, id := range cls.Spec.ScaleStrategy.IDToRelease {
+		if id == podWrapper.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countPodToRelease counts the Pods holding the instance IDs to release. These Pods are scaled in regardless of
+// replicas, and the replicas is decreased once their IDs are released.
+func countPodToRelease(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper) int {
+	count := 0
+	for _, podWrapper := range podWrappers {
+		if isPodToRelease(cls, podWrapper) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// releaseContextDetail removes the ownership of CollaSet from the instance ID context, with the other data kept for
+// the CollaSet adopting it. The PVC names of the ID are recorded, so that the adopting CollaSet reuses the PVCs.
+func releaseContextDetail(cls *appsv1alpha1.CollaSet, contextDetail *appsv1alpha1.ContextDetail) {
+	if pvccontrol.IsStateful(cls) && contextDetail.Data[pvccontrol.PvcCollaSetContextDataKey] == "" {
+		contextDetail.Put(pvccontrol.PvcCollaSetContextDataKey, cls.Name)
+	}
+
+	contextDetail.Remove(podcontext.OwnerContextKey)
+	contextDetail.Remove(podcontext.RevisionContextDataKey)
+	contextDetail.Remove(ScaleInContextDataKey)
+}
+
+// updateMigratedIDs recomputes the replicas of CollaSet, and clears the handled entries of ScaleStrategy.IDToRelease
+// and ScaleStrategy.IDToAdopt.
+func (sc *RealSyncControl) updateMigratedIDs(cls *appsv1alpha1.CollaSet, replicasDelta int, handledRelease, handledAdopt sets.Int) error {
+	if err := sc.updateReplicas(cls, func(cls *appsv1alpha1.CollaSet) {
+		addReplicas(cls, replicasDelta)
+		cls.Spec.ScaleStrategy.IDToRelease = filterOutIDs(cls.Spec.ScaleStrategy.IDToRelease, handledRelease)
+		cls.Spec.ScaleStrategy.IDToAdopt = filterOutIDs(cls.Spec.ScaleStrategy.IDToAdopt, handledAdopt)
+	}); err != nil {
+		return fmt.Errorf("fail to clear migrated IDs: %s", err)
+	}
+
+	return nil
+}
+
+func filterOutIDs(ids []int, handled sets.Int) []int {
+	var filtered []int
+	for _, id := range ids {
+		if handled.Has(id) {
+			continue
+		}
+		filtered = append(filtered, id)
+	}
+
+	return filtered
+}
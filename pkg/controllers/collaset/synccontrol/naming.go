@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontrol"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/expectations"
+	commonutils "kusionstack.io/operating/pkg/utils"
+)
+
+// podNameHeldRequeueAfter is the duration to wait for the Pod holding the name of a new Pod to be deleted.
+const podNameHeldRequeueAfter = 5 * time.Second
+
+func isNamedByInstanceID(cls *appsv1alpha1.CollaSet) bool {
+	return cls.Spec.ScaleStrategy.PodNamingPolicy == appsv1alpha1.InstanceIDPodNamingPolicyType
+}
+
+// GetPodNameByInstanceID returns the name of Pod with the instance ID, if CollaSet names Pods by instance ID.
+// Otherwise, it returns empty string, and the Pod is named by generateName.
+func GetPodNameByInstanceID(cls *appsv1alpha1.CollaSet, id int) string {
+	if !isNamedByInstanceID(cls) {
+		return ""
+	}
+
+	return fmt.Sprintf("%s-%d", cls.Name, id)
+}
+
+// filterOutNameHeldContexts filters out the instance ID contexts whose Pod names are still held by existing Pods,
+// like the terminating ones or the inactive ones which are not counted as owned Pods. The inactive Pods owned by
+// CollaSet are deleted to release the names. It returns the duration to requeue for waiting the names released.
+func (sc *RealSyncControl) filterOutNameHeldContexts(cls *appsv1alpha1.CollaSet, contexts []*appsv1alpha1.ContextDetail) ([]*appsv1alpha1.ContextDetail, time.Duration, error) {
+	if !isNamedByInstanceID(cls) {
+		return contexts, 0, nil
+	}
+
+	var requeueAfter time.Duration
+	var availableContexts []*appsv1alpha1.ContextDetail
+	for _, contextDetail := range contexts {
+		pod := &corev1.Pod{}
+		name := GetPodNameByInstanceID(cls, contextDetail.ID)
+		if err := sc.client.Get(context.TODO(), types.NamespacedName{Namespace: cls.Namespace, Name: name}, pod); err != nil {
+			if errors.IsNotFound(err) {
+				availableContexts = append(availableContexts, contextDetail)
+				continue
+			}
+
+			return nil, 0, fmt.Errorf("fail to get Pod %s/%s: %s", cls.Namespace, name, err)
+		}
+
+		requeueAfter = podNameHeldRequeueAfter
+		if pod.DeletionTimestamp != nil {
+			sc.logger.V(1).Info("wait for terminating Pod to release its name", "pod", commonutils.ObjectKeyString(pod))
+			continue
+		}
+
+		if ownerRef := metav1.GetControllerOf(pod); ownerRef == nil || ownerRef.UID != cls.UID {
+			sc.recorder.Eventf(cls, corev1.EventTypeWarning, "PodNameHeld", "Pod %s/%s not owned by CollaSet holds the name of Pod with ID %d", pod.Namespace, pod.Name, contextDetail.ID)
+			continue
+		}
+
+		if podcontrol.IsPodInactive(pod) {
+			if err := sc.podControl.DeletePod(pod); err != nil && !errors.IsNotFound(err) {
+				return nil, 0, fmt.Errorf("fail to delete inactive Pod %s/%s to release its name: %s", pod.Namespace, pod.Name, err)
+			}
+
+			sc.recorder.Eventf(cls, corev1.EventTypeNormal, "PodDeleted", "succeed to delete inactive Pod %s/%s to release its name", pod.Namespace, pod.Name)
+			if err := collasetutils.ActiveExpectations.ExpectDelete(cls, expectations.Pod, pod.Name); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	return availableContexts, requeueAfter, nil
+}
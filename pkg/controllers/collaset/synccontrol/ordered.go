@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
+)
+
+func isOrderedReady(cls *appsv1alpha1.CollaSet) bool {
+	return cls.Spec.PodManagementPolicy == appsv1alpha1.OrderedReadyPodManagementPolicyType
+}
+
+// isPodOrderedReady indicates whether the Pod is service available and has been ready for MinReadySeconds,
+// after which the next Pod is allowed to operate with policy OrderedReady.
+func isPodOrderedReady(cls *appsv1alpha1.CollaSet, pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || !controllerutils.IsServiceAvailable(pod) {
+		return false
+	}
+
+	available, _ := IsPodAvailable(cls, pod)
+	return available
+}
+
+// limitContextsByOrder limits the Pods to create with policy OrderedReady to the one with the lowest instance ID,
+// and none is created until all the existing Pods are ordered ready.
+func limitContextsByOrder(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper, contexts []*appsv1alpha1.ContextDetail) []*appsv1alpha1.ContextDetail {
+	if !isOrderedReady(cls) || len(contexts) == 0 {
+		return contexts
+	}
+
+	for _, podWrapper := range podWrappers {
+		if !isPodOrderedReady(cls, podWrapper.Pod) {
+			return nil
+		}
+	}
+
+	lowest := contexts[0]
+	for _, contextDetail := range contexts {
+		if contextDetail.ID < lowest.ID {
+			lowest = contextDetail
+		}
+	}
+
+	return []*appsv1alpha1.ContextDetail{lowest}
+}
+
+// selectPodsToDeleteByOrder selects the Pods to scale in with policy OrderedReady. The Pods terminating or scaling in
+// are selected until they are deleted, otherwise the one with the highest instance ID is selected once the remaining
+// Pods are ordered ready. The Pods without instance ID are selected first, followed by the ones holding IDs to release.
+func selectPodsToDeleteByOrder(cls *appsv1alpha1.CollaSet, pods []*collasetutils.PodWrapper, count int) []*collasetutils.PodWrapper {
+	if count <= 0 || len(pods) == 0 {
+		return nil
+	}
+
+	var selected []*collasetutils.PodWrapper
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, pod) {
+			selected = append(selected, pod)
+		}
+	}
+	if len(selected) > 0 {
+		if len(selected) > count {
+			selected = selected[:count]
+		}
+		return selected
+	}
+
+	sorted := make([]*collasetutils.PodWrapper, len(pods))
+	copy(sorted, pods)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if (sorted[i].ID < 0) != (sorted[j].ID < 0) {
+			return sorted[i].ID < 0
+		}
+		if lToRelease, rToRelease := isPodToRelease(cls, sorted[i]), isPodToRelease(cls, sorted[j]); lToRelease != rToRelease {
+			return lToRelease
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+
+	for _, pod := range sorted[1:] {
+		if !isPodOrderedReady(cls, pod.Pod) {
+			return nil
+		}
+	}
+
+	return sorted[:1]
+}
+
+// limitPodToUpdateByOrder limits the Pods to update with policy OrderedReady to the one with the highest instance ID.
+// No more Pod begins updating until the Pods updating have finished, and the updated ones are ordered ready.
+func limitPodToUpdateByOrder(cls *appsv1alpha1.CollaSet, podInfos []*PodUpdateInfo, podToUpdate []*PodUpdateInfo) []*PodUpdateInfo {
+	if !isOrderedReady(cls) {
+		return podToUpdate
+	}
+
+	var allowed []*PodUpdateInfo
+	for _, podInfo := range podToUpdate {
+		if podInfo.isDuringOps {
+			allowed = append(allowed, podInfo)
+		}
+	}
+	if len(allowed) > 0 {
+		return allowed
+	}
+
+	for _, podInfo := range podInfos {
+		if podInfo.isDuringOps || podInfo.DeletionTimestamp != nil || (podInfo.IsUpdatedRevision && !isPodOrderedReady(cls, podInfo.Pod)) {
+			return nil
+		}
+	}
+
+	var highest *PodUpdateInfo
+	for _, podInfo := range podToUpdate {
+		if podInfo.IsUpdatedRevision {
+			continue
+		}
+
+		if highest == nil || podInfo.ID > highest.ID {
+			highest = podInfo
+		}
+	}
+	if highest == nil {
+		return nil
+	}
+
+	return []*PodUpdateInfo{highest}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func newOrderedPod(id int, ready bool) *collasetutils.PodWrapper {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("foo-%d", id),
+			Labels: map[string]string{},
+		},
+	}
+	if ready {
+		pod.Labels[appsv1alpha1.PodServiceAvailableLabel] = "true"
+		pod.Status.Conditions = []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+		}
+	}
+
+	return &collasetutils.PodWrapper{Pod: pod, ID: id}
+}
+
+func TestOrderedReady(t *testing.T) {
+	cls := &appsv1alpha1.CollaSet{
+		Spec: appsv1alpha1.CollaSetSpec{
+			PodManagementPolicy: appsv1alpha1.OrderedReadyPodManagementPolicyType,
+		},
+	}
+	contexts := []*appsv1alpha1.ContextDetail{{ID: 3}, {ID: 2}}
+
+	// the Pod with the lowest ID is created after the existing ones are ready
+	if limited := limitContextsByOrder(cls, []*collasetutils.PodWrapper{newOrderedPod(0, true), newOrderedPod(1, false)}, contexts); len(limited) != 0 {
+		t.Fatalf("expected no Pod created before Pod foo-1 ready, got %d", len(limited))
+	}
+	if limited := limitContextsByOrder(cls, []*collasetutils.PodWrapper{newOrderedPod(0, true), newOrderedPod(1, true)}, contexts); len(limited) != 1 || limited[0].ID != 2 {
+		t.Fatalf("expected Pod with ID 2 created, got %v", limited)
+	}
+
+	// the Pod with the highest ID is scaled in, and it is selected until deleted
+	pods := []*collasetutils.PodWrapper{newOrderedPod(0, true), newOrderedPod(2, true), newOrderedPod(1, true)}
+	if selected := selectPodsToDeleteByOrder(cls, pods, 2); len(selected) != 1 || selected[0].ID != 2 {
+		t.Fatalf("expected Pod foo-2 selected to scale in, got %v", selected)
+	}
+
+	// no more Pod is scaled in until the remaining ones are ready
+	pods[2] = newOrderedPod(1, false)
+	if selected := selectPodsToDeleteByOrder(cls, pods, 2); len(selected) != 0 {
+		t.Fatalf("expected no Pod selected to scale in before Pod foo-1 ready, got %v", selected)
+	}
+	pods[2] = newOrderedPod(1, true)
+
+	// the Pod holding the ID to release is scaled in before the one with the highest ID
+	cls.Spec.ScaleStrategy.IDToRelease = []int{0}
+	if selected := selectPodsToDeleteByOrder(cls, pods, 2); len(selected) != 1 || selected[0].ID != 0 {
+		t.Fatalf("expected Pod foo-0 holding ID to release selected to scale in, got %v", selected)
+	}
+	cls.Spec.ScaleStrategy.IDToRelease = nil
+	now := metav1.Now()
+	pods[0].DeletionTimestamp = &now
+	if selected := selectPodsToDeleteByOrder(cls, pods, 2); len(selected) != 1 || selected[0].ID != 0 {
+		t.Fatalf("expected terminating Pod foo-0 selected to scale in, got %v", selected)
+	}
+
+	// the Pod with the highest ID is updated after the updated ones are ready
+	podInfos := []*PodUpdateInfo{
+		{PodWrapper: newOrderedPod(0, true)},
+		{PodWrapper: newOrderedPod(1, true)},
+		{PodWrapper: newOrderedPod(2, false), IsUpdatedRevision: true},
+	}
+	if limited := limitPodToUpdateByOrder(cls, podInfos, podInfos); len(limited) != 0 {
+		t.Fatalf("expected no Pod updated before Pod foo-2 ready, got %d", len(limited))
+	}
+	podInfos[2].PodWrapper = newOrderedPod(2, true)
+	if limited := limitPodToUpdateByOrder(cls, podInfos, podInfos); len(limited) != 1 || limited[0].ID != 1 {
+		t.Fatalf("expected Pod foo-1 updated, got %v", limited)
+	}
+	podInfos[1].isDuringOps = true
+	if limited := limitPodToUpdateByOrder(cls, podInfos, podInfos); len(limited) != 1 || limited[0].ID != 1 {
+		t.Fatalf("expected Pod foo-1 kept updating, got %v", limited)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/expectations"
+	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
+	commonutils "kusionstack.io/operating/pkg/utils"
+)
+
+// dealPodToDelete makes the Pods indicated by ScaleStrategy.PodToDelete go through the PodOpsLifecycle of scaling in.
+// Once a Pod is allowed to scale in, the replicas of CollaSet is decreased by one and its entry is cleared in the same
+// update, so that the Pod is going to be deleted by the following scaling in, which prefers the Pods during scaling in.
+// The entries matching no owned Pod are cleared as well, and reported by a warning event.
+// It returns whether there are entries still waiting for the PodOpsLifecycle.
+func (sc *RealSyncControl) dealPodToDelete(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper) (bool, bool, time.Duration, error) {
+	logger := sc.logger.WithValues("collaset", commonutils.ObjectKeyString(cls))
+	var recordedRequeueAfter time.Duration
+	if len(cls.Spec.ScaleStrategy.PodToDelete) == 0 {
+		return false, false, recordedRequeueAfter, nil
+	}
+
+	toDelete := sets.NewString(cls.Spec.ScaleStrategy.PodToDelete...)
+	matched := sets.String{}
+	var podsToDelete []*collasetutils.PodWrapper
+	for _, podWrapper := range podWrappers {
+		id := strconv.Itoa(podWrapper.ID)
+		if toDelete.Has(podWrapper.Name) || (podWrapper.ID >= 0 && toDelete.Has(id)) {
+			podsToDelete = append(podsToDelete, podWrapper)
+			matched.Insert(podWrapper.Name, id)
+		}
+	}
+
+	// the entries matching no Pod owned by CollaSet are nothing to delete
+	unmatched := toDelete.Difference(matched)
+	handled := sets.NewString(unmatched.UnsortedList()...)
+
+	scaling := false
+	pending := false
+	replicasDelta := 0
+	for _, podWrapper := range podsToDelete {
+		// if CollaSet is paused, only the Pods which have begun PodOpsLifecycle will be scaled in
+		if !cls.Spec.Paused && podWrapper.DeletionTimestamp == nil && !podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podWrapper.Pod) {
+			logger.V(1).Info("try to begin PodOpsLifecycle for deleting Pod in CollaSet", "pod", commonutils.ObjectKeyString(podWrapper))
+			if updated, err := podopslifecycle.Begin(sc.client, collasetutils.ScaleInOpsLifecycleAdapter, podWrapper.Pod); err != nil {
+				return scaling, true, recordedRequeueAfter, fmt.Errorf("fail to begin PodOpsLifecycle for deleting Pod %s/%s: %s", podWrapper.Namespace, podWrapper.Name, err)
+			} else if updated {
+				scaling = true
+				sc.recorder.Eventf(podWrapper.Pod, corev1.EventTypeNormal, "BeginScaleInLifecycle", "succeed to begin PodOpsLifecycle for scaling in")
+				if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, podWrapper.Name, podWrapper.ResourceVersion); err != nil {
+					return scaling, true, recordedRequeueAfter, err
+				}
+			}
+		}
+
+		requeueAfter, allowed := podopslifecycle.AllowOps(collasetutils.ScaleInOpsLifecycleAdapter, realValue(cls.Spec.ScaleStrategy.OperationDelaySeconds), podWrapper.Pod)
+		if !allowed && podWrapper.DeletionTimestamp == nil {
+			pending = true
+			continue
+		}
+
+		if requeueAfter > 0 {
+			pending = true
+			if recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter {
+				recordedRequeueAfter = requeueAfter
+			}
+			continue
+		}
+
+		handled.Insert(podWrapper.Name, strconv.Itoa(podWrapper.ID))
+		// the replicas is decreased for the Pods holding IDs to release once the IDs are released
+		if !isPodToRelease(cls, podWrapper) {
+			replicasDelta--
+		}
+	}
+
+	if handled.Len() == 0 {
+		return scaling, pending, recordedRequeueAfter, nil
+	}
+
+	// decrease replicas and clear the handled entries
+	if err := sc.updateReplicas(cls, func(cls *appsv1alpha1.CollaSet) {
+		addReplicas(cls, replicasDelta)
+		cls.Spec.ScaleStrategy.PodToDelete = filterOutNames(cls.Spec.ScaleStrategy.PodToDelete, handled)
+	}); err != nil {
+		return scaling, true, recordedRequeueAfter, fmt.Errorf("fail to decrease replicas for deleting Pods: %s", err)
+	}
+
+	if replicasDelta < 0 {
+		sc.recorder.Eventf(cls, corev1.EventTypeNormal, "DeletePod", "decrease replicas by %d for deleting indicated Pod(s)", -replicasDelta)
+	}
+	if unmatched.Len() > 0 {
+		sc.recorder.Eventf(cls, corev1.EventTypeWarning, "PodToDeleteNotFound", "drop entries %v of PodToDelete matching no Pod owned by CollaSet", unmatched.List())
+	}
+
+	return true, pending, recordedRequeueAfter, nil
+}
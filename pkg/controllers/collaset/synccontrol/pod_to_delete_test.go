@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func TestDealPodToDeleteWithUnmatchedEntries(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("fail to build scheme: %s", err)
+	}
+	if err := appsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("fail to build scheme: %s", err)
+	}
+
+	replicas := int32(2)
+	cls := &appsv1alpha1.CollaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test",
+			Name:      "foo",
+		},
+		Spec: appsv1alpha1.CollaSetSpec{
+			Replicas: &replicas,
+			ScaleStrategy: appsv1alpha1.ScaleStrategy{
+				PodToDelete: []string{"foo-0", "foo-9", "7"},
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test",
+			Name:      "foo-0",
+			Labels:    map[string]string{},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cls, pod).Build()
+	collasetutils.InitExpectations(c)
+	recorder := record.NewFakeRecorder(10)
+	sc := &RealSyncControl{client: c, logger: logr.Discard(), recorder: recorder}
+
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "test", Name: "foo"}, cls); err != nil {
+		t.Fatalf("fail to get CollaSet: %s", err)
+	}
+	podWrappers := []*collasetutils.PodWrapper{{Pod: pod, ID: 0}}
+	scaling, pending, _, err := sc.dealPodToDelete(cls, podWrappers)
+	if err != nil {
+		t.Fatalf("fail to deal PodToDelete: %s", err)
+	}
+	if !scaling || !pending {
+		t.Fatalf("expected scaling and pending, got %t and %t", scaling, pending)
+	}
+
+	// Pod foo-0 is waiting for PodOpsLifecycle, and the unmatched entries are dropped
+	latest := &appsv1alpha1.CollaSet{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "test", Name: "foo"}, latest); err != nil {
+		t.Fatalf("fail to get CollaSet: %s", err)
+	}
+	if podToDelete := latest.Spec.ScaleStrategy.PodToDelete; len(podToDelete) != 1 || podToDelete[0] != "foo-0" {
+		t.Fatalf("expected PodToDelete [foo-0], got %v", podToDelete)
+	}
+	if realValue(latest.Spec.Replicas) != replicas {
+		t.Fatalf("expected replicas %d, got %d", replicas, realValue(latest.Spec.Replicas))
+	}
+
+	var warning string
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.HasPrefix(event, corev1.EventTypeWarning) {
+			warning = event
+		}
+	}
+	if !strings.Contains(warning, "PodToDeleteNotFound") || !strings.Contains(warning, "[7 foo-9]") {
+		t.Fatalf("expected warning event listing the dropped entries, got %q", warning)
+	}
+}
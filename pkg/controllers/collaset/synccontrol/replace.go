@@ -0,0 +1,263 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
+	"kusionstack.io/operating/pkg/controllers/collaset/pvccontrol"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/expectations"
+	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
+	commonutils "kusionstack.io/operating/pkg/utils"
+)
+
+// collectReplacePairs returns the new Pods by the names of the origin Pods they replace.
+// A pair is only valid when both Pods are active and share the same instance ID.
+func collectReplacePairs(podWrappers []*collasetutils.PodWrapper) map[string]*collasetutils.PodWrapper {
+	podByName := map[string]*collasetutils.PodWrapper{}
+	for _, podWrapper := range podWrappers {
+		podByName[podWrapper.Name] = podWrapper
+	}
+
+	pairs := map[string]*collasetutils.PodWrapper{}
+	for _, podWrapper := range podWrappers {
+		if podWrapper.Annotations == nil {
+			continue
+		}
+
+		originName, exist := podWrapper.Annotations[appsv1alpha1.PodReplacePairOriginNameAnnotationKey]
+		if !exist {
+			continue
+		}
+
+		if origin, exist := podByName[originName]; exist && origin.ID == podWrapper.ID && origin.Name != podWrapper.Name {
+			pairs[originName] = podWrapper
+		}
+	}
+
+	return pairs
+}
+
+// isPodInReplacing indicates whether the Pod is the origin or the new one of a replace pair,
+// or is indicated to be replaced by label.
+func isPodInReplacing(pod *corev1.Pod, replacePairs map[string]*collasetutils.PodWrapper) bool {
+	if _, exist := pod.Labels[appsv1alpha1.CollaSetReplaceIndicateLabelKey]; exist {
+		return true
+	}
+
+	if _, exist := replacePairs[pod.Name]; exist {
+		return true
+	}
+
+	if pod.Annotations != nil {
+		if originName, exist := pod.Annotations[appsv1alpha1.PodReplacePairOriginNameAnnotationKey]; exist {
+			if newPod, exist := replacePairs[originName]; exist && newPod.Name == pod.Name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// filterOutReplacePairs returns the Pods which are neither the origin nor the new one of a replace pair.
+func filterOutReplacePairs(podWrappers []*collasetutils.PodWrapper, replacePairs map[string]*collasetutils.PodWrapper) []*collasetutils.PodWrapper {
+	newPodNames := map[string]struct{}{}
+	for _, newPod := range replacePairs {
+		newPodNames[newPod.Name] = struct{}{}
+	}
+
+	var filtered []*collasetutils.PodWrapper
+	for _, podWrapper := range podWrappers {
+		if _, exist := replacePairs[podWrapper.Name]; exist {
+			continue
+		}
+		if _, exist := newPodNames[podWrapper.Name]; exist {
+			continue
+		}
+		filtered = append(filtered, podWrapper)
+	}
+
+	return filtered
+}
+
+// replacePods replaces the Pods indicated by the label CollaSetReplaceIndicateLabelKey. A new Pod with updated revision
+// is created on the same instance ID first, and linked with the origin one by annotations. Once the new Pod is service
+// available, the origin one goes through the PodOpsLifecycle of scaling in and is deleted. The instance ID is not
+// marked as scaling in, so that it is kept for the new Pod.
+func (sc *RealSyncControl) replacePods(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper, updatedRevision *appsv1.ControllerRevision, ownedIDs map[int]*appsv1alpha1.ContextDetail) (bool, time.Duration, error) {
+	logger := sc.logger.WithValues("collaset", commonutils.ObjectKeyString(cls))
+	var recordedRequeueAfter time.Duration
+
+	replacePairs := collectReplacePairs(podWrappers)
+	newPodNames := map[string]struct{}{}
+	for _, newPod := range replacePairs {
+		newPodNames[newPod.Name] = struct{}{}
+	}
+
+	var podsToReplace, podsToDelete []*collasetutils.PodWrapper
+	for _, podWrapper := range podWrappers {
+		if newPod, exist := replacePairs[podWrapper.Name]; exist {
+			// once the new Pod is created, the origin one is going to be deleted even if the label is removed
+			if available, _ := IsPodAvailable(cls, newPod.Pod); available {
+				podsToDelete = append(podsToDelete, podWrapper)
+			}
+			continue
+		}
+
+		if _, exist := podWrapper.Labels[appsv1alpha1.CollaSetReplaceIndicateLabelKey]; !exist {
+			continue
+		}
+
+		// the new Pod is not allowed to be replaced until its origin one is deleted,
+		// and the Pod during other ops is replaced after the ops finished.
+		if _, exist := newPodNames[podWrapper.Name]; exist || podWrapper.DeletionTimestamp != nil || podWrapper.ID < 0 ||
+			podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podWrapper) {
+			continue
+		}
+
+		if pvccontrol.IsStateful(cls) {
+			// the PVCs are bound with instance ID, which are not able to be shared by two Pods
+			sc.recorder.Eventf(cls, corev1.EventTypeWarning, "ReplacePod", "Pod %s/%s is not allowed to replace in CollaSet with volumeClaimTemplates", podWrapper.Namespace, podWrapper.Name)
+			continue
+		}
+
+		if isNamedByInstanceID(cls) {
+			// the new Pod is not able to hold the same name with the origin one at the same time
+			sc.recorder.Eventf(cls, corev1.EventTypeWarning, "ReplacePod", "Pod %s/%s is not allowed to replace in CollaSet naming Pods by instance ID", podWrapper.Namespace, podWrapper.Name)
+			continue
+		}
+
+		podsToReplace = append(podsToReplace, podWrapper)
+	}
+
+	// 1. create new Pods on the same instance IDs with the origin ones
+	replacing := false
+	if len(podsToReplace) > 0 && !cls.Spec.Paused {
+		succCount, err := controllerutils.SlowStartBatchWithLimit(len(podsToReplace), controllerutils.SlowStartInitialBatchSize, getUpdateMaxConcurrency(cls), false, func(idx int, _ error) error {
+			originPod := podsToReplace[idx]
+			domain := GetPodTopologyDomain(originPod.Pod)
+			pod, err := collasetutils.NewPodFrom(cls, metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet")), updatedRevision, domain, ownedIDs[originPod.ID])
+			if err != nil {
+				return fmt.Errorf("fail to new Pod from revision %s: %s", updatedRevision.Name, err)
+			}
+			newPod := pod.DeepCopy()
+			newPod.Labels[appsv1alpha1.PodInstanceIDLabelKey] = fmt.Sprintf("%d", originPod.ID)
+			applyTopologyDomain(cls, newPod, domain)
+			if newPod.Annotations == nil {
+				newPod.Annotations = map[string]string{}
+			}
+			newPod.Annotations[appsv1alpha1.PodReplacePairOriginNameAnnotationKey] = originPod.Name
+
+			logger.V(1).Info("try to create Pod to replace", "pod", commonutils.ObjectKeyString(originPod), "revision", updatedRevision.Name)
+			if newPod, err = sc.podControl.CreatePod(newPod); err != nil {
+				return fmt.Errorf("fail to create Pod to replace Pod %s/%s: %s", originPod.Namespace, originPod.Name, err)
+			}
+			if err := collasetutils.ActiveExpectations.ExpectCreate(cls, expectations.Pod, newPod.Name); err != nil {
+				return err
+			}
+			sc.recorder.Eventf(originPod.Pod, corev1.EventTypeNormal, "ReplacePod", "succeed to create Pod %s to replace", newPod.Name)
+
+			// link the origin Pod with the new one
+			linkedPod := originPod.Pod.DeepCopy()
+			if linkedPod.Annotations == nil {
+				linkedPod.Annotations = map[string]string{}
+			}
+			linkedPod.Annotations[appsv1alpha1.PodReplacePairNewNameAnnotationKey] = newPod.Name
+			if err := sc.podControl.UpdatePod(linkedPod); err != nil {
+				return fmt.Errorf("fail to link Pod %s/%s with its replacement %s: %s", originPod.Namespace, originPod.Name, newPod.Name, err)
+			}
+			return collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, linkedPod.Name, linkedPod.ResourceVersion)
+		})
+		replacing = succCount > 0
+		if err != nil {
+			return replacing, recordedRequeueAfter, err
+		}
+
+		// the instance IDs are held by the new Pods with updated revision after replacing
+		needUpdateContext := false
+		for _, podWrapper := range podsToReplace {
+			if contextDetail, exist := ownedIDs[podWrapper.ID]; exist && !contextDetail.Contains(podcontext.RevisionContextDataKey, updatedRevision.Name) {
+				needUpdateContext = true
+				contextDetail.Put(podcontext.RevisionContextDataKey, updatedRevision.Name)
+			}
+		}
+
+		if needUpdateContext {
+			if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				return podcontext.UpdateToPodContext(sc.client, cls, ownedIDs)
+			}); err != nil {
+				return replacing, recordedRequeueAfter, fmt.Errorf("fail to update ResourceContext for replacing: %s", err)
+			}
+		}
+	}
+
+	// 2. delete the origin Pods whose new ones are service available through PodOpsLifecycle
+	for _, podWrapper := range podsToDelete {
+		if !podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podWrapper) {
+			// if CollaSet is paused, only the Pods which have begun PodOpsLifecycle will be deleted
+			if cls.Spec.Paused {
+				continue
+			}
+
+			logger.V(1).Info("try to begin PodOpsLifecycle for replacing Pod in CollaSet", "pod", commonutils.ObjectKeyString(podWrapper))
+			if updated, err := podopslifecycle.Begin(sc.client, collasetutils.ScaleInOpsLifecycleAdapter, podWrapper.Pod); err != nil {
+				return replacing, recordedRequeueAfter, fmt.Errorf("fail to begin PodOpsLifecycle for replacing Pod %s/%s: %s", podWrapper.Namespace, podWrapper.Name, err)
+			} else if updated {
+				replacing = true
+				sc.recorder.Eventf(podWrapper.Pod, corev1.EventTypeNormal, "BeginScaleInLifecycle", "succeed to begin PodOpsLifecycle for replacing")
+				if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, podWrapper.Name, podWrapper.ResourceVersion); err != nil {
+					return replacing, recordedRequeueAfter, err
+				}
+			}
+		}
+
+		requeueAfter, allowed := podopslifecycle.AllowOps(collasetutils.ScaleInOpsLifecycleAdapter, realValue(cls.Spec.ScaleStrategy.OperationDelaySeconds), podWrapper.Pod)
+		if !allowed {
+			continue
+		}
+
+		if requeueAfter > 0 {
+			if recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter {
+				recordedRequeueAfter = requeueAfter
+			}
+			continue
+		}
+
+		logger.V(1).Info("try to delete Pod replaced", "pod", commonutils.ObjectKeyString(podWrapper))
+		if err := sc.podControl.DeletePod(podWrapper.Pod); err != nil {
+			return replacing, recordedRequeueAfter, fmt.Errorf("fail to delete Pod %s/%s replaced: %s", podWrapper.Namespace, podWrapper.Name, err)
+		}
+		replacing = true
+		sc.recorder.Eventf(cls, corev1.EventTypeNormal, "PodDeleted", "succeed to delete Pod %s/%s replaced by %s", podWrapper.Namespace, podWrapper.Name, replacePairs[podWrapper.Name].Name)
+		if err := collasetutils.ActiveExpectations.ExpectDelete(cls, expectations.Pod, podWrapper.Name); err != nil {
+			return replacing, recordedRequeueAfter, err
+		}
+	}
+
+	return replacing, recordedRequeueAfter, nil
+}
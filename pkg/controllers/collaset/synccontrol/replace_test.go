@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func TestCollectReplacePairs(t *testing.T) {
+	newPod := func(name string, id int, originName string) *collasetutils.PodWrapper {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      map[string]string{},
+				Annotations: map[string]string{},
+			},
+		}
+		if originName != "" {
+			pod.Annotations[appsv1alpha1.PodReplacePairOriginNameAnnotationKey] = originName
+		}
+		return &collasetutils.PodWrapper{Pod: pod, ID: id}
+	}
+
+	podWrappers := []*collasetutils.PodWrapper{
+		newPod("foo-a", 0, ""),
+		newPod("foo-b", 0, "foo-a"),
+		// the origin Pod has gone
+		newPod("foo-c", 1, "foo-x"),
+		// the new Pod is not on the same instance ID
+		newPod("foo-d", 2, ""),
+		newPod("foo-e", 3, "foo-d"),
+	}
+
+	pairs := collectReplacePairs(podWrappers)
+	if len(pairs) != 1 || pairs["foo-a"] == nil || pairs["foo-a"].Name != "foo-b" {
+		t.Fatalf("expected only pair foo-a and foo-b, got %v", pairs)
+	}
+
+	filtered := filterOutReplacePairs(podWrappers, pairs)
+	if len(filtered) != 3 {
+		t.Fatalf("expected 3 Pods not in replace pair, got %d", len(filtered))
+	}
+
+	if !isPodInReplacing(podWrappers[1].Pod, pairs) || isPodInReplacing(podWrappers[2].Pod, pairs) {
+		t.Fatalf("unexpected result of checking Pods in replacing")
+	}
+}
@@ -17,40 +17,236 @@ limitations under the License.
 package synccontrol
 
 import (
+	"context"
+	"fmt"
 	"sort"
+	"strconv"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
 	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
 	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
 )
 
-func getPodsToDelete(filteredPods []*collasetutils.PodWrapper, diff int) []*collasetutils.PodWrapper {
-	sort.Sort(ActivePodsForDeletion(filteredPods))
-	start := len(filteredPods) - diff
-	if start < 0 {
-		start = 0
+// PodDeletionCostAnnotationKey is the annotation indicating the cost of deleting a Pod, the same as ReplicaSet.
+const PodDeletionCostAnnotationKey = "controller.kubernetes.io/pod-deletion-cost"
+
+// getPodsToDelete chooses diff Pods to scale in from the filtered Pods by the scale-in policies of CollaSet. The Pods
+// are taken from the front of the scale-in order, so the terminating ones and the ones during scaling in go first,
+// followed by the ones holding the IDs to release.
+func (sc *RealSyncControl) getPodsToDelete(cls *appsv1alpha1.CollaSet, filteredPods []*collasetutils.PodWrapper, revisions []*appsv1.ControllerRevision, diff int) ([]*collasetutils.PodWrapper, error) {
+	if err := sc.sortPodsToDelete(cls, filteredPods, revisions); err != nil {
+		return nil, err
+	}
+
+	if diff > len(filteredPods) {
+		diff = len(filteredPods)
+	}
+	if isOrderedReady(cls) {
+		return selectPodsToDeleteByOrder(cls, filteredPods, diff), nil
+	}
+	if isSpreadByTopology(cls) {
+		return selectPodsToDeleteByTopology(cls, filteredPods, filteredPods, diff), nil
+	}
+	return filteredPods[:diff], nil
+}
+
+// getScaleMaxConcurrency returns the maximum number of Pods operated concurrently during scaling, which is also
+// limited by the global ceiling of batch size.
+func getScaleMaxConcurrency(cls *appsv1alpha1.CollaSet) int {
+	return limitMaxConcurrency(cls.Spec.ScaleStrategy.MaxConcurrency)
+}
+
+// getUpdateMaxConcurrency returns the maximum number of Pods operated concurrently during updating, which is also
+// limited by the global ceiling of batch size.
+func getUpdateMaxConcurrency(cls *appsv1alpha1.CollaSet) int {
+	return limitMaxConcurrency(cls.Spec.UpdateStrategy.MaxConcurrency)
+}
+
+func limitMaxConcurrency(maxConcurrency *int32) int {
+	limit := controllerutils.SlowStartMaxBatchSize
+	if maxConcurrency != nil && *maxConcurrency > 0 && (limit <= 0 || int(*maxConcurrency) < limit) {
+		limit = int(*maxConcurrency)
+	}
+
+	return limit
+}
+
+// sortPodsToDelete sorts Pods in the order of being scaled in.
+func (sc *RealSyncControl) sortPodsToDelete(cls *appsv1alpha1.CollaSet, pods []*collasetutils.PodWrapper, revisions []*appsv1.ControllerRevision) error {
+	sorter := newPodsToDeleteSorter(pods, cls.Spec.ScaleStrategy.ScaleInPolicies, revisions)
+	sorter.idToRelease = sets.NewInt(cls.Spec.ScaleStrategy.IDToRelease...)
+	if sorter.zoneCounts != nil {
+		nodeZones, err := sc.getNodeZones()
+		if err != nil {
+			return err
+		}
+		sorter.countZones(nodeZones)
+	}
+
+	sort.Sort(sorter)
+	return nil
+}
+
+// getNodeZones returns the zone of each node, which is indicated by the node label topology.kubernetes.io/zone.
+func (sc *RealSyncControl) getNodeZones() (map[string]string, error) {
+	nodeList := &corev1.NodeList{}
+	if err := sc.client.List(context.TODO(), nodeList); err != nil {
+		return nil, fmt.Errorf("fail to list nodes for scaling in spread by zone: %s", err)
+	}
+
+	nodeZones := map[string]string{}
+	for i := range nodeList.Items {
+		if zone, exist := nodeList.Items[i].Labels[corev1.LabelTopologyZone]; exist {
+			nodeZones[nodeList.Items[i].Name] = zone
+		}
+	}
+
+	return nodeZones, nil
+}
+
+// podsToDeleteSorter sorts Pods in the order of being scaled in. Pods which are terminating or during scaling in
+// are always in front, and then the scale-in policies are applied in order, at last the default order by ComparePod.
+type podsToDeleteSorter struct {
+	pods     []*collasetutils.PodWrapper
+	policies []appsv1alpha1.ScaleInPolicyType
+
+	revisionNumbers map[string]int64
+	nodeCounts      map[string]int
+	nodeZones       map[string]string
+	zoneCounts      map[string]int
+	idToRelease     sets.Int
+}
+
+func newPodsToDeleteSorter(pods []*collasetutils.PodWrapper, policies []appsv1alpha1.ScaleInPolicyType, revisions []*appsv1.ControllerRevision) *podsToDeleteSorter {
+	s := &podsToDeleteSorter{
+		pods:     pods,
+		policies: policies,
+	}
+
+	for _, policy := range policies {
+		switch policy {
+		case appsv1alpha1.OldestRevisionScaleInPolicyType:
+			s.revisionNumbers = map[string]int64{}
+			for _, revision := range revisions {
+				s.revisionNumbers[revision.Name] = revision.Revision
+			}
+		case appsv1alpha1.SpreadByNodeScaleInPolicyType:
+			s.nodeCounts = map[string]int{}
+			for _, pod := range pods {
+				if pod.Spec.NodeName != "" {
+					s.nodeCounts[pod.Spec.NodeName]++
+				}
+			}
+		case appsv1alpha1.SpreadByZoneScaleInPolicyType:
+			s.zoneCounts = map[string]int{}
+		}
+	}
+
+	return s
+}
+
+// countZones counts Pods in each zone with the zones of nodes.
+func (s *podsToDeleteSorter) countZones(nodeZones map[string]string) {
+	s.nodeZones = nodeZones
+	for _, pod := range s.pods {
+		if zone, exist := nodeZones[pod.Spec.NodeName]; exist {
+			s.zoneCounts[zone]++
+		}
 	}
-	return filteredPods[start:]
 }
 
-type ActivePodsForDeletion []*collasetutils.PodWrapper
+func (s *podsToDeleteSorter) Len() int      { return len(s.pods) }
+func (s *podsToDeleteSorter) Swap(i, j int) { s.pods[i], s.pods[j] = s.pods[j], s.pods[i] }
 
-func (s ActivePodsForDeletion) Len() int      { return len(s) }
-func (s ActivePodsForDeletion) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s *podsToDeleteSorter) Less(i, j int) bool {
+	l, r := s.pods[i], s.pods[j]
 
-func (s ActivePodsForDeletion) Less(i, j int) bool {
-	l, r := s[i], s[j]
+	// Pods which are terminating should be deleted first
+	lTerminating := l.DeletionTimestamp != nil
+	rTerminating := r.DeletionTimestamp != nil
+	if lTerminating != rTerminating {
+		return lTerminating
+	}
 
 	lDuringScaleIn := podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, l)
 	rDuringScaleIn := podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, r)
+	if lDuringScaleIn != rDuringScaleIn {
+		return lDuringScaleIn
+	}
 
-	if lDuringScaleIn && !rDuringScaleIn {
-		return true
+	// Pods holding the IDs to release are deleted regardless of the policies
+	lToRelease := l.ID >= 0 && s.idToRelease.Has(l.ID)
+	rToRelease := r.ID >= 0 && s.idToRelease.Has(r.ID)
+	if lToRelease != rToRelease {
+		return lToRelease
 	}
 
-	if !lDuringScaleIn && rDuringScaleIn {
-		return false
+	for _, policy := range s.policies {
+		if less, decided := s.compareByPolicy(policy, l, r); decided {
+			return less
+		}
 	}
 
 	return controllerutils.ComparePod(l.Pod, r.Pod)
 }
+
+// compareByPolicy compares two Pods by the indicated policy. It returns whether l should be deleted before r,
+// and whether they are able to be distinguished by the policy.
+func (s *podsToDeleteSorter) compareByPolicy(policy appsv1alpha1.ScaleInPolicyType, l, r *collasetutils.PodWrapper) (bool, bool) {
+	switch policy {
+	case appsv1alpha1.NotReadyScaleInPolicyType:
+		lReady, rReady := controllerutils.IsPodReady(l.Pod), controllerutils.IsPodReady(r.Pod)
+		return !lReady, lReady != rReady
+	case appsv1alpha1.HighestInstanceIDScaleInPolicyType:
+		return l.ID > r.ID, l.ID != r.ID
+	case appsv1alpha1.OldestRevisionScaleInPolicyType:
+		// Pods with unknown revision are regarded as the oldest ones
+		lRevision := s.revisionNumbers[l.Labels[appsv1.ControllerRevisionHashLabelKey]]
+		rRevision := s.revisionNumbers[r.Labels[appsv1.ControllerRevisionHashLabelKey]]
+		return lRevision < rRevision, lRevision != rRevision
+	case appsv1alpha1.DeletionCostScaleInPolicyType:
+		lCost, rCost := getPodDeletionCost(l.Pod), getPodDeletionCost(r.Pod)
+		return lCost < rCost, lCost != rCost
+	case appsv1alpha1.SpreadByNodeScaleInPolicyType:
+		return compareByDomainCount(l.Spec.NodeName, r.Spec.NodeName, s.nodeCounts)
+	case appsv1alpha1.SpreadByZoneScaleInPolicyType:
+		return compareByDomainCount(s.nodeZones[l.Spec.NodeName], s.nodeZones[r.Spec.NodeName], s.zoneCounts)
+	}
+
+	return false, false
+}
+
+// compareByDomainCount prefers the Pod in the domain with more Pods, so that Pods keep spread after scaling in.
+// Pods without domain, like not scheduled, are preferred most.
+func compareByDomainCount(lDomain, rDomain string, domainCounts map[string]int) (bool, bool) {
+	if (lDomain == "") != (rDomain == "") {
+		return lDomain == "", true
+	}
+
+	lCount, rCount := domainCounts[lDomain], domainCounts[rDomain]
+	return lCount > rCount, lCount != rCount
+}
+
+// getPodDeletionCost returns the deletion cost of Pod, which is 0 if not indicated or invalid.
+func getPodDeletionCost(pod *corev1.Pod) int64 {
+	if pod.Annotations == nil {
+		return 0
+	}
+
+	value, exist := pod.Annotations[PodDeletionCostAnnotationKey]
+	if !exist {
+		return 0
+	}
+
+	cost, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0
+	}
+
+	return cost
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func TestPodsToDeleteSorter(t *testing.T) {
+	newPod := func(id int, mutateFn func(pod *corev1.Pod)) *collasetutils.PodWrapper {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("foo-%d", id),
+				Labels: map[string]string{
+					appsv1.ControllerRevisionHashLabelKey: "foo-v2",
+				},
+				Annotations: map[string]string{},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "node-a",
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{
+						Type:   corev1.PodReady,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		}
+
+		if mutateFn != nil {
+			mutateFn(pod)
+		}
+		return &collasetutils.PodWrapper{Pod: pod, ID: id}
+	}
+
+	revisions := []*appsv1.ControllerRevision{
+		{ObjectMeta: metav1.ObjectMeta{Name: "foo-v1"}, Revision: 1},
+		{ObjectMeta: metav1.ObjectMeta{Name: "foo-v2"}, Revision: 2},
+	}
+	nodeZones := map[string]string{
+		"node-a": "zone-a",
+		"node-b": "zone-a",
+		"node-c": "zone-b",
+	}
+
+	testCases := map[string]struct {
+		policies []appsv1alpha1.ScaleInPolicyType
+		pods     []*collasetutils.PodWrapper
+		expected int
+	}{
+		"not-ready-first": {
+			policies: []appsv1alpha1.ScaleInPolicyType{appsv1alpha1.NotReadyScaleInPolicyType},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, nil),
+				newPod(1, func(pod *corev1.Pod) {
+					pod.Status.Conditions[0].Status = corev1.ConditionFalse
+				}),
+				newPod(2, nil),
+			},
+			expected: 1,
+		},
+		"highest-instance-id-first": {
+			policies: []appsv1alpha1.ScaleInPolicyType{appsv1alpha1.HighestInstanceIDScaleInPolicyType},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, nil),
+				newPod(5, nil),
+				newPod(2, nil),
+			},
+			expected: 5,
+		},
+		"oldest-revision-first": {
+			policies: []appsv1alpha1.ScaleInPolicyType{appsv1alpha1.OldestRevisionScaleInPolicyType},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, nil),
+				newPod(1, func(pod *corev1.Pod) {
+					pod.Labels[appsv1.ControllerRevisionHashLabelKey] = "foo-v1"
+				}),
+				newPod(2, nil),
+			},
+			expected: 1,
+		},
+		"deletion-cost": {
+			policies: []appsv1alpha1.ScaleInPolicyType{appsv1alpha1.DeletionCostScaleInPolicyType},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, func(pod *corev1.Pod) {
+					pod.Annotations[PodDeletionCostAnnotationKey] = "10"
+				}),
+				newPod(1, nil),
+				newPod(2, func(pod *corev1.Pod) {
+					pod.Annotations[PodDeletionCostAnnotationKey] = "-10"
+				}),
+			},
+			expected: 2,
+		},
+		"spread-by-node": {
+			policies: []appsv1alpha1.ScaleInPolicyType{appsv1alpha1.SpreadByNodeScaleInPolicyType},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, func(pod *corev1.Pod) {
+					pod.Spec.NodeName = "node-b"
+				}),
+				newPod(1, nil),
+				newPod(2, nil),
+			},
+			expected: 1,
+		},
+		"spread-by-zone": {
+			policies: []appsv1alpha1.ScaleInPolicyType{appsv1alpha1.SpreadByZoneScaleInPolicyType},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, func(pod *corev1.Pod) {
+					pod.Spec.NodeName = "node-c"
+				}),
+				newPod(1, func(pod *corev1.Pod) {
+					pod.Spec.NodeName = "node-b"
+				}),
+				newPod(2, func(pod *corev1.Pod) {
+					pod.Spec.NodeName = "node-c"
+				}),
+				newPod(3, nil),
+				newPod(4, nil),
+			},
+			expected: 1,
+		},
+		"policies-in-order": {
+			policies: []appsv1alpha1.ScaleInPolicyType{
+				appsv1alpha1.NotReadyScaleInPolicyType,
+				appsv1alpha1.HighestInstanceIDScaleInPolicyType,
+			},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, func(pod *corev1.Pod) {
+					pod.Status.Conditions[0].Status = corev1.ConditionFalse
+				}),
+				newPod(1, func(pod *corev1.Pod) {
+					pod.Status.Conditions[0].Status = corev1.ConditionFalse
+				}),
+				newPod(2, nil),
+			},
+			expected: 1,
+		},
+		"terminating-first": {
+			policies: []appsv1alpha1.ScaleInPolicyType{appsv1alpha1.HighestInstanceIDScaleInPolicyType},
+			pods: []*collasetutils.PodWrapper{
+				newPod(0, func(pod *corev1.Pod) {
+					pod.DeletionTimestamp = &metav1.Time{}
+				}),
+				newPod(1, nil),
+				newPod(2, nil),
+			},
+			expected: 0,
+		},
+	}
+
+	for name, tc := range testCases {
+		sorter := newPodsToDeleteSorter(tc.pods, tc.policies, revisions)
+		if sorter.zoneCounts != nil {
+			sorter.countZones(nodeZones)
+		}
+		sort.Sort(sorter)
+
+		if tc.pods[0].ID != tc.expected {
+			t.Fatalf("case %s: expected Pod with ID %d to be scaled in first, got %d", name, tc.expected, tc.pods[0].ID)
+		}
+	}
+}
+
+func TestGetPodsToDelete(t *testing.T) {
+	newPod := func(id int, ready bool) *collasetutils.PodWrapper {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("foo-%d", id),
+				Labels: map[string]string{},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "node-a",
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+			},
+		}
+		if ready {
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		}
+		return &collasetutils.PodWrapper{Pod: pod, ID: id}
+	}
+
+	newPods := func() []*collasetutils.PodWrapper {
+		scalingIn := newPod(2, true)
+		scalingIn.Labels[fmt.Sprintf("%s/%s", appsv1alpha1.PodOperatingLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())] = "true"
+		scalingIn.Labels[fmt.Sprintf("%s/%s", appsv1alpha1.PodOperationTypeLabelPrefix, collasetutils.ScaleInOpsLifecycleAdapter.GetID())] = string(collasetutils.ScaleInOpsLifecycleAdapter.GetType())
+		terminating := newPod(3, true)
+		terminating.DeletionTimestamp = &metav1.Time{}
+
+		return []*collasetutils.PodWrapper{newPod(0, true), newPod(1, false), scalingIn, terminating}
+	}
+
+	testCases := map[string]struct {
+		idToRelease []int
+		diff        int
+		expected    []int
+	}{
+		// the victims are taken from the front of the scale-in order: terminating Pods, Pods during scaling in,
+		// Pods holding IDs to release, and then the least valuable ones by ComparePod
+		"partial": {
+			diff:     3,
+			expected: []int{3, 2, 1},
+		},
+		"exceeded": {
+			diff:     10,
+			expected: []int{3, 2, 1, 0},
+		},
+		"id-to-release": {
+			idToRelease: []int{0},
+			diff:        3,
+			expected:    []int{3, 2, 0},
+		},
+	}
+
+	sc := &RealSyncControl{}
+	for name, tc := range testCases {
+		cls := &appsv1alpha1.CollaSet{}
+		cls.Spec.ScaleStrategy.IDToRelease = tc.idToRelease
+		selected, err := sc.getPodsToDelete(cls, newPods(), nil, tc.diff)
+		if err != nil {
+			t.Fatalf("case %s: unexpected error %s", name, err)
+		}
+
+		var ids []int
+		for _, pod := range selected {
+			ids = append(ids, pod.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tc.expected) {
+			t.Fatalf("case %s: expected Pods with IDs %v to be scaled in, got %v", name, tc.expected, ids)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/retry"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
+	commonutils "kusionstack.io/operating/pkg/utils"
+)
+
+// getMaxSurge returns how many Pods are allowed to be created above replicas during updating.
+func getMaxSurge(cls *appsv1alpha1.CollaSet) int {
+	if cls.Spec.UpdateStrategy.RollingUpdate == nil || cls.Spec.UpdateStrategy.RollingUpdate.MaxSurge == nil {
+		return 0
+	}
+
+	maxSurge, err := intstr.GetScaledValueFromIntOrPercent(cls.Spec.UpdateStrategy.RollingUpdate.MaxSurge, int(realValue(cls.Spec.Replicas)), true)
+	if err != nil || maxSurge < 0 {
+		return 0
+	}
+
+	return maxSurge
+}
+
+// countPodToReplace counts the Pods with old revision which are going to be replaced by the Pods surged above
+// replicas. The candidates updated in-place are not counted, since no Pod is surged for them. With policy InPlaceOnly,
+// the candidates not able to be updated in-place are not counted either, since they are kept in current revision.
+// The candidates are decided by the batch status in progress, the same as updating.
+func countPodToReplace(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper, revisions []*appsv1.ControllerRevision, updatedRevision *appsv1.ControllerRevision, batchStatus *appsv1alpha1.CollaSetUpdateBatchStatus) (int, error) {
+	podToUpdate := decidePodToUpdate(cls, attachPodUpdateInfo(podWrappers, revisions, updatedRevision), batchStatus)
+	updater := newPodUpdater(cls, nil)
+	if inPlaceOnlyUpdater, ok := updater.(*InPlaceOnlyPodUpdater); ok {
+		var err error
+		if podToUpdate, _, err = inPlaceOnlyUpdater.decidePodNotInPlaceUpdatable(cls, updatedRevision, podToUpdate); err != nil {
+			return 0, err
+		}
+	}
+
+	podToReplace, _, err := decidePodToReplace(cls, updater, updatedRevision, podToUpdate)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(podToReplace), nil
+}
+
+// decidePodToReplace picks out the Pods which are not able to be updated in-place from the update candidates.
+// They are going to be replaced by new Pods surged above replicas instead of being recreated, so that the capacity
+// is not dropped. The rest candidates are still updated in the original way.
+func decidePodToReplace(cls *appsv1alpha1.CollaSet, updater PodUpdater, updatedRevision *appsv1.ControllerRevision, podToUpdate []*PodUpdateInfo) (podToReplace, podToUpdateRest []*PodUpdateInfo, err error) {
+	for _, podInfo := range podToUpdate {
+		// Pods which have begun updating are kept updating
+		if podInfo.IsUpdatedRevision || podInfo.DeletionTimestamp != nil || podInfo.isDuringOps {
+			podToUpdateRest = append(podToUpdateRest, podInfo)
+			continue
+		}
+
+		inPlaceSupport, onlyMetadataChanged, _, err := updater.AnalyseAndGetUpdatedPod(cls, updatedRevision, podInfo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fail to analyse pod %s/%s in-place update support: %s", podInfo.Namespace, podInfo.Name, err)
+		}
+
+		if inPlaceSupport || onlyMetadataChanged {
+			podToUpdateRest = append(podToUpdateRest, podInfo)
+			continue
+		}
+
+		podToReplace = append(podToReplace, podInfo)
+	}
+
+	return podToReplace, podToUpdateRest, nil
+}
+
+// replaceBySurge creates Pods with updated revision above replicas no more than maxSurge first,
+// and then scales in the indicated old Pods through PodOpsLifecycle, once the new Pods are service available.
+func (sc *RealSyncControl) replaceBySurge(cls *appsv1alpha1.CollaSet, podToReplace []*PodUpdateInfo, podUpdateInfos []*PodUpdateInfo, revisions []*appsv1.ControllerRevision, updatedRevision *appsv1.ControllerRevision, ownedIDs map[int]*appsv1alpha1.ContextDetail, newStatus *appsv1alpha1.CollaSetStatus) (bool, time.Duration, error) {
+	logger := sc.logger.WithValues("collaset", commonutils.ObjectKeyString(cls))
+	var recordedRequeueAfter time.Duration
+
+	activeCount := 0
+	unavailableUpdatedCount := 0
+	usedIDs := map[int]struct{}{}
+	podWrappers := make([]*collasetutils.PodWrapper, 0, len(podUpdateInfos))
+	for _, podInfo := range podUpdateInfos {
+		podWrappers = append(podWrappers, podInfo.PodWrapper)
+		if podInfo.ID >= 0 {
+			usedIDs[podInfo.ID] = struct{}{}
+		}
+
+		if podInfo.DeletionTimestamp != nil {
+			continue
+		}
+
+		activeCount++
+		if available, _ := IsPodAvailable(cls, podInfo.Pod); podInfo.IsUpdatedRevision && !available {
+			unavailableUpdatedCount++
+		}
+	}
+
+	// the pair of Pods in replacing is counted as one
+	activeCount -= len(collectReplacePairs(podWrappers))
+
+	// Pods already scaling in have been matched with the surged ones
+	var pendingPods, replacingPods []*collasetutils.PodWrapper
+	for _, podInfo := range podToReplace {
+		if podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podInfo) {
+			replacingPods = append(replacingPods, podInfo.PodWrapper)
+		} else {
+			pendingPods = append(pendingPods, podInfo.PodWrapper)
+		}
+	}
+
+	surged := activeCount - int(realValue(cls.Spec.Replicas))
+	if surged < 0 {
+		surged = 0
+	}
+
+	// 1. surge new Pods with updated revision for the pending Pods which have no replacement
+	surging := false
+	toCreate := integerMin(getMaxSurge(cls)-surged, len(pendingPods)-(surged-len(replacingPods)))
+	if toCreate > 0 && !cls.Spec.Paused {
+		availableContexts, err := sc.allocateSurgeContexts(cls, toCreate, usedIDs, updatedRevision, ownedIDs)
+		if err != nil {
+			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetUpdate, err, "UpdateFailed", err.Error())
+			return false, recordedRequeueAfter, err
+		}
+
+		succCount, requeueAfter, err := sc.scaleOut(cls, podWrappers, availableContexts, revisions, updatedRevision)
+		if requeueAfter > 0 && (recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter) {
+			recordedRequeueAfter = requeueAfter
+		}
+		if succCount > 0 {
+			surging = true
+			sc.recorder.Eventf(cls, corev1.EventTypeNormal, "SurgePod", "surge %d Pod(s) with revision %s", succCount, updatedRevision.Name)
+		}
+		if err != nil {
+			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetUpdate, err, "UpdateFailed", fmt.Sprintf("fail to surge Pods for updating: %s", err))
+			return surging, recordedRequeueAfter, err
+		}
+	}
+
+	// 2. scale in old Pods whose replacements are service available
+	toScaleIn := integerMin(len(pendingPods), surged-len(replacingPods)-unavailableUpdatedCount)
+	if toScaleIn < 0 {
+		toScaleIn = 0
+	}
+	if err := sc.sortPodsToDelete(cls, pendingPods, revisions); err != nil {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetUpdate, err, "UpdateFailed", err.Error())
+		return surging, recordedRequeueAfter, err
+	}
+	podsToScaleIn := pendingPods[:toScaleIn]
+	if isSpreadByTopology(cls) {
+		podsToScaleIn = selectPodsToDeleteByTopology(cls, pendingPods, podWrappers, toScaleIn)
+	}
+	podsToScaleIn = append(replacingPods, podsToScaleIn...)
+	if len(podsToScaleIn) == 0 {
+		return surging, recordedRequeueAfter, nil
+	}
+
+	logger.V(1).Info("try to scale in Pods replaced by surged ones", "count", len(podsToScaleIn))
+	scaling, requeueAfter, err := sc.scaleIn(cls, podsToScaleIn, ownedIDs, newStatus)
+	return surging || scaling, requeueAfter, err
+}
+
+// allocateSurgeContexts returns the contexts of IDs not in use for the surged Pods, and marks them with updated revision.
+// IDs are allocated from ResourceContext if the owned ones are not enough.
+func (sc *RealSyncControl) allocateSurgeContexts(cls *appsv1alpha1.CollaSet, count int, usedIDs map[int]struct{}, updatedRevision *appsv1.ControllerRevision, ownedIDs map[int]*appsv1alpha1.ContextDetail) ([]*appsv1alpha1.ContextDetail, error) {
+	availableContexts := extractAvailableContexts(count, ownedIDs, usedIDs)
+	if lack := count - len(availableContexts); lack > 0 {
+		var allocatedIDs map[int]*appsv1alpha1.ContextDetail
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() (err error) {
+			allocatedIDs, err = podcontext.AllocateID(sc.client, cls, updatedRevision.Name, len(ownedIDs)+lack)
+			return err
+		}); err != nil && !podcontext.IsIDShortage(err) {
+			// Pods are surged with the IDs allocated, if there are not enough IDs allowed
+			return nil, fmt.Errorf("fail to allocate %d IDs using context for surging: %s", lack, err)
+		}
+
+		for id, contextDetail := range allocatedIDs {
+			if _, exist := ownedIDs[id]; !exist {
+				ownedIDs[id] = contextDetail
+			}
+		}
+		availableContexts = extractAvailableContexts(count, ownedIDs, usedIDs)
+	}
+
+	// surged Pods should be created with updated revision
+	needUpdateContext := false
+	for _, contextDetail := range availableContexts {
+		if !contextDetail.Contains(podcontext.RevisionContextDataKey, updatedRevision.Name) {
+			needUpdateContext = true
+			contextDetail.Put(podcontext.RevisionContextDataKey, updatedRevision.Name)
+		}
+	}
+
+	if needUpdateContext {
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			return podcontext.UpdateToPodContext(sc.client, cls, ownedIDs)
+		}); err != nil {
+			return nil, fmt.Errorf("fail to update ResourceContext for surging: %s", err)
+		}
+	}
+
+	return availableContexts, nil
+}
+
+func integerMin(l, r int) int {
+	if l < r {
+		return l
+	}
+
+	return r
+}
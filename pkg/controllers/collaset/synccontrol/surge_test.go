@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func TestCountPodToReplace(t *testing.T) {
+	newRevision := func(name, container string) *appsv1.ControllerRevision {
+		return &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Data: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"labels":{"app":"foo"}},"spec":{"containers":[%s]},"$patch":"replace"}}}`, container)),
+			},
+		}
+	}
+
+	maxSurge := intstr.FromInt(1)
+	cls := &appsv1alpha1.CollaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "foo",
+		},
+		Spec: appsv1alpha1.CollaSetSpec{
+			UpdateStrategy: appsv1alpha1.UpdateStrategy{
+				RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+					MaxSurge: &maxSurge,
+				},
+			},
+		},
+	}
+
+	var pods []*collasetutils.PodWrapper
+	for i := 0; i < 3; i++ {
+		pods = append(pods, &collasetutils.PodWrapper{
+			Pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      fmt.Sprintf("foo-%d", i),
+					Labels: map[string]string{
+						appsv1.ControllerRevisionHashLabelKey: "foo-v1",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "foo", Image: "nginx:v1"}},
+				},
+			},
+			ID: i,
+		})
+	}
+
+	currentRevision := newRevision("foo-v1", `{"name":"foo","image":"nginx:v1"}`)
+	testCases := map[string]struct {
+		podUpdatePolicy appsv1alpha1.PodUpdateStrategyType
+		updatedRevision *appsv1.ControllerRevision
+		expected        int
+	}{
+		// no Pod is surged for the ones updated in-place
+		"in-place-update": {
+			updatedRevision: newRevision("foo-v2", `{"name":"foo","image":"nginx:v2"}`),
+			expected:        0,
+		},
+		"recreate-update": {
+			updatedRevision: newRevision("foo-v2", `{"name":"foo","image":"nginx:v1"},{"name":"bar","image":"nginx:v1"}`),
+			expected:        3,
+		},
+		// the Pods not able to be updated in-place are kept with policy InPlaceOnly, instead of failing scaling in
+		"in-place-only-recreate-update": {
+			podUpdatePolicy: appsv1alpha1.CollaSetInPlaceOnlyPodUpdateStrategyType,
+			updatedRevision: newRevision("foo-v2", `{"name":"foo","image":"nginx:v1"},{"name":"bar","image":"nginx:v1"}`),
+			expected:        0,
+		},
+		"in-place-only-in-place-update": {
+			podUpdatePolicy: appsv1alpha1.CollaSetInPlaceOnlyPodUpdateStrategyType,
+			updatedRevision: newRevision("foo-v2", `{"name":"foo","image":"nginx:v2"}`),
+			expected:        0,
+		},
+	}
+
+	for name, tc := range testCases {
+		cls.Spec.UpdateStrategy.PodUpdatePolicy = tc.podUpdatePolicy
+		count, err := countPodToReplace(cls, pods, []*appsv1.ControllerRevision{currentRevision, tc.updatedRevision}, tc.updatedRevision, nil)
+		if err != nil {
+			t.Fatalf("case %s: unexpected error %s", name, err)
+		}
+		if count != tc.expected {
+			t.Fatalf("case %s: expected %d Pods to replace, got %d", name, tc.expected, count)
+		}
+	}
+}
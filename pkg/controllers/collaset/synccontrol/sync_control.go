@@ -18,6 +18,7 @@ package synccontrol
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -32,6 +33,7 @@ import (
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
 	"kusionstack.io/operating/pkg/controllers/collaset/podcontrol"
+	"kusionstack.io/operating/pkg/controllers/collaset/pvccontrol"
 	"kusionstack.io/operating/pkg/controllers/collaset/utils"
 	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
 	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
@@ -50,11 +52,12 @@ type Interface interface {
 	Update(instance *appsv1alpha1.CollaSet, filteredPods []*collasetutils.PodWrapper, revisions []*appsv1.ControllerRevision, updatedRevision *appsv1.ControllerRevision, ownedIDs map[int]*appsv1alpha1.ContextDetail, newStatus *appsv1alpha1.CollaSetStatus) (bool, time.Duration, error)
 }
 
-func NewRealSyncControl(client client.Client, logger logr.Logger, podControl podcontrol.Interface, recorder record.EventRecorder) *RealSyncControl {
+func NewRealSyncControl(client client.Client, logger logr.Logger, podControl podcontrol.Interface, pvcControl pvccontrol.Interface, recorder record.EventRecorder) *RealSyncControl {
 	return &RealSyncControl{
 		client:     client,
 		logger:     logger,
 		podControl: podControl,
+		pvcControl: pvcControl,
 		recorder:   recorder,
 	}
 }
@@ -63,38 +66,77 @@ type RealSyncControl struct {
 	client     client.Client
 	logger     logr.Logger
 	podControl podcontrol.Interface
+	pvcControl pvccontrol.Interface
 	recorder   record.EventRecorder
 }
 
 // SyncPods is used to reclaim Pod instance ID
-func (sc *RealSyncControl) SyncPods(instance *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, _ *appsv1alpha1.CollaSetStatus) (bool, []*collasetutils.PodWrapper, map[int]*appsv1alpha1.ContextDetail, error) {
+func (sc *RealSyncControl) SyncPods(instance *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, newStatus *appsv1alpha1.CollaSetStatus) (bool, []*collasetutils.PodWrapper, map[int]*appsv1alpha1.ContextDetail, error) {
 	logger := sc.logger.WithValues("collaset", commonutils.ObjectKeyString(instance))
 	filteredPods, err := sc.podControl.GetFilteredPods(instance.Spec.Selector, instance)
 	if err != nil {
 		return false, nil, nil, fmt.Errorf("fail to get filtered Pods: %s", err)
 	}
 
+	// release Pods indicated to exclude and adopt Pods indicated to include
+	filteredPods, releasedIDs, err := sc.dealIncludeExcludePods(instance, filteredPods)
+	if err != nil {
+		return false, nil, nil, fmt.Errorf("fail to deal with Pods to include or exclude: %s", err)
+	}
+
+	// adopt IDs indicated to adopt, once they are released by other CollaSets
+	if err := sc.dealIDToAdopt(instance, updatedRevision.Name); err != nil {
+		return false, nil, nil, fmt.Errorf("fail to deal with IDs to adopt: %s", err)
+	}
+
 	// get owned IDs
 	var ownedIDs map[int]*appsv1alpha1.ContextDetail
 	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		ownedIDs, err = podcontext.AllocateID(sc.client, instance, updatedRevision.Name, int(realValue(instance.Spec.Replicas)))
 		return err
 	}); err != nil {
-		return false, nil, ownedIDs, fmt.Errorf("fail to allocate %d IDs using context when sync Pods: %s", instance.Spec.Replicas, err)
+		if !podcontext.IsIDShortage(err) {
+			return false, nil, ownedIDs, fmt.Errorf("fail to allocate %d IDs using context when sync Pods: %s", realValue(instance.Spec.Replicas), err)
+		}
+
+		// continue to sync with the IDs allocated, and the rest Pods are not created until more IDs are allowed
+		sc.recorder.Event(instance, corev1.EventTypeWarning, "IDShortage", err.Error())
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetIDAllocation, err, "IDShortage", err.Error())
+	} else {
+		collasetutils.RemoveCondition(newStatus, appsv1alpha1.CollaSetIDAllocation)
+	}
+
+	// get PVCs provisioned from VolumeClaimTemplates, and keep their owner references matching retention policy
+	pvcs, err := sc.pvcControl.GetFilteredPvcs(instance, ownedIDs)
+	if err != nil {
+		return false, nil, ownedIDs, fmt.Errorf("fail to get filtered PVCs: %s", err)
+	}
+
+	if err := sc.pvcControl.SyncPvcOwnerRefs(instance, pvcs); err != nil {
+		return false, nil, ownedIDs, fmt.Errorf("fail to sync owner references of PVCs: %s", err)
+	}
+
+	pvcsOfID := map[int][]*corev1.PersistentVolumeClaim{}
+	for _, pvc := range pvcs {
+		if _, id, owned := pvccontrol.ParsePvcName(instance, pvc, ownedIDs); owned {
+			pvcsOfID[id] = append(pvcsOfID[id], pvc)
+		}
 	}
 
 	// wrap Pod with more information
 	var podWrappers []*collasetutils.PodWrapper
 
-	// stateless case
+	stateful := pvccontrol.IsStateful(instance)
+	idToRelease := sets.NewInt(instance.Spec.ScaleStrategy.IDToRelease...)
 	currentIDs := sets.Int{}
 	idToReclaim := sets.Int{}
 	for i := range filteredPods {
 		pod := filteredPods[i]
 		id, _ := collasetutils.GetPodInstanceID(pod)
-		if pod.DeletionTimestamp != nil {
+		if pod.DeletionTimestamp != nil && !stateful {
+			// stateless case
 			// 1. Reclaim ID from Pod which is scaling in and terminating.
-			if contextDetail, exist := ownedIDs[id]; exist && contextDetail.Contains(ScaleInContextDataKey, "true") {
+			if contextDetail, exist := ownedIDs[id]; exist && contextDetail.Contains(ScaleInContextDataKey, "true") && !idToRelease.Has(id) {
 				idToReclaim.Insert(id)
 			}
 
@@ -102,6 +144,10 @@ func (sc *RealSyncControl) SyncPods(instance *appsv1alpha1.CollaSet, updatedRevi
 			continue
 		}
 
+		// stateful case
+		// Terminating Pods are kept, so that their IDs will not be reclaimed or reused by new Pods until they have been
+		// deleted from ETCD. Otherwise, the new Pod with the same ID may compete for the PVCs with the terminating one.
+
 		podWrappers = append(podWrappers, &collasetutils.PodWrapper{
 			Pod:           pod,
 			ID:            id,
@@ -113,23 +159,54 @@ func (sc *RealSyncControl) SyncPods(instance *appsv1alpha1.CollaSet, updatedRevi
 		}
 	}
 
-	// 3. Reclaim Pod ID which Pod & PVC are all non-existing
+	// 3. Reclaim Pod ID which Pod is non-existing
 	for id, contextDetail := range ownedIDs {
-		if contextDetail.Contains(ScaleInContextDataKey, "true") && !currentIDs.Has(id) {
+		if contextDetail.Contains(ScaleInContextDataKey, "true") && !currentIDs.Has(id) && !idToRelease.Has(id) {
+			// PVCs which are not retained should be deleted before reclaiming the ID,
+			// otherwise the Pod with this ID scaled out later may attach them again.
+			if pvcs := pvcsOfID[id]; len(pvcs) > 0 {
+				if !pvccontrol.RetainPvcsWhenScaled(instance) {
+					logger.V(1).Info("try to delete PVCs of Pod scaled in", "id", id)
+					if err := sc.pvcControl.DeletePvcs(instance, pvcs); err != nil {
+						return false, nil, ownedIDs, fmt.Errorf("fail to delete PVCs of Pod with ID %d: %s", id, err)
+					}
+					continue
+				}
+			}
+
 			idToReclaim.Insert(id)
 		}
 	}
 
-	needUpdateContext := false
+	// 4. Reclaim Pod ID which Pod is excluded
+	for _, id := range releasedIDs {
+		if _, exist := ownedIDs[id]; exist && !currentIDs.Has(id) {
+			idToReclaim.Insert(id)
+		}
+	}
+
+	// 5. Release Pod ID indicated to release to other CollaSets, which Pod is non-existing
+	idReleased := sets.Int{}
+	for _, id := range idToRelease.List() {
+		contextDetail, exist := ownedIDs[id]
+		if !exist || currentIDs.Has(id) || idToReclaim.Has(id) {
+			continue
+		}
+
+		if err := sc.pvcControl.ReleasePvcs(instance, pvcsOfID[id]); err != nil {
+			return false, nil, ownedIDs, fmt.Errorf("fail to release PVCs of Pod with ID %d: %s", id, err)
+		}
+
+		releaseContextDetail(instance, contextDetail)
+		idReleased.Insert(id)
+	}
+
+	needUpdateContext := idReleased.Len() > 0
 	for _, id := range idToReclaim.List() {
 		needUpdateContext = true
 		delete(ownedIDs, id)
 	}
 
-	// TODO stateful case
-	// 1. only reclaim non-existing Pods' ID. Do not reclaim terminating Pods' ID until these Pods and PVC have been deleted from ETCD
-	// 2. do not filter out these terminating Pods
-
 	if needUpdateContext {
 		logger.V(1).Info("try to update ResourceContext for CollaSet when sync")
 		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -139,210 +216,335 @@ func (sc *RealSyncControl) SyncPods(instance *appsv1alpha1.CollaSet, updatedRevi
 		}
 	}
 
+	// the released IDs are kept in ResourceContext without owner, until adopted by other CollaSets
+	for _, id := range idReleased.List() {
+		delete(ownedIDs, id)
+	}
+
+	// clear the entries of IDs no longer owned
+	handledRelease := sets.Int{}
+	for _, id := range idToRelease.List() {
+		if _, exist := ownedIDs[id]; !exist {
+			handledRelease.Insert(id)
+		}
+	}
+	if handledRelease.Len() > 0 {
+		if idReleased.Len() > 0 {
+			sc.recorder.Eventf(instance, corev1.EventTypeNormal, "IDReleased", "succeed to release IDs %v", idReleased.List())
+		}
+
+		// the replicas is decreased along with the IDs released, whose Pods have been scaled in
+		if err := sc.updateMigratedIDs(instance, -idReleased.Len(), handledRelease, nil); err != nil {
+			return false, nil, ownedIDs, err
+		}
+	}
+
+	// allocate IDs to the Pods without ID, like the ones included
+	if err := sc.allocateIDForPods(instance, podWrappers, ownedIDs); err != nil {
+		return false, podWrappers, ownedIDs, fmt.Errorf("fail to allocate IDs for Pods: %s", err)
+	}
+
 	return false, podWrappers, ownedIDs, nil
 }
 
 func (sc *RealSyncControl) Scale(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper, revisions []*appsv1.ControllerRevision, updatedRevision *appsv1.ControllerRevision, ownedIDs map[int]*appsv1alpha1.ContextDetail, newStatus *appsv1alpha1.CollaSetStatus) (bool, time.Duration, error) {
-	logger := sc.logger.WithValues("collaset", commonutils.ObjectKeyString(cls))
-	var recordedRequeueAfter time.Duration
-
-	diff := int(realValue(cls.Spec.Replicas)) - len(podWrappers)
-	scaling := false
-
-	if diff > 0 {
-		// collect instance ID in used from owned Pods
-		podInstanceIDSet := collasetutils.CollectPodInstanceID(podWrappers)
-		// find IDs and their contexts which have not been used by owned Pods
-		availableContext := extractAvailableContexts(diff, ownedIDs, podInstanceIDSet)
+	// scale in the Pods indicated by PodToDelete first, and wait for them to be allowed to scale in
+	scaling, pending, recordedRequeueAfter, err := sc.dealPodToDelete(cls, podWrappers)
+	if err != nil {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", err.Error())
+		return scaling, recordedRequeueAfter, err
+	} else if pending {
+		return scaling, recordedRequeueAfter, nil
+	}
 
-		succCount, err := controllerutils.SlowStartBatch(diff, controllerutils.SlowStartInitialBatchSize, false, func(idx int, _ error) error {
-			availableIDContext := availableContext[idx]
-			// use revision recorded in Context
-			revision := updatedRevision
-			if revisionName, exist := availableIDContext.Data[podcontext.RevisionContextDataKey]; exist && revisionName != "" {
-				for i := range revisions {
-					if revisions[i].Name == revisionName {
-						revision = revisions[i]
-						break
-					}
-				}
-			}
+	// replace the Pods indicated by label with new ones on the same instance IDs
+	replacing, requeueAfter, err := sc.replacePods(cls, podWrappers, updatedRevision, ownedIDs)
+	scaling = scaling || replacing
+	if requeueAfter > 0 && (recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter) {
+		recordedRequeueAfter = requeueAfter
+	}
+	if err != nil {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ReplaceFailed", err.Error())
+		return scaling, recordedRequeueAfter, err
+	}
 
-			// scale out new Pods with updatedRevision
-			// TODO use cache
-			pod, err := collasetutils.NewPodFrom(cls, metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet")), revision)
+	// the pair of Pods in replacing is counted as one replica, and not chosen to scale in
+	replacePairs := collectReplacePairs(podWrappers)
+	scaleInCandidates := filterOutReplacePairs(podWrappers, replacePairs)
+	// the Pods holding IDs to release are scaled in, and the replicas is decreased after the IDs are released
+	diff := int(realValue(cls.Spec.Replicas)) - countPodToRelease(cls, scaleInCandidates) - (len(podWrappers) - len(replacePairs))
+	if diff < 0 {
+		// Pods surged for updating are not expected to be scaled in here, they are handled by Update.
+		if maxSurge := getMaxSurge(cls); maxSurge > 0 {
+			podToReplaceCount, err := countPodToReplace(cls, podWrappers, revisions, updatedRevision, newStatus.UpdateBatch)
 			if err != nil {
-				return fmt.Errorf("fail to new Pod from revision %s: %s", revision.Name, err)
+				collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", err.Error())
+				return scaling, recordedRequeueAfter, err
 			}
-			newPod := pod.DeepCopy()
-			// allocate new Pod a instance ID
-			newPod.Labels[appsv1alpha1.PodInstanceIDLabelKey] = fmt.Sprintf("%d", availableIDContext.ID)
 
-			logger.V(1).Info("try to create Pod with revision of collaSet", "revision", revision.Name)
-			if pod, err = sc.podControl.CreatePod(newPod); err != nil {
-				return err
+			diff += integerMin(maxSurge, podToReplaceCount)
+			if diff > 0 {
+				diff = 0
 			}
+		}
+	}
 
-			// add an expectation for this pod creation, before next reconciling
-			return collasetutils.ActiveExpectations.ExpectCreate(cls, expectations.Pod, pod.Name)
-		})
+	if diff > 0 {
+		if cls.Spec.Paused {
+			// do not scale out new Pods if CollaSet is paused
+			return scaling, recordedRequeueAfter, nil
+		}
+
+		// collect instance ID in used from owned Pods
+		podInstanceIDSet := collasetutils.CollectPodInstanceID(podWrappers)
+		// find IDs and their contexts which have not been used by owned Pods
+		availableContext := extractAvailableContexts(diff, ownedIDs, podInstanceIDSet)
 
+		succCount, requeueAfter, err := sc.scaleOut(cls, podWrappers, availableContext, revisions, updatedRevision)
+		if requeueAfter > 0 && (recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter) {
+			recordedRequeueAfter = requeueAfter
+		}
 		sc.recorder.Eventf(cls, corev1.EventTypeNormal, "ScaleOut", "scale out %d Pod(s)", succCount)
 		if err != nil {
 			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleOutFailed", err.Error())
-			return succCount > 0, recordedRequeueAfter, err
+			return scaling || succCount > 0, recordedRequeueAfter, err
 		}
 		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, nil, "ScaleOut", "")
 
-		return succCount > 0, recordedRequeueAfter, err
+		return scaling || succCount > 0, recordedRequeueAfter, err
 	} else if diff < 0 {
 		// chose the pods to scale in
-		podsToScaleIn := getPodsToDelete(podWrappers, diff*-1)
-		// filter out Pods need to trigger PodOpsLifecycle
-		podCh := make(chan *collasetutils.PodWrapper, len(podsToScaleIn))
-		for i := range podsToScaleIn {
-			if podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podsToScaleIn[i].Pod) {
-				continue
-			}
-			podCh <- podsToScaleIn[i]
+		podsToScaleIn, err := sc.getPodsToDelete(cls, scaleInCandidates, revisions, diff*-1)
+		if err != nil {
+			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", err.Error())
+			return scaling, recordedRequeueAfter, err
 		}
+		scalingIn, requeueAfter, err := sc.scaleIn(cls, podsToScaleIn, ownedIDs, newStatus)
+		return scaling || scalingIn, requeueAfter, err
+	}
 
-		// trigger Pods to enter PodOpsLifecycle
-		succCount, err := controllerutils.SlowStartBatch(len(podCh), controllerutils.SlowStartInitialBatchSize, false, func(_ int, err error) error {
-			pod := <-podCh
+	// reset ContextDetail.ScalingIn, if there are Pods had its PodOpsLifecycle reverted
+	needUpdatePodContext := false
+	for _, podWrapper := range podWrappers {
+		if !podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podWrapper) && ownedIDs[podWrapper.ID].Contains(ScaleInContextDataKey, "true") {
+			needUpdatePodContext = true
+			ownedIDs[podWrapper.ID].Remove(ScaleInContextDataKey)
+		}
+	}
 
-			// trigger PodOpsLifecycle with scaleIn OperationType
-			logger.V(1).Info("try to begin PodOpsLifecycle for scaling in Pod in CollaSet", "pod", commonutils.ObjectKeyString(pod))
-			if updated, err := podopslifecycle.Begin(sc.client, collasetutils.ScaleInOpsLifecycleAdapter, pod.Pod); err != nil {
-				return fmt.Errorf("fail to begin PodOpsLifecycle for Scaling in Pod %s/%s: %s", pod.Namespace, pod.Name, err)
-			} else if updated {
-				sc.recorder.Eventf(pod.Pod, corev1.EventTypeNormal, "BeginScaleInLifecycle", "succeed to begin PodOpsLifecycle for scaling in")
-				// add an expectation for this pod creation, before next reconciling
-				if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, pod.Name, pod.ResourceVersion); err != nil {
-					return err
+	if needUpdatePodContext {
+		sc.logger.V(1).Info("try to update ResourceContext for CollaSet after scaling", "collaset", commonutils.ObjectKeyString(cls))
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			return podcontext.UpdateToPodContext(sc.client, cls, ownedIDs)
+		}); err != nil {
+			return scaling, recordedRequeueAfter, fmt.Errorf("fail to reset ResourceContext: %s", err)
+		}
+	}
+
+	return scaling, recordedRequeueAfter, nil
+}
+
+// scaleOut creates Pods with the indicated instance ID contexts. Each Pod uses the revision recorded in its context,
+// and is assigned to the topology domain lacking the most Pods, if CollaSet is spread by topology. If CollaSet names
+// Pods by instance ID, the contexts whose Pod names are still held are skipped, and the duration to requeue is returned.
+func (sc *RealSyncControl) scaleOut(cls *appsv1alpha1.CollaSet, podWrappers []*collasetutils.PodWrapper, availableContext []*appsv1alpha1.ContextDetail, revisions []*appsv1.ControllerRevision, updatedRevision *appsv1.ControllerRevision) (int, time.Duration, error) {
+	logger := sc.logger.WithValues("collaset", commonutils.ObjectKeyString(cls))
+	availableContext, requeueAfter, err := sc.filterOutNameHeldContexts(cls, availableContext)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	availableContext = limitContextsByOrder(cls, podWrappers, availableContext)
+
+	podDomains := assignTopologyDomains(cls, podWrappers, len(availableContext))
+	succCount, err := controllerutils.SlowStartBatchWithLimit(len(availableContext), controllerutils.SlowStartInitialBatchSize, getScaleMaxConcurrency(cls), false, func(idx int, _ error) error {
+		availableIDContext := availableContext[idx]
+		// use revision recorded in Context
+		revision := updatedRevision
+		if revisionName, exist := availableIDContext.Data[podcontext.RevisionContextDataKey]; exist && revisionName != "" {
+			for i := range revisions {
+				if revisions[i].Name == revisionName {
+					revision = revisions[i]
+					break
 				}
 			}
+		}
 
-			return nil
-		})
-		scaling = succCount != 0
-
+		// scale out new Pods with updatedRevision
+		// TODO use cache
+		var domain string
+		if idx < len(podDomains) {
+			domain = podDomains[idx]
+		}
+		pod, err := collasetutils.NewPodFrom(cls, metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet")), revision, domain, availableIDContext)
 		if err != nil {
-			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", err.Error())
-			return scaling, recordedRequeueAfter, err
-		} else {
-			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, nil, "ScaleIn", "")
+			return fmt.Errorf("fail to new Pod from revision %s: %s", revision.Name, err)
 		}
+		newPod := pod.DeepCopy()
+		// allocate new Pod a instance ID
+		newPod.Labels[appsv1alpha1.PodInstanceIDLabelKey] = fmt.Sprintf("%d", availableIDContext.ID)
+		if name := GetPodNameByInstanceID(cls, availableIDContext.ID); name != "" {
+			newPod.Name = name
+			newPod.GenerateName = ""
+		}
+		applyTopologyDomain(cls, newPod, domain)
 
-		needUpdateContext := false
-		for i, podWrapper := range podsToScaleIn {
-			requeueAfter, allowed := podopslifecycle.AllowOps(collasetutils.ScaleInOpsLifecycleAdapter, realValue(cls.Spec.ScaleStrategy.OperationDelaySeconds), podWrapper.Pod)
-			if !allowed && podWrapper.DeletionTimestamp == nil {
-				sc.recorder.Eventf(podWrapper.Pod, corev1.EventTypeNormal, "PodScaleInLifecycle", "Pod is not allowed to scale in")
-				continue
-			}
+		// provision PVCs for this instance ID, or reuse the existing ones provisioned before.
+		if err := sc.pvcControl.CreatePodPvcs(cls, availableIDContext); err != nil {
+			return fmt.Errorf("fail to provision PVCs for Pod with ID %d: %s", availableIDContext.ID, err)
+		}
+		pvccontrol.AttachPodPvcs(cls, newPod, availableIDContext)
 
-			if requeueAfter > 0 {
-				sc.recorder.Eventf(podWrapper.Pod, corev1.EventTypeNormal, "PodScaleInLifecycle", "delay Pod scale in for %d seconds", requeueAfter.Seconds())
-				if recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter {
-					recordedRequeueAfter = requeueAfter
-				}
+		logger.V(1).Info("try to create Pod with revision of collaSet", "revision", revision.Name)
+		if pod, err = sc.podControl.CreatePod(newPod); err != nil {
+			return err
+		}
 
-				continue
-			}
+		// add an expectation for this pod creation, before next reconciling
+		return collasetutils.ActiveExpectations.ExpectCreate(cls, expectations.Pod, pod.Name)
+	})
 
-			// if Pod is allowed to operate or Pod has already been deleted, promte to delete Pod
-			if podWrapper.ID >= 0 && !ownedIDs[podWrapper.ID].Contains(ScaleInContextDataKey, "true") {
-				needUpdateContext = true
-				ownedIDs[podWrapper.ID].Put(ScaleInContextDataKey, "true")
-			}
+	return succCount, requeueAfter, err
+}
 
-			if podWrapper.DeletionTimestamp != nil {
-				continue
-			}
+// scaleIn makes the indicated Pods go through the PodOpsLifecycle with scaleIn OperationType,
+// and deletes the ones allowed.
+func (sc *RealSyncControl) scaleIn(cls *appsv1alpha1.CollaSet, podsToScaleIn []*collasetutils.PodWrapper, ownedIDs map[int]*appsv1alpha1.ContextDetail, newStatus *appsv1alpha1.CollaSetStatus) (bool, time.Duration, error) {
+	logger := sc.logger.WithValues("collaset", commonutils.ObjectKeyString(cls))
+	var recordedRequeueAfter time.Duration
 
-			podCh <- podsToScaleIn[i]
+	// filter out Pods need to trigger PodOpsLifecycle
+	podCh := make(chan *collasetutils.PodWrapper, len(podsToScaleIn))
+	for i := range podsToScaleIn {
+		// if CollaSet is paused, only the Pods which have begun PodOpsLifecycle will be scaled in
+		if cls.Spec.Paused || podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podsToScaleIn[i].Pod) {
+			continue
 		}
+		podCh <- podsToScaleIn[i]
+	}
 
-		// mark these Pods to scalingIn
-		if needUpdateContext {
-			logger.V(1).Info("try to update ResourceContext for CollaSet when scaling in Pod")
-			err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
-				return podcontext.UpdateToPodContext(sc.client, cls, ownedIDs)
-			})
+	// trigger Pods to enter PodOpsLifecycle
+	succCount, err := controllerutils.SlowStartBatchWithLimit(len(podCh), controllerutils.SlowStartInitialBatchSize, getScaleMaxConcurrency(cls), false, func(_ int, err error) error {
+		pod := <-podCh
 
-			if err != nil {
-				collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", fmt.Sprintf("failed to update Context for scaling in: %s", err))
-				return scaling, recordedRequeueAfter, err
-			} else {
-				collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, nil, "ScaleIn", "")
+		// trigger PodOpsLifecycle with scaleIn OperationType
+		logger.V(1).Info("try to begin PodOpsLifecycle for scaling in Pod in CollaSet", "pod", commonutils.ObjectKeyString(pod))
+		if updated, err := podopslifecycle.Begin(sc.client, collasetutils.ScaleInOpsLifecycleAdapter, pod.Pod); err != nil {
+			return fmt.Errorf("fail to begin PodOpsLifecycle for Scaling in Pod %s/%s: %s", pod.Namespace, pod.Name, err)
+		} else if updated {
+			sc.recorder.Eventf(pod.Pod, corev1.EventTypeNormal, "BeginScaleInLifecycle", "succeed to begin PodOpsLifecycle for scaling in")
+			// add an expectation for this pod creation, before next reconciling
+			if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, pod.Name, pod.ResourceVersion); err != nil {
+				return err
 			}
 		}
 
-		// do delete Pod resource
-		succCount, err = controllerutils.SlowStartBatch(len(podCh), controllerutils.SlowStartInitialBatchSize, false, func(i int, _ error) error {
-			pod := <-podCh
-			logger.V(1).Info("try to scale in Pod", "pod", commonutils.ObjectKeyString(pod))
-			if err := sc.podControl.DeletePod(pod.Pod); err != nil {
-				return fmt.Errorf("fail to delete Pod %s/%s when scaling in: %s", pod.Namespace, pod.Name, err)
-			}
+		return nil
+	})
+	scaling := succCount != 0
 
-			sc.recorder.Eventf(cls, corev1.EventTypeNormal, "PodDeleted", "succeed to scale in Pod %s/%s", pod.Namespace, pod.Name)
-			if err := collasetutils.ActiveExpectations.ExpectDelete(cls, expectations.Pod, pod.Name); err != nil {
-				return err
+	if err != nil {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", err.Error())
+		return scaling, recordedRequeueAfter, err
+	} else {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, nil, "ScaleIn", "")
+	}
+
+	needUpdateContext := false
+	for i, podWrapper := range podsToScaleIn {
+		requeueAfter, allowed := podopslifecycle.AllowOps(collasetutils.ScaleInOpsLifecycleAdapter, realValue(cls.Spec.ScaleStrategy.OperationDelaySeconds), podWrapper.Pod)
+		if !allowed && podWrapper.DeletionTimestamp == nil {
+			sc.recorder.Eventf(podWrapper.Pod, corev1.EventTypeNormal, "PodScaleInLifecycle", "Pod is not allowed to scale in")
+			continue
+		}
+
+		if requeueAfter > 0 {
+			sc.recorder.Eventf(podWrapper.Pod, corev1.EventTypeNormal, "PodScaleInLifecycle", "delay Pod scale in for %d seconds", requeueAfter.Seconds())
+			if recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter {
+				recordedRequeueAfter = requeueAfter
 			}
 
-			// TODO also need to delete PVC from PVC template here
+			continue
+		}
 
-			return nil
-		})
-		scaling := scaling || succCount > 0
+		// if Pod is allowed to operate or Pod has already been deleted, promte to delete Pod
+		if podWrapper.ID >= 0 && !ownedIDs[podWrapper.ID].Contains(ScaleInContextDataKey, "true") {
+			needUpdateContext = true
+			ownedIDs[podWrapper.ID].Put(ScaleInContextDataKey, "true")
+		}
 
-		if succCount > 0 {
-			sc.recorder.Eventf(cls, corev1.EventTypeNormal, "ScaleIn", "scale in %d Pod(s)", succCount)
+		if podWrapper.DeletionTimestamp != nil {
+			continue
 		}
+
+		podCh <- podsToScaleIn[i]
+	}
+
+	// mark these Pods to scalingIn
+	if needUpdateContext {
+		logger.V(1).Info("try to update ResourceContext for CollaSet when scaling in Pod")
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			return podcontext.UpdateToPodContext(sc.client, cls, ownedIDs)
+		})
+
 		if err != nil {
-			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", fmt.Sprintf("fail to delete Pod for scaling in: %s", err))
+			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", fmt.Sprintf("failed to update Context for scaling in: %s", err))
 			return scaling, recordedRequeueAfter, err
 		} else {
 			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, nil, "ScaleIn", "")
 		}
-
-		return scaling, recordedRequeueAfter, err
 	}
 
-	// reset ContextDetail.ScalingIn, if there are Pods had its PodOpsLifecycle reverted
-	needUpdatePodContext := false
-	for _, podWrapper := range podWrappers {
-		if !podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, podWrapper) && ownedIDs[podWrapper.ID].Contains(ScaleInContextDataKey, "true") {
-			needUpdatePodContext = true
-			ownedIDs[podWrapper.ID].Remove(ScaleInContextDataKey)
+	// do delete Pod resource
+	succCount, err = controllerutils.SlowStartBatchWithLimit(len(podCh), controllerutils.SlowStartInitialBatchSize, getScaleMaxConcurrency(cls), false, func(i int, _ error) error {
+		pod := <-podCh
+		logger.V(1).Info("try to scale in Pod", "pod", commonutils.ObjectKeyString(pod))
+		if err := sc.podControl.DeletePod(pod.Pod); err != nil {
+			return fmt.Errorf("fail to delete Pod %s/%s when scaling in: %s", pod.Namespace, pod.Name, err)
 		}
-	}
 
-	if needUpdatePodContext {
-		logger.V(1).Info("try to update ResourceContext for CollaSet after scaling")
-		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-			return podcontext.UpdateToPodContext(sc.client, cls, ownedIDs)
-		}); err != nil {
-			return scaling, recordedRequeueAfter, fmt.Errorf("fail to reset ResourceContext: %s", err)
+		sc.recorder.Eventf(cls, corev1.EventTypeNormal, "PodDeleted", "succeed to scale in Pod %s/%s", pod.Namespace, pod.Name)
+		if err := collasetutils.ActiveExpectations.ExpectDelete(cls, expectations.Pod, pod.Name); err != nil {
+			return err
 		}
+
+		// PVCs of this Pod will be deleted according to PersistentVolumeClaimRetentionPolicy when syncing,
+		// after this Pod has been deleted.
+		return nil
+	})
+	scaling = scaling || succCount > 0
+
+	if succCount > 0 {
+		sc.recorder.Eventf(cls, corev1.EventTypeNormal, "ScaleIn", "scale in %d Pod(s)", succCount)
+	}
+	if err != nil {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, err, "ScaleInFailed", fmt.Sprintf("fail to delete Pod for scaling in: %s", err))
+		return scaling, recordedRequeueAfter, err
+	} else {
+		collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetScale, nil, "ScaleIn", "")
 	}
 
-	return scaling, recordedRequeueAfter, nil
+	return scaling, recordedRequeueAfter, err
 }
 
 func extractAvailableContexts(diff int, ownedIDs map[int]*appsv1alpha1.ContextDetail, podInstanceIDSet map[int]struct{}) []*appsv1alpha1.ContextDetail {
-	availableContexts := make([]*appsv1alpha1.ContextDetail, diff)
+	var availableContexts []*appsv1alpha1.ContextDetail
 
-	idx := 0
+	// ownedIDs may be more than replicas, like the ones allocated for surging, so pick up IDs in order
+	var ids []int
 	for id := range ownedIDs {
-		if _, inUsed := podInstanceIDSet[id]; inUsed {
+		ids = append(ids, id)
+	}
+	for _, id := range sets.NewInt(ids...).List() {
+		if len(availableContexts) >= diff {
+			break
+		}
+
+		if _, inUsed := podInstanceIDSet[id]; inUsed || ownedIDs[id].Contains(ScaleInContextDataKey, "true") {
 			continue
 		}
 
-		availableContexts[idx] = ownedIDs[id]
-		idx++
+		availableContexts = append(availableContexts, ownedIDs[id])
 	}
 
 	return availableContexts
@@ -354,17 +556,88 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 	// 1. scan and analysis pods update info
 	podUpdateInfos := attachPodUpdateInfo(podWrapers, revisions, updatedRevision)
 
-	// 2. decide Pod update candidates
-	podToUpdate := decidePodToUpdate(cls, podUpdateInfos)
+	// 2. decide Pod update candidates, and the Pods in replacing are not updated unless they have begun updating
+	if isUpdatingByBatches(cls) {
+		recordedRequeueAfter = sc.progressBatches(cls, podUpdateInfos, updatedRevision, newStatus)
+	} else {
+		newStatus.UpdateBatch = nil
+	}
+
+	replacePairs := collectReplacePairs(podWrapers)
+	var podToUpdate []*PodUpdateInfo
+	for _, podInfo := range decidePodToUpdate(cls, podUpdateInfos, newStatus.UpdateBatch) {
+		if isPodInReplacing(podInfo.Pod, replacePairs) && !podopslifecycle.IsDuringOps(utils.UpdateOpsLifecycleAdapter, podInfo) {
+			continue
+		}
+		podToUpdate = append(podToUpdate, podInfo)
+	}
+	updater := newPodUpdater(cls, sc.client)
+	updating := false
+
+	// 2.0 Pods not able to be updated in-place are kept in current revision with InPlaceOnly policy
+	inPlaceUpdateUnsupported := false
+	if inPlaceOnlyUpdater, ok := updater.(*InPlaceOnlyPodUpdater); ok {
+		podToUpdateRest, reasons, err := inPlaceOnlyUpdater.decidePodNotInPlaceUpdatable(cls, updatedRevision, podToUpdate)
+		if err != nil {
+			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetUpdate, err, "UpdateFailed", err.Error())
+			return updating, recordedRequeueAfter, err
+		}
+
+		if len(reasons) > 0 {
+			var podNames []string
+			for podName := range reasons {
+				podNames = append(podNames, podName)
+			}
+			sort.Strings(podNames)
+
+			message := fmt.Sprintf("%d Pod(s) are not able to be updated in-place to revision %s, like Pod %s: %s",
+				len(podNames), updatedRevision.Name, podNames[0], reasons[podNames[0]])
+			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetInPlaceUpdateUnsupported, nil, "InPlaceUpdateUnsupported", message)
+			sc.recorder.Eventf(cls, corev1.EventTypeWarning, "InPlaceUpdateUnsupported", message)
+			inPlaceUpdateUnsupported = true
+		}
+		podToUpdate = podToUpdateRest
+	}
+	if !inPlaceUpdateUnsupported {
+		collasetutils.RemoveCondition(newStatus, appsv1alpha1.CollaSetInPlaceUpdateUnsupported)
+	}
+
+	// 2.1 limit the Pods to update one by one in order, with policy OrderedReady
+	podToUpdate = limitPodToUpdateByOrder(cls, podUpdateInfos, podToUpdate)
+
+	// 2.2 replace the candidates not able to update in-place by surging new Pods, if maxSurge is indicated
+	if getMaxSurge(cls) > 0 {
+		podToReplace, podToUpdateRest, err := decidePodToReplace(cls, updater, updatedRevision, podToUpdate)
+		if err != nil {
+			collasetutils.AddOrUpdateCondition(newStatus, appsv1alpha1.CollaSetUpdate, err, "UpdateFailed", err.Error())
+			return updating, recordedRequeueAfter, err
+		}
+
+		if len(podToReplace) > 0 {
+			surging, requeueAfter, err := sc.replaceBySurge(cls, podToReplace, podUpdateInfos, revisions, updatedRevision, ownedIDs, newStatus)
+			updating = updating || surging
+			if requeueAfter > 0 && (recordedRequeueAfter == 0 || requeueAfter < recordedRequeueAfter) {
+				recordedRequeueAfter = requeueAfter
+			}
+			if err != nil {
+				return updating, recordedRequeueAfter, err
+			}
+		}
+		podToUpdate = podToUpdateRest
+	}
+
+	// 2.3 limit the Pods to update by MaxUnavailable
+	podToUpdate = limitPodToUpdateByMaxUnavailable(cls, podUpdateInfos, podToUpdate)
 
 	// 3. prepare Pods to begin PodOpsLifecycle
 	podCh := make(chan *PodUpdateInfo, len(podToUpdate))
 	for _, podInfo := range podToUpdate {
-		if podInfo.IsUpdatedRevision {
+		if podInfo.IsUpdatedRevision || podInfo.DeletionTimestamp != nil {
 			continue
 		}
 
-		if podopslifecycle.IsDuringOps(utils.UpdateOpsLifecycleAdapter, podInfo) {
+		// if CollaSet is paused, only the Pods which have begun PodOpsLifecycle will be updated
+		if cls.Spec.Paused || podopslifecycle.IsDuringOps(utils.UpdateOpsLifecycleAdapter, podInfo) {
 			continue
 		}
 
@@ -372,9 +645,7 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 	}
 
 	// 4. begin podOpsLifecycle parallel
-	updater := newPodUpdater(cls)
-	updating := false
-	succCount, err := controllerutils.SlowStartBatch(len(podCh), controllerutils.SlowStartInitialBatchSize, false, func(_ int, err error) error {
+	succCount, err := controllerutils.SlowStartBatchWithLimit(len(podCh), controllerutils.SlowStartInitialBatchSize, getUpdateMaxConcurrency(cls), false, func(_ int, err error) error {
 		podInfo := <-podCh
 
 		logger.V(1).Info("try to begin PodOpsLifecycle for updating Pod of CollaSet", "pod", commonutils.ObjectKeyString(podInfo.Pod))
@@ -420,7 +691,7 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 			ownedIDs[podInfo.ID].Put(podcontext.RevisionContextDataKey, updatedRevision.Name)
 		}
 
-		if podInfo.IsUpdatedRevision {
+		if podInfo.IsUpdatedRevision || podInfo.DeletionTimestamp != nil {
 			continue
 		}
 
@@ -444,7 +715,7 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 	}
 
 	// 6. update Pod
-	succCount, err = controllerutils.SlowStartBatch(len(podCh), controllerutils.SlowStartInitialBatchSize, false, func(_ int, _ error) error {
+	succCount, err = controllerutils.SlowStartBatchWithLimit(len(podCh), controllerutils.SlowStartInitialBatchSize, getUpdateMaxConcurrency(cls), false, func(_ int, _ error) error {
 		podInfo := <-podCh
 
 		// analyse Pod to get update information
@@ -455,7 +726,7 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 
 		logger.V(1).Info("before pod update operation",
 			"pod", commonutils.ObjectKeyString(podInfo.Pod),
-			"revision.from", podInfo.CurrentRevision.Name,
+			"revision.from", podInfo.currentRevisionName(),
 			"revision.to", updatedRevision.Name,
 			"inPlaceUpdate", inPlaceSupport,
 			"onlyMetadataChanged", onlyMetadataChanged,
@@ -466,7 +737,7 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 				return fmt.Errorf("fail to update Pod %s/%s when updating by in-place: %s", podInfo.Namespace, podInfo.Name, err)
 			} else {
 				podInfo.Pod = updatedPod
-				sc.recorder.Eventf(podInfo.Pod, corev1.EventTypeNormal, "UpdatePod", "succeed to update Pod %s/%s to from revision %s to revision %s by in-place", podInfo.Namespace, podInfo.Name, podInfo.CurrentRevision.Name, updatedRevision.Name)
+				sc.recorder.Eventf(podInfo.Pod, corev1.EventTypeNormal, "UpdatePod", "succeed to update Pod %s/%s to from revision %s to revision %s by in-place", podInfo.Namespace, podInfo.Name, podInfo.currentRevisionName(), updatedRevision.Name)
 				if err := collasetutils.ActiveExpectations.ExpectUpdate(cls, expectations.Pod, podInfo.Name, updatedPod.ResourceVersion); err != nil {
 					return err
 				}
@@ -476,7 +747,11 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 			if err = sc.podControl.DeletePod(podInfo.Pod); err != nil {
 				return fmt.Errorf("fail to delete Pod %s/%s when updating by recreate: %s", podInfo.Namespace, podInfo.Name, err)
 			} else {
-				sc.recorder.Eventf(podInfo.Pod, corev1.EventTypeNormal, "UpdatePod", "succeed to update Pod %s/%s to from revision %s to revision %s by recreate", podInfo.Namespace, podInfo.Name, podInfo.CurrentRevision.Name, updatedRevision.Name)
+				msg := fmt.Sprintf("succeed to update Pod %s/%s to from revision %s to revision %s by recreate", podInfo.Namespace, podInfo.Name, podInfo.currentRevisionName(), updatedRevision.Name)
+				if podInfo.recreateReason != "" {
+					msg = fmt.Sprintf("%s, because %s", msg, podInfo.recreateReason)
+				}
+				sc.recorder.Event(podInfo.Pod, corev1.EventTypeNormal, "UpdatePod", msg)
 				if err := collasetutils.ActiveExpectations.ExpectDelete(cls, expectations.Pod, podInfo.Name); err != nil {
 					return err
 				}
@@ -495,7 +770,7 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 	}
 
 	// try to finish all Pods'PodOpsLifecycle if its update is finished.
-	succCount, err = controllerutils.SlowStartBatch(len(podUpdateInfos), controllerutils.SlowStartInitialBatchSize, false, func(i int, _ error) error {
+	succCount, err = controllerutils.SlowStartBatchWithLimit(len(podUpdateInfos), controllerutils.SlowStartInitialBatchSize, getUpdateMaxConcurrency(cls), false, func(i int, _ error) error {
 		podInfo := podUpdateInfos[i]
 
 		if !podInfo.isDuringOps {
@@ -529,6 +804,29 @@ func (sc *RealSyncControl) Update(cls *appsv1alpha1.CollaSet, podWrapers []*coll
 	return updating || succCount > 0, recordedRequeueAfter, err
 }
 
+// updateReplicas updates the spec of CollaSet, like replicas and the handled entries of ScaleStrategy, with the
+// changes made by mutateFn, and then continues to sync with the updated spec.
+func (sc *RealSyncControl) updateReplicas(cls *appsv1alpha1.CollaSet, mutateFn func(cls *appsv1alpha1.CollaSet)) error {
+	latest, err := collasetutils.UpdateCollaSetWithRetry(sc.client, cls, mutateFn)
+	if err != nil {
+		return err
+	}
+
+	cls.ResourceVersion = latest.ResourceVersion
+	cls.Generation = latest.Generation
+	cls.Spec = latest.Spec
+	return nil
+}
+
+// addReplicas changes the replicas of CollaSet by delta, and keeps it not less than zero.
+func addReplicas(cls *appsv1alpha1.CollaSet, delta int) {
+	replicas := int32(int(realValue(cls.Spec.Replicas)) + delta)
+	if replicas < 0 {
+		replicas = 0
+	}
+	cls.Spec.Replicas = &replicas
+}
+
 func realValue(val *int32) int32 {
 	if val == nil {
 		return 0
@@ -0,0 +1,261 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+	"kusionstack.io/operating/pkg/controllers/utils/podopslifecycle"
+)
+
+func isSpreadByTopology(cls *appsv1alpha1.CollaSet) bool {
+	return cls.Spec.Topology != nil && len(cls.Spec.Topology.Domains) > 0
+}
+
+// GetTopologyDomainReplicas returns the number of Pods expected in each topology domain, in the order of domains.
+// The domains with fixed replicas are satisfied first in order, and the rest Pods are shared by the other domains
+// according to their weights. If all the domains have fixed replicas, the rest Pods are shared equally.
+func GetTopologyDomainReplicas(cls *appsv1alpha1.CollaSet) []int {
+	if !isSpreadByTopology(cls) {
+		return nil
+	}
+
+	domains := cls.Spec.Topology.Domains
+	targets := make([]int, len(domains))
+	rest := int(realValue(cls.Spec.Replicas))
+	for i, domain := range domains {
+		if domain.Replicas == nil {
+			continue
+		}
+		targets[i] = integerMin(int(*domain.Replicas), rest)
+		if targets[i] < 0 {
+			targets[i] = 0
+		}
+		rest -= targets[i]
+	}
+
+	weights := make([]int, len(domains))
+	totalWeight := 0
+	for i, domain := range domains {
+		if domain.Replicas != nil {
+			continue
+		}
+		weights[i] = 1
+		if domain.Weight != nil {
+			weights[i] = int(*domain.Weight)
+		}
+		if weights[i] < 0 {
+			weights[i] = 0
+		}
+		totalWeight += weights[i]
+	}
+	if totalWeight == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		totalWeight = len(weights)
+	}
+
+	// share the rest Pods by weights, and the remainders go to the domains with the largest fractions
+	remainders := make([]int, len(domains))
+	shared := 0
+	for i := range domains {
+		targets[i] += rest * weights[i] / totalWeight
+		shared += rest * weights[i] / totalWeight
+		remainders[i] = rest * weights[i] % totalWeight
+	}
+
+	indexes := make([]int, len(domains))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return remainders[indexes[i]] > remainders[indexes[j]]
+	})
+	for i := 0; i < rest-shared; i++ {
+		targets[indexes[i%len(indexes)]]++
+	}
+
+	return targets
+}
+
+// GetPodTopologyDomain returns the name of topology domain assigned to the Pod.
+func GetPodTopologyDomain(pod *corev1.Pod) string {
+	if pod.Labels == nil {
+		return ""
+	}
+
+	return pod.Labels[appsv1alpha1.PodTopologyDomainLabelKey]
+}
+
+// countTopologyDomains counts the active Pods in each topology domain, in the order of domains.
+// The Pods terminating or scaling in are not counted.
+func countTopologyDomains(cls *appsv1alpha1.CollaSet, pods []*collasetutils.PodWrapper) []int {
+	indexes := map[string]int{}
+	for i, domain := range cls.Spec.Topology.Domains {
+		indexes[domain.Name] = i
+	}
+
+	counts := make([]int, len(cls.Spec.Topology.Domains))
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, pod) {
+			continue
+		}
+
+		if i, exist := indexes[GetPodTopologyDomain(pod.Pod)]; exist {
+			counts[i]++
+		}
+	}
+
+	return counts
+}
+
+// assignTopologyDomains returns the topology domains for the Pods to create, each of which is the domain lacking the
+// most Pods at that time. It returns nil if CollaSet is not spread by topology.
+func assignTopologyDomains(cls *appsv1alpha1.CollaSet, pods []*collasetutils.PodWrapper, count int) []string {
+	if !isSpreadByTopology(cls) || count <= 0 {
+		return nil
+	}
+
+	targets := GetTopologyDomainReplicas(cls)
+	counts := countTopologyDomains(cls, pods)
+	assigned := make([]string, count)
+	for n := 0; n < count; n++ {
+		chosen := 0
+		for i := range targets {
+			if targets[i]-counts[i] > targets[chosen]-counts[chosen] {
+				chosen = i
+			}
+		}
+		counts[chosen]++
+		assigned[n] = cls.Spec.Topology.Domains[chosen].Name
+	}
+
+	return assigned
+}
+
+// applyTopologyDomain assigns the Pod to the topology domain, and requires it to be scheduled to the nodes in the domain.
+func applyTopologyDomain(cls *appsv1alpha1.CollaSet, pod *corev1.Pod, domainName string) {
+	if !isSpreadByTopology(cls) || domainName == "" {
+		return
+	}
+
+	var domain *appsv1alpha1.CollaSetTopologyDomain
+	for i := range cls.Spec.Topology.Domains {
+		if cls.Spec.Topology.Domains[i].Name == domainName {
+			domain = &cls.Spec.Topology.Domains[i]
+			break
+		}
+	}
+	if domain == nil {
+		return
+	}
+
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[appsv1alpha1.PodTopologyDomainLabelKey] = domain.Name
+
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      cls.Spec.Topology.TopologyKey,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   domain.Values,
+	}
+
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	nodeSelector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+
+	// the terms are ORed, so the requirement is added to each of them
+	for i := range nodeSelector.NodeSelectorTerms {
+		nodeSelector.NodeSelectorTerms[i].MatchExpressions = append(nodeSelector.NodeSelectorTerms[i].MatchExpressions, requirement)
+	}
+}
+
+// selectPodsToDeleteByTopology selects the Pods to scale in from the sorted candidates to keep the distribution across
+// topology domains. The Pods terminating, scaling in or holding IDs to release are selected first, and then each of
+// the rest is the first candidate in the domain exceeding its expected number the most. The Pods assigned to no domain
+// are selected first.
+func selectPodsToDeleteByTopology(cls *appsv1alpha1.CollaSet, sortedPods []*collasetutils.PodWrapper, activePods []*collasetutils.PodWrapper, count int) []*collasetutils.PodWrapper {
+	targets := GetTopologyDomainReplicas(cls)
+	counts := countTopologyDomains(cls, activePods)
+	indexes := map[string]int{}
+	for i, domain := range cls.Spec.Topology.Domains {
+		indexes[domain.Name] = i
+	}
+
+	selected := make([]*collasetutils.PodWrapper, 0, count)
+	picked := make([]bool, len(sortedPods))
+	for i, pod := range sortedPods {
+		if len(selected) >= count {
+			break
+		}
+
+		if pod.DeletionTimestamp != nil || podopslifecycle.IsDuringOps(collasetutils.ScaleInOpsLifecycleAdapter, pod) || isPodToRelease(cls, pod) {
+			picked[i] = true
+			selected = append(selected, pod)
+		}
+	}
+
+	for len(selected) < count {
+		chosen := -1
+		chosenSurplus := 0
+		for i, pod := range sortedPods {
+			if picked[i] {
+				continue
+			}
+
+			domainIndex, exist := indexes[GetPodTopologyDomain(pod.Pod)]
+			if !exist {
+				chosen = i
+				break
+			}
+
+			if surplus := counts[domainIndex] - targets[domainIndex]; chosen < 0 || surplus > chosenSurplus {
+				chosen = i
+				chosenSurplus = surplus
+			}
+		}
+
+		if chosen < 0 {
+			break
+		}
+
+		picked[chosen] = true
+		selected = append(selected, sortedPods[chosen])
+		if domainIndex, exist := indexes[GetPodTopologyDomain(sortedPods[chosen].Pod)]; exist {
+			counts[domainIndex]--
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func newTopologyCollaSet(replicas int32, domains ...appsv1alpha1.CollaSetTopologyDomain) *appsv1alpha1.CollaSet {
+	return &appsv1alpha1.CollaSet{
+		Spec: appsv1alpha1.CollaSetSpec{
+			Replicas: &replicas,
+			Topology: &appsv1alpha1.CollaSetTopology{
+				TopologyKey: corev1.LabelTopologyZone,
+				Domains:     domains,
+			},
+		},
+	}
+}
+
+func newTopologyDomain(name string, replicas, weight *int32) appsv1alpha1.CollaSetTopologyDomain {
+	return appsv1alpha1.CollaSetTopologyDomain{
+		Name:     name,
+		Values:   []string{name},
+		Replicas: replicas,
+		Weight:   weight,
+	}
+}
+
+func int32Ptr(val int32) *int32 {
+	return &val
+}
+
+func TestGetTopologyDomainReplicas(t *testing.T) {
+	testCases := map[string]struct {
+		cls      *appsv1alpha1.CollaSet
+		expected []int
+	}{
+		"equally": {
+			cls:      newTopologyCollaSet(7, newTopologyDomain("a", nil, nil), newTopologyDomain("b", nil, nil), newTopologyDomain("c", nil, nil)),
+			expected: []int{3, 2, 2},
+		},
+		"by-weight": {
+			cls:      newTopologyCollaSet(6, newTopologyDomain("a", nil, int32Ptr(2)), newTopologyDomain("b", nil, int32Ptr(1))),
+			expected: []int{4, 2},
+		},
+		"fixed-first": {
+			cls:      newTopologyCollaSet(5, newTopologyDomain("a", int32Ptr(3), nil), newTopologyDomain("b", nil, nil), newTopologyDomain("c", nil, nil)),
+			expected: []int{3, 1, 1},
+		},
+		"fixed-exceeding-replicas": {
+			cls:      newTopologyCollaSet(2, newTopologyDomain("a", int32Ptr(3), nil), newTopologyDomain("b", nil, nil)),
+			expected: []int{2, 0},
+		},
+		"all-fixed": {
+			cls:      newTopologyCollaSet(4, newTopologyDomain("a", int32Ptr(1), nil), newTopologyDomain("b", int32Ptr(1), nil)),
+			expected: []int{2, 2},
+		},
+	}
+
+	for name, tc := range testCases {
+		if got := GetTopologyDomainReplicas(tc.cls); !reflect.DeepEqual(got, tc.expected) {
+			t.Fatalf("case %s: expected %v, got %v", name, tc.expected, got)
+		}
+	}
+}
+
+func TestSpreadByTopology(t *testing.T) {
+	newPod := func(name, domain string) *collasetutils.PodWrapper {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{},
+			},
+		}
+		if domain != "" {
+			pod.Labels[appsv1alpha1.PodTopologyDomainLabelKey] = domain
+		}
+		return &collasetutils.PodWrapper{Pod: pod}
+	}
+
+	cls := newTopologyCollaSet(3, newTopologyDomain("a", nil, nil), newTopologyDomain("b", nil, nil), newTopologyDomain("c", nil, nil))
+	pods := []*collasetutils.PodWrapper{
+		newPod("foo-0", "a"),
+		newPod("foo-1", "a"),
+	}
+
+	// new Pods go to the domains lacking Pods
+	if assigned := assignTopologyDomains(cls, pods, 2); !reflect.DeepEqual(assigned, []string{"b", "c"}) {
+		t.Fatalf("expected new Pods assigned to domain b and c, got %v", assigned)
+	}
+
+	// the Pods are scaled in from the domains exceeding the expected number, and the ones assigned to no domain first
+	pods = append(pods, newPod("foo-2", "b"), newPod("foo-3", "c"), newPod("foo-4", ""))
+	selected := selectPodsToDeleteByTopology(cls, pods, pods, 2)
+	if len(selected) != 2 || selected[0].Name != "foo-4" || selected[1].Name != "foo-0" {
+		t.Fatalf("expected foo-4 and foo-0 selected to scale in, got %v", selected)
+	}
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}}}},
+						},
+					},
+				},
+			},
+		},
+	}
+	applyTopologyDomain(cls, pod, "b")
+	if GetPodTopologyDomain(pod) != "b" {
+		t.Fatalf("expected Pod assigned to domain b, got %s", GetPodTopologyDomain(pod))
+	}
+	requirements := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions
+	if len(requirements) != 2 || requirements[1].Key != corev1.LabelTopologyZone || !reflect.DeepEqual(requirements[1].Values, []string{"b"}) {
+		t.Fatalf("expected node affinity of domain b injected, got %v", requirements)
+	}
+}
+
+func TestNewPodFromTopologyDomain(t *testing.T) {
+	newRevision := func(name, version string) *appsv1.ControllerRevision {
+		return &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Data: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"labels":{"app":"foo"}},"spec":{"containers":[{"name":"foo","image":"nginx:%s"}]},"$patch":"replace"},`+
+					`"topology":{"domains":[{"name":"zone-b","podTemplatePatch":{"spec":{"containers":[{"name":"foo","image":"mirror/nginx:%s"}]}}}]}}}`, version, version)),
+			},
+		}
+	}
+
+	cls := newTopologyCollaSet(2, newTopologyDomain("zone-a", nil, nil), newTopologyDomain("zone-b", nil, nil))
+	ownerRef := metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet"))
+	currentRevision := newRevision("foo-v1", "v1")
+	updatedRevision := newRevision("foo-v2", "v2")
+
+	pod, err := collasetutils.NewPodFrom(cls, ownerRef, currentRevision, "zone-a", nil)
+	if err != nil {
+		t.Fatalf("fail to new Pod: %s", err)
+	}
+	if pod.Spec.Containers[0].Image != "nginx:v1" {
+		t.Fatalf("expected image nginx:v1 without patch, got %s", pod.Spec.Containers[0].Image)
+	}
+
+	currentPod, err := collasetutils.NewPodFrom(cls, ownerRef, currentRevision, "zone-b", nil)
+	if err != nil {
+		t.Fatalf("fail to new Pod: %s", err)
+	}
+	if currentPod.Spec.Containers[0].Image != "mirror/nginx:v1" {
+		t.Fatalf("expected image mirror/nginx:v1 patched by domain, got %s", currentPod.Spec.Containers[0].Image)
+	}
+
+	// the changes are compared in the same domain, so that Pods are still able to be updated in-place
+	updatedPod, err := collasetutils.NewPodFrom(cls, ownerRef, updatedRevision, "zone-b", nil)
+	if err != nil {
+		t.Fatalf("fail to new Pod: %s", err)
+	}
+	updater := &InPlaceIfPossibleUpdater{}
+	if inPlaceUpdate, _, reason := updater.diffPod(currentPod, updatedPod); !inPlaceUpdate {
+		t.Fatalf("expected Pod in domain to be updated in-place, got reason: %s", reason)
+	}
+	if updatedPod.Spec.Containers[0].Image != "mirror/nginx:v2" {
+		t.Fatalf("expected image mirror/nginx:v2 patched by domain, got %s", updatedPod.Spec.Containers[0].Image)
+	}
+}
@@ -17,14 +17,23 @@ limitations under the License.
 package synccontrol
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 	"kusionstack.io/operating/pkg/controllers/collaset/utils"
 	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
@@ -42,6 +51,17 @@ type PodUpdateInfo struct {
 
 	// indicates the PodOpsLifecycle is started.
 	isDuringOps bool
+	// records why the Pod is not able to be updated in-place, once it is analysed
+	recreateReason string
+}
+
+// currentRevisionName returns the name of Pod current revision, or empty if it is unknown, like the Pod included.
+func (i *PodUpdateInfo) currentRevisionName() string {
+	if i.CurrentRevision == nil {
+		return ""
+	}
+
+	return i.CurrentRevision.Name
 }
 
 func attachPodUpdateInfo(pods []*collasetutils.PodWrapper, revisions []*appsv1.ControllerRevision, updatedRevision *appsv1.ControllerRevision) []*PodUpdateInfo {
@@ -77,11 +97,15 @@ func attachPodUpdateInfo(pods []*collasetutils.PodWrapper, revisions []*appsv1.C
 	return podUpdateInfoList
 }
 
-func decidePodToUpdate(cls *appsv1alpha1.CollaSet, podInfos []*PodUpdateInfo) []*PodUpdateInfo {
+func decidePodToUpdate(cls *appsv1alpha1.CollaSet, podInfos []*PodUpdateInfo, batchStatus *appsv1alpha1.CollaSetUpdateBatchStatus) []*PodUpdateInfo {
 	if cls.Spec.UpdateStrategy.RollingUpdate != nil && cls.Spec.UpdateStrategy.RollingUpdate.ByLabel != nil {
 		return decidePodToUpdateByLabel(cls, podInfos)
 	}
 
+	if isUpdatingByBatches(cls) {
+		return decidePodToUpdateByBatches(cls, podInfos, batchStatus)
+	}
+
 	return decidePodToUpdateByPartition(cls, podInfos)
 }
 
@@ -97,6 +121,7 @@ func decidePodToUpdateByLabel(_ *appsv1alpha1.CollaSet, podInfos []*PodUpdateInf
 
 func decidePodToUpdateByPartition(cls *appsv1alpha1.CollaSet, podInfos []*PodUpdateInfo) (podToUpdate []*PodUpdateInfo) {
 	if cls.Spec.UpdateStrategy.RollingUpdate == nil ||
+		cls.Spec.UpdateStrategy.RollingUpdate.ByPartition == nil ||
 		cls.Spec.UpdateStrategy.RollingUpdate.ByPartition.Partition == nil {
 		return podInfos
 	}
@@ -105,9 +130,102 @@ func decidePodToUpdateByPartition(cls *appsv1alpha1.CollaSet, podInfos []*PodUpd
 	sort.Sort(ordered)
 
 	partition := int(*cls.Spec.UpdateStrategy.RollingUpdate.ByPartition.Partition)
+	if partition > len(podInfos) {
+		partition = len(podInfos)
+	}
 	return podInfos[:partition]
 }
 
+// GetMaxUnavailable returns how many Pods are allowed to be unavailable during updating, and false if it is not limited.
+func GetMaxUnavailable(cls *appsv1alpha1.CollaSet) (int, bool) {
+	if cls.Spec.UpdateStrategy.RollingUpdate == nil || cls.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable == nil {
+		return 0, false
+	}
+
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(cls.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable, int(realValue(cls.Spec.Replicas)), false)
+	if err != nil || maxUnavailable < 0 {
+		maxUnavailable = 0
+	}
+
+	// at least one Pod is allowed to update, unless Pods are replaced by surging
+	if maxUnavailable == 0 && getMaxSurge(cls) == 0 {
+		maxUnavailable = 1
+	}
+
+	return maxUnavailable, true
+}
+
+// IsPodUnavailable indicates whether the Pod is terminating, during PodOpsLifecycle or not available.
+func IsPodUnavailable(cls *appsv1alpha1.CollaSet, pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil ||
+		podopslifecycle.IsDuringOps(utils.UpdateOpsLifecycleAdapter, pod) ||
+		podopslifecycle.IsDuringOps(utils.ScaleInOpsLifecycleAdapter, pod) {
+		return true
+	}
+
+	available, _ := IsPodAvailable(cls, pod)
+	return !available
+}
+
+// IsPodAvailable indicates whether the Pod is service available and has been ready for at least MinReadySeconds.
+// If the Pod is service available but not ready for long enough, it returns the duration to wait for.
+func IsPodAvailable(cls *appsv1alpha1.CollaSet, pod *corev1.Pod) (bool, time.Duration) {
+	if !controllerutils.IsServiceAvailable(pod) {
+		return false, 0
+	}
+
+	if cls.Spec.MinReadySeconds <= 0 {
+		return true, 0
+	}
+
+	condition := controllerutils.GetPodReadyCondition(pod.Status)
+	if condition == nil || condition.Status != corev1.ConditionTrue {
+		return false, 0
+	}
+
+	minReady := time.Duration(cls.Spec.MinReadySeconds) * time.Second
+	if elapsed := time.Since(condition.LastTransitionTime.Time); elapsed < minReady {
+		return false, minReady - elapsed
+	}
+
+	return true, 0
+}
+
+// limitPodToUpdateByMaxUnavailable filters out the candidates which are not allowed to begin updating, in case of
+// the number of unavailable Pods exceeding MaxUnavailable. Pods which have begun updating or are already unavailable
+// do not consume the budget.
+func limitPodToUpdateByMaxUnavailable(cls *appsv1alpha1.CollaSet, podInfos []*PodUpdateInfo, podToUpdate []*PodUpdateInfo) []*PodUpdateInfo {
+	maxUnavailable, limited := GetMaxUnavailable(cls)
+	if !limited {
+		return podToUpdate
+	}
+
+	unavailableCount := 0
+	for _, podInfo := range podInfos {
+		if IsPodUnavailable(cls, podInfo.Pod) {
+			unavailableCount++
+		}
+	}
+
+	budget := maxUnavailable - unavailableCount
+	var allowed []*PodUpdateInfo
+	for _, podInfo := range podToUpdate {
+		if podInfo.IsUpdatedRevision || podInfo.isDuringOps || IsPodUnavailable(cls, podInfo.Pod) {
+			allowed = append(allowed, podInfo)
+			continue
+		}
+
+		if budget <= 0 {
+			continue
+		}
+
+		budget--
+		allowed = append(allowed, podInfo)
+	}
+
+	return allowed
+}
+
 type orderByDefault []*PodUpdateInfo
 
 func (o orderByDefault) Len() int {
@@ -142,16 +260,19 @@ type PodUpdater interface {
 	GetPodUpdateFinishStatus(podUpdateInfo *PodUpdateInfo) (bool, string, error)
 }
 
-func newPodUpdater(cls *appsv1alpha1.CollaSet) PodUpdater {
+func newPodUpdater(cls *appsv1alpha1.CollaSet, reader client.Reader) PodUpdater {
+	inPlaceIfPossibleUpdater := InPlaceIfPossibleUpdater{
+		inPlaceResizeEnabled: cls.Spec.UpdateStrategy.InPlaceResizeEnabled,
+		reader:               reader,
+	}
+
 	switch cls.Spec.UpdateStrategy.PodUpdatePolicy {
 	case appsv1alpha1.CollaSetRecreatePodUpdateStrategyType:
 		return &RecreatePodUpdater{}
 	case appsv1alpha1.CollaSetInPlaceOnlyPodUpdateStrategyType:
-		// In case of using native K8s, Pod is only allowed to update with container image, so InPlaceOnly policy is
-		// implemented with InPlaceIfPossible policy as default for compatibility.
-		return &InPlaceIfPossibleUpdater{}
+		return &InPlaceOnlyPodUpdater{InPlaceIfPossibleUpdater: inPlaceIfPossibleUpdater}
 	default:
-		return &InPlaceIfPossibleUpdater{}
+		return &inPlaceIfPossibleUpdater
 	}
 }
 
@@ -160,38 +281,59 @@ type PodStatus struct {
 }
 
 type ContainerStatus struct {
-	LatestImage string `json:"latestImage,omitempty"`
-	LastImageID string `json:"lastImageID,omitempty"`
+	LatestImage     string                       `json:"latestImage,omitempty"`
+	LastImageID     string                       `json:"lastImageID,omitempty"`
+	LatestResources *corev1.ResourceRequirements `json:"latestResources,omitempty"`
 }
 
 type InPlaceIfPossibleUpdater struct {
+	// inPlaceResizeEnabled indicates the changes of container resources are updated in-place
+	inPlaceResizeEnabled bool
+	// reader is used to get the resources of containers in Pod status, to check whether they are resized
+	reader client.Reader
 }
 
 func (u *InPlaceIfPossibleUpdater) AnalyseAndGetUpdatedPod(cls *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, podUpdateInfo *PodUpdateInfo) (inPlaceUpdateSupport bool, onlyMetadataChanged bool, updatedPod *corev1.Pod, err error) {
+	inPlaceUpdateSupport, onlyMetadataChanged, podUpdateInfo.recreateReason, updatedPod, err = u.analyse(cls, updatedRevision, podUpdateInfo)
+	return
+}
+
+// analyse builds the updated Pod if it is able to be updated in-place, otherwise returns the reason why it is not.
+func (u *InPlaceIfPossibleUpdater) analyse(cls *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, podUpdateInfo *PodUpdateInfo) (inPlaceUpdateSupport bool, onlyMetadataChanged bool, reason string, updatedPod *corev1.Pod, err error) {
+	// Pod with unknown current revision is not able to be analysed, so it will be recreated.
+	if podUpdateInfo.CurrentRevision == nil {
+		return false, false, "current revision is unknown", nil, nil
+	}
+
 	// 1. build pod from current and updated revision
 	ownerRef := metav1.NewControllerRef(cls, appsv1alpha1.GroupVersion.WithKind("CollaSet"))
 	// TODO: use cache
-	currentPod, err := collasetutils.NewPodFrom(cls, ownerRef, podUpdateInfo.CurrentRevision)
+	// the Pod template patch of its topology domain is applied to both, so that the per-domain changes are compared
+	domain := GetPodTopologyDomain(podUpdateInfo.Pod)
+	currentPod, err := collasetutils.NewPodFrom(cls, ownerRef, podUpdateInfo.CurrentRevision, domain, podUpdateInfo.ContextDetail)
 	if err != nil {
-		return false, false, nil, fmt.Errorf("fail to build Pod from current revision %s: %s", podUpdateInfo.CurrentRevision.Name, err)
+		return false, false, "", nil, fmt.Errorf("fail to build Pod from current revision %s: %s", podUpdateInfo.CurrentRevision.Name, err)
 	}
 
 	// TODO: use cache
-	updatedPod, err = collasetutils.NewPodFrom(cls, ownerRef, updatedRevision)
+	updatedPod, err = collasetutils.NewPodFrom(cls, ownerRef, updatedRevision, domain, podUpdateInfo.ContextDetail)
 	if err != nil {
-		return false, false, nil, fmt.Errorf("fail to build Pod from updated revision %s: %s", updatedRevision.Name, err)
+		return false, false, "", nil, fmt.Errorf("fail to build Pod from updated revision %s: %s", updatedRevision.Name, err)
 	}
 
-	// 2. compare current and updated pods. Only pod image and metadata are supported to update in-place
+	// 2. compare current and updated pods. Only pod metadata, container image and resources are supported to update in-place
 	// TODO: use cache
-	inPlaceUpdateSupport, onlyMetadataChanged = u.diffPod(currentPod, updatedPod)
-	// 2.1 if pod has changes more than metadata and image
+	inPlaceUpdateSupport, onlyMetadataChanged, reason = u.diffPod(currentPod, updatedPod)
+	// 2.1 if pod has changes not supported to update in-place
 	if !inPlaceUpdateSupport {
-		return false, onlyMetadataChanged, nil, nil
+		return false, onlyMetadataChanged, reason, nil, nil
 	}
 
 	inPlaceUpdateSupport = true
 	updatedPod, err = controllerutils.PatchToPod(currentPod, updatedPod, podUpdateInfo.Pod)
+	if err != nil {
+		return inPlaceUpdateSupport, onlyMetadataChanged, "", nil, fmt.Errorf("fail to patch Pod with updated revision %s: %s", updatedRevision.Name, err)
+	}
 
 	if onlyMetadataChanged {
 		if updatedPod.Annotations != nil {
@@ -204,25 +346,39 @@ func (u *InPlaceIfPossibleUpdater) AnalyseAndGetUpdatedPod(cls *appsv1alpha1.Col
 			containerCurrentStatusMapping[status.Name] = &status
 		}
 
+		currentContainerMapping := map[string]*corev1.Container{}
+		for i := range podUpdateInfo.Spec.Containers {
+			currentContainerMapping[podUpdateInfo.Spec.Containers[i].Name] = &podUpdateInfo.Spec.Containers[i]
+		}
+
 		podStatus := &PodStatus{ContainerStates: map[string]*ContainerStatus{}}
 		for _, container := range updatedPod.Spec.Containers {
-			podStatus.ContainerStates[container.Name] = &ContainerStatus{
+			containerState := &ContainerStatus{}
+			currentContainer, exist := currentContainerMapping[container.Name]
+			if !exist || currentContainer.Image != container.Image {
 				// store image of each container in updated Pod
-				LatestImage: container.Image,
+				containerState.LatestImage = container.Image
+
+				// store image ID of each container in current Pod
+				if containerCurrentStatus, exist := containerCurrentStatusMapping[container.Name]; exist {
+					containerState.LastImageID = containerCurrentStatus.ImageID
+				}
 			}
 
-			containerCurrentStatus, exist := containerCurrentStatusMapping[container.Name]
-			if !exist {
-				continue
+			// container only resized is not restarted, so it waits for the resources in status instead of image ID
+			if exist && !equality.Semantic.DeepEqual(currentContainer.Resources, container.Resources) {
+				containerState.LatestResources = container.Resources.DeepCopy()
 			}
 
-			// store image ID of each container in current Pod
-			podStatus.ContainerStates[container.Name].LastImageID = containerCurrentStatus.ImageID
+			if containerState.LatestImage == "" && containerState.LatestResources == nil {
+				continue
+			}
+			podStatus.ContainerStates[container.Name] = containerState
 		}
 
 		podStatusStr, err := json.Marshal(podStatus)
 		if err != nil {
-			return inPlaceUpdateSupport, onlyMetadataChanged, updatedPod, err
+			return inPlaceUpdateSupport, onlyMetadataChanged, "", updatedPod, err
 		}
 
 		if updatedPod.Annotations == nil {
@@ -234,33 +390,100 @@ func (u *InPlaceIfPossibleUpdater) AnalyseAndGetUpdatedPod(cls *appsv1alpha1.Col
 	return
 }
 
-func (u *InPlaceIfPossibleUpdater) diffPod(currentPod, updatedPod *corev1.Pod) (inPlaceSetUpdateSupport bool, onlyMetadataChanged bool) {
+// diffPod compares the Pods built from current and updated revision. Changes of metadata and container image are
+// supported to update in-place. Changes of container resources are only supported if in-place resize is enabled,
+// since they are rejected by the Kubernetes versions without in-place Pod resize.
+// Env referring to Pod labels or annotations by downward API is resolved when container starts, so the changes of
+// these metadata are only supported to update in-place along with image changes, which will restart the container.
+func (u *InPlaceIfPossibleUpdater) diffPod(currentPod, updatedPod *corev1.Pod) (inPlaceSetUpdateSupport bool, onlyMetadataChanged bool, reason string) {
 	if len(currentPod.Spec.Containers) != len(updatedPod.Spec.Containers) {
-		return false, false
+		return false, false, "containers are added or removed"
 	}
 
+	envMetadataChanged := downwardAPIEnvMetadataChanged(currentPod, updatedPod)
+
 	currentPod = currentPod.DeepCopy()
 	// sync metadata
 	currentPod.ObjectMeta = updatedPod.ObjectMeta
 
-	// sync image
+	// sync image and resources
 	imageChanged := false
+	resourcesChanged := false
 	for i := range currentPod.Spec.Containers {
-		if currentPod.Spec.Containers[i].Image != updatedPod.Spec.Containers[i].Image {
+		currentContainer := &currentPod.Spec.Containers[i]
+		updatedContainer := &updatedPod.Spec.Containers[i]
+		if currentContainer.Name != updatedContainer.Name {
+			return false, false, fmt.Sprintf("container %s is replaced by %s", currentContainer.Name, updatedContainer.Name)
+		}
+
+		if currentContainer.Image != updatedContainer.Image {
 			imageChanged = true
-			currentPod.Spec.Containers[i].Image = updatedPod.Spec.Containers[i].Image
+			currentContainer.Image = updatedContainer.Image
+		}
+
+		if !equality.Semantic.DeepEqual(currentContainer.Resources, updatedContainer.Resources) {
+			if !u.inPlaceResizeEnabled {
+				return false, false, fmt.Sprintf("resources of container %s are changed without in-place resize enabled", currentContainer.Name)
+			}
+			resourcesChanged = true
+			currentContainer.Resources = updatedContainer.Resources
 		}
 	}
 
 	if !equality.Semantic.DeepEqual(currentPod, updatedPod) {
-		return false, false
+		return false, false, "fields other than metadata, container image and resources are changed"
 	}
 
-	if !imageChanged {
-		return true, true
+	if envMetadataChanged && !imageChanged {
+		return false, false, "labels or annotations referred by env through downward API are changed without image change to restart container"
 	}
 
-	return true, false
+	if !imageChanged && !resourcesChanged && !envMetadataChanged {
+		return true, true, ""
+	}
+
+	return true, false, ""
+}
+
+// downwardAPIEnvMetadataChanged indicates whether the labels or annotations referred by container env through
+// downward API are changed.
+func downwardAPIEnvMetadataChanged(currentPod, updatedPod *corev1.Pod) bool {
+	for _, container := range updatedPod.Spec.Containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil || env.ValueFrom.FieldRef == nil {
+				continue
+			}
+
+			var current, updated map[string]string
+			var key string
+			fieldPath := env.ValueFrom.FieldRef.FieldPath
+			switch {
+			case strings.HasPrefix(fieldPath, "metadata.labels"):
+				current, updated = currentPod.Labels, updatedPod.Labels
+				key = strings.TrimPrefix(fieldPath, "metadata.labels")
+			case strings.HasPrefix(fieldPath, "metadata.annotations"):
+				current, updated = currentPod.Annotations, updatedPod.Annotations
+				key = strings.TrimPrefix(fieldPath, "metadata.annotations")
+			default:
+				continue
+			}
+
+			// fieldPath is in format of metadata.labels['<KEY>']
+			key = strings.TrimSuffix(strings.TrimPrefix(key, "['"), "']")
+			if key == "" {
+				if !equality.Semantic.DeepEqual(current, updated) {
+					return true
+				}
+				continue
+			}
+
+			if current[key] != updated[key] {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 func (u *InPlaceIfPossibleUpdater) GetPodUpdateFinishStatus(podUpdateInfo *PodUpdateInfo) (finished bool, msg string, err error) {
@@ -301,46 +524,158 @@ func (u *InPlaceIfPossibleUpdater) GetPodUpdateFinishStatus(podUpdateInfo *PodUp
 		imageMapping[containerSpec.Name] = containerSpec.Image
 	}
 
+	resourcesMapping := map[string]corev1.ResourceRequirements{}
+	for _, containerSpec := range podUpdateInfo.Spec.Containers {
+		resourcesMapping[containerSpec.Name] = containerSpec.Resources
+	}
+
 	imageIdMapping := map[string]string{}
 	for _, containerStatus := range podUpdateInfo.Status.ContainerStatuses {
 		imageIdMapping[containerStatus.Name] = containerStatus.ImageID
 	}
 
+	var statusResourcesMapping map[string]corev1.ResourceRequirements
 	for containerName, lastContaienrState := range podLastState.ContainerStates {
-		latestImage := lastContaienrState.LatestImage
-		lastImageId := lastContaienrState.LastImageID
+		if updated, msg := isContainerImageUpdated(containerName, lastContaienrState, imageMapping, imageIdMapping); !updated {
+			return false, msg, nil
+		}
 
-		if currentImage, exist := imageMapping[containerName]; !exist {
-			// If no this container image recorded, ignore this container.
+		latestResources := lastContaienrState.LatestResources
+		if latestResources == nil || !equality.Semantic.DeepEqual(resourcesMapping[containerName], *latestResources) {
+			// If container resources are not changed, or changed again in pod spec, ignore this container.
 			continue
-		} else if currentImage != latestImage {
-			// If container image in pod spec has changed, ignore this container.
+		}
+
+		if statusResourcesMapping == nil {
+			if statusResourcesMapping, err = u.getContainerStatusResources(podUpdateInfo.Pod); err != nil {
+				return false, "", err
+			}
+		}
+
+		if statusResources, exist := statusResourcesMapping[containerName]; !exist || !equality.Semantic.DeepEqual(statusResources, *latestResources) {
+			// Resources in container status not matched means the pod in-place resize has not finished by kubelet.
+			return false, fmt.Sprintf("container %s has not been resized", containerName), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// isContainerImageUpdated indicates whether the image of container recorded in last state is updated by kubelet.
+func isContainerImageUpdated(containerName string, lastContainerState *ContainerStatus, imageMapping, imageIdMapping map[string]string) (bool, string) {
+	latestImage := lastContainerState.LatestImage
+	lastImageId := lastContainerState.LastImageID
+	if latestImage == "" {
+		// If container image is not changed, there is no image to check.
+		return true, ""
+	}
+
+	if currentImage, exist := imageMapping[containerName]; !exist {
+		// If no this container image recorded, ignore this container.
+		return true, ""
+	} else if currentImage != latestImage {
+		// If container image in pod spec has changed, ignore this container.
+		return true, ""
+	}
+
+	if currentImageId, exist := imageIdMapping[containerName]; !exist {
+		// If no this container image id recorded, ignore this container.
+		return true, ""
+	} else if currentImageId == lastImageId {
+		// No image id changed means the pod in-place update has not finished by kubelet.
+		return false, fmt.Sprintf("container has %s not been updated: last image id %s, current image id %s", containerName, lastImageId, currentImageId)
+	}
+
+	return true, ""
+}
+
+// getContainerStatusResources returns the resources of containers reported in Pod status, which are applied by kubelet
+// after resized in-place. The field is missing in the Pod type of the Kubernetes version depended on, so the Pod is
+// read as unstructured.
+func (u *InPlaceIfPossibleUpdater) getContainerStatusResources(pod *corev1.Pod) (map[string]corev1.ResourceRequirements, error) {
+	if u.reader == nil {
+		return nil, fmt.Errorf("no reader to get container resources in status of Pod %s/%s", pod.Namespace, pod.Name)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+	if err := u.reader.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, obj); err != nil {
+		return nil, fmt.Errorf("fail to get Pod %s/%s: %s", pod.Namespace, pod.Name, err)
+	}
+
+	containerStatuses, _, err := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse container status of Pod %s/%s: %s", pod.Namespace, pod.Name, err)
+	}
+
+	resourcesMapping := map[string]corev1.ResourceRequirements{}
+	for _, containerStatus := range containerStatuses {
+		status, ok := containerStatus.(map[string]interface{})
+		if !ok {
 			continue
 		}
 
-		if currentImageId, exist := imageIdMapping[containerName]; !exist {
-			// If no this container image id recorded, ignore this container.
+		name, _, _ := unstructured.NestedString(status, "name")
+		resources, exist, _ := unstructured.NestedMap(status, "resources")
+		if !exist {
 			continue
-		} else if currentImageId == lastImageId {
-			// No image id changed means the pod in-place update has not finished by kubelet.
-			return false, fmt.Sprintf("container has %s not been updated: last image id %s, current image id %s", containerName, lastImageId, currentImageId), nil
 		}
+
+		requirements := corev1.ResourceRequirements{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(resources, &requirements); err != nil {
+			return nil, fmt.Errorf("fail to parse resources in status of container %s in Pod %s/%s: %s", name, pod.Namespace, pod.Name, err)
+		}
+		resourcesMapping[name] = requirements
 	}
 
-	return true, "", nil
+	return resourcesMapping, nil
 }
 
-// TODO
+// InPlaceOnlyPodUpdater updates Pods only in-place, and never recreates them.
 type InPlaceOnlyPodUpdater struct {
+	InPlaceIfPossibleUpdater
 }
 
-func (u *InPlaceOnlyPodUpdater) AnalyseAndGetUpdatedPod(_ *appsv1alpha1.CollaSet, _ *appsv1.ControllerRevision, _ *PodUpdateInfo) (inPlaceUpdateSupport bool, onlyMetadataChanged bool, updatedPod *corev1.Pod, err error) {
+func (u *InPlaceOnlyPodUpdater) AnalyseAndGetUpdatedPod(cls *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, podUpdateInfo *PodUpdateInfo) (inPlaceUpdateSupport bool, onlyMetadataChanged bool, updatedPod *corev1.Pod, err error) {
+	inPlaceUpdateSupport, onlyMetadataChanged, reason, updatedPod, err := u.analyse(cls, updatedRevision, podUpdateInfo)
+	if err != nil {
+		return inPlaceUpdateSupport, onlyMetadataChanged, updatedPod, err
+	}
+
+	if !inPlaceUpdateSupport {
+		return false, false, nil, fmt.Errorf("Pod %s/%s is not able to be updated in-place: %s", podUpdateInfo.Namespace, podUpdateInfo.Name, reason)
+	}
 
-	return
+	return inPlaceUpdateSupport, onlyMetadataChanged, updatedPod, nil
 }
 
-func (u *InPlaceOnlyPodUpdater) GetPodUpdateFinishStatus(_ *PodUpdateInfo) (finished bool, msg string, err error) {
-	return
+// decidePodNotInPlaceUpdatable picks out the update candidates which are not able to be updated in-place,
+// so that they will not begin PodOpsLifecycle for the InPlaceOnly policy.
+// It returns the rest candidates, and the reasons of the ones picked out by Pod name.
+func (u *InPlaceOnlyPodUpdater) decidePodNotInPlaceUpdatable(cls *appsv1alpha1.CollaSet, updatedRevision *appsv1.ControllerRevision, podToUpdate []*PodUpdateInfo) ([]*PodUpdateInfo, map[string]string, error) {
+	var podToUpdateRest []*PodUpdateInfo
+	reasons := map[string]string{}
+	for _, podInfo := range podToUpdate {
+		// Pods which have begun updating are checked again before updating
+		if podInfo.IsUpdatedRevision || podInfo.DeletionTimestamp != nil || podInfo.isDuringOps {
+			podToUpdateRest = append(podToUpdateRest, podInfo)
+			continue
+		}
+
+		inPlaceUpdateSupport, _, reason, _, err := u.analyse(cls, updatedRevision, podInfo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fail to analyse pod %s/%s in-place update support: %s", podInfo.Namespace, podInfo.Name, err)
+		}
+
+		if !inPlaceUpdateSupport {
+			reasons[podInfo.Name] = reason
+			continue
+		}
+
+		podToUpdateRest = append(podToUpdateRest, podInfo)
+	}
+
+	return podToUpdateRest, reasons, nil
 }
 
 type RecreatePodUpdater struct {
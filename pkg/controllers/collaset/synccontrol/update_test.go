@@ -0,0 +1,403 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
+	"kusionstack.io/operating/pkg/controllers/collaset/pvccontrol"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
+)
+
+func TestDiffPod(t *testing.T) {
+	newPod := func(mutateFn func(pod *corev1.Pod)) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					"app": "foo",
+				},
+				Annotations: map[string]string{
+					"config": "v1",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "foo",
+						Image: "nginx:v1",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU: resource.MustParse("1"),
+							},
+						},
+						Env: []corev1.EnvVar{
+							{
+								Name: "CONFIG",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{
+										FieldPath: "metadata.annotations['config']",
+									},
+								},
+							},
+							{
+								Name: "APP",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{
+										FieldPath: "metadata.labels['app']",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		if mutateFn != nil {
+			mutateFn(pod)
+		}
+		return pod
+	}
+
+	testCases := map[string]struct {
+		updatedPod           *corev1.Pod
+		inPlaceResizeEnabled bool
+		inPlaceUpdate        bool
+		onlyMetadataChanged  bool
+	}{
+		"no-change": {
+			updatedPod:          newPod(nil),
+			inPlaceUpdate:       true,
+			onlyMetadataChanged: true,
+		},
+		"label-changed": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Labels["test"] = "v2"
+			}),
+			inPlaceUpdate:       true,
+			onlyMetadataChanged: true,
+		},
+		"image-changed": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Image = "nginx:v2"
+			}),
+			inPlaceUpdate:       true,
+			onlyMetadataChanged: false,
+		},
+		"resources-changed": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("2")
+			}),
+			inPlaceUpdate:       false,
+			onlyMetadataChanged: false,
+		},
+		"resources-changed-with-resize-enabled": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("2")
+			}),
+			inPlaceResizeEnabled: true,
+			inPlaceUpdate:        true,
+			onlyMetadataChanged:  false,
+		},
+		"env-metadata-changed": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Annotations["config"] = "v2"
+			}),
+			inPlaceUpdate:       false,
+			onlyMetadataChanged: false,
+		},
+		"env-metadata-changed-with-image": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Annotations["config"] = "v2"
+				pod.Spec.Containers[0].Image = "nginx:v2"
+			}),
+			inPlaceUpdate:       true,
+			onlyMetadataChanged: false,
+		},
+		"env-label-changed": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Labels["app"] = "bar"
+			}),
+			inPlaceUpdate:       false,
+			onlyMetadataChanged: false,
+		},
+		"env-label-changed-with-image": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Labels["app"] = "bar"
+				pod.Spec.Containers[0].Image = "nginx:v2"
+			}),
+			inPlaceUpdate:       true,
+			onlyMetadataChanged: false,
+		},
+		"command-changed": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Command = []string{"sleep"}
+			}),
+			inPlaceUpdate:       false,
+			onlyMetadataChanged: false,
+		},
+		"container-added": {
+			updatedPod: newPod(func(pod *corev1.Pod) {
+				pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "bar", Image: "nginx:v1"})
+			}),
+			inPlaceUpdate:       false,
+			onlyMetadataChanged: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		updater := &InPlaceIfPossibleUpdater{inPlaceResizeEnabled: tc.inPlaceResizeEnabled}
+		inPlaceUpdate, onlyMetadataChanged, reason := updater.diffPod(newPod(nil), tc.updatedPod)
+		if inPlaceUpdate != tc.inPlaceUpdate || onlyMetadataChanged != tc.onlyMetadataChanged {
+			t.Fatalf("case %s: expected inPlaceUpdate %t and onlyMetadataChanged %t, got %t and %t (%s)",
+				name, tc.inPlaceUpdate, tc.onlyMetadataChanged, inPlaceUpdate, onlyMetadataChanged, reason)
+		}
+
+		if !inPlaceUpdate && reason == "" {
+			t.Fatalf("case %s: expected reason for not supporting in-place update", name)
+		}
+	}
+}
+
+// podStatusReader returns Pods with the raw container statuses, which are not fully kept by the Pod type.
+type podStatusReader struct {
+	client.Reader
+	containerStatuses []interface{}
+}
+
+func (r *podStatusReader) Get(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+	return unstructured.SetNestedSlice(obj.(*unstructured.Unstructured).Object, r.containerStatuses, "status", "containerStatuses")
+}
+
+func TestInPlaceResizeFinishStatus(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "foo-0",
+			Annotations: map[string]string{
+				appsv1alpha1.LastPodStatusAnnotationKey: `{"containerStates":{"foo":{"latestResources":{"requests":{"cpu":"2"}}}}}`,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "foo",
+					Image: "nginx:v1",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("2"),
+						},
+					},
+				},
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "foo", ImageID: "nginx@v1"}},
+		},
+	}
+
+	testCases := map[string]struct {
+		containerStatuses []interface{}
+		finished          bool
+	}{
+		"resources-not-reported": {
+			containerStatuses: []interface{}{
+				map[string]interface{}{"name": "foo"},
+			},
+			finished: false,
+		},
+		"not-resized": {
+			containerStatuses: []interface{}{
+				map[string]interface{}{"name": "foo", "resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "1"}}},
+			},
+			finished: false,
+		},
+		"resized": {
+			containerStatuses: []interface{}{
+				map[string]interface{}{"name": "foo", "resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "2000m"}}},
+			},
+			finished: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		updater := &InPlaceIfPossibleUpdater{inPlaceResizeEnabled: true, reader: &podStatusReader{containerStatuses: tc.containerStatuses}}
+		finished, msg, err := updater.GetPodUpdateFinishStatus(&PodUpdateInfo{PodWrapper: &collasetutils.PodWrapper{Pod: pod}, IsUpdatedRevision: true})
+		if err != nil {
+			t.Fatalf("case %s: unexpected error %s", name, err)
+		}
+		if finished != tc.finished {
+			t.Fatalf("case %s: expected finished %t, got %t (%s)", name, tc.finished, finished, msg)
+		}
+	}
+}
+
+func TestIsPodAvailable(t *testing.T) {
+	newPod := func(serviceAvailable bool, readySince *time.Time) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{},
+			},
+		}
+		if serviceAvailable {
+			pod.Labels[appsv1alpha1.PodServiceAvailableLabel] = "true"
+		}
+		if readySince != nil {
+			pod.Status.Conditions = []corev1.PodCondition{
+				{
+					Type:               corev1.PodReady,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(*readySince),
+				},
+			}
+		}
+		return pod
+	}
+
+	now := time.Now()
+	longAgo := now.Add(-time.Minute)
+	justNow := now.Add(-time.Second)
+
+	testCases := map[string]struct {
+		minReadySeconds int32
+		pod             *corev1.Pod
+		available       bool
+		waitFor         bool
+	}{
+		"not-service-available": {
+			pod:       newPod(false, &longAgo),
+			available: false,
+		},
+		"service-available-without-min-ready": {
+			pod:       newPod(true, nil),
+			available: true,
+		},
+		"not-ready-with-min-ready": {
+			minReadySeconds: 10,
+			pod:             newPod(true, nil),
+			available:       false,
+		},
+		"ready-not-long-enough": {
+			minReadySeconds: 10,
+			pod:             newPod(true, &justNow),
+			available:       false,
+			waitFor:         true,
+		},
+		"ready-long-enough": {
+			minReadySeconds: 10,
+			pod:             newPod(true, &longAgo),
+			available:       true,
+		},
+	}
+
+	for name, tc := range testCases {
+		cls := &appsv1alpha1.CollaSet{
+			Spec: appsv1alpha1.CollaSetSpec{
+				MinReadySeconds: tc.minReadySeconds,
+			},
+		}
+
+		available, waitFor := IsPodAvailable(cls, tc.pod)
+		if available != tc.available {
+			t.Fatalf("case %s: expected available %t, got %t", name, tc.available, available)
+		}
+		if (waitFor > 0) != tc.waitFor {
+			t.Fatalf("case %s: expected waiting %t, got %s", name, tc.waitFor, waitFor)
+		}
+	}
+}
+
+func TestPodTemplateVariables(t *testing.T) {
+	newPod := func(image string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					"app":   "foo",
+					"shard": "shard-${context.shard}",
+				},
+				Annotations: map[string]string{
+					"instance": "${context.ID}",
+					"zone":     "${context.zone}",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "foo",
+						Image: image,
+						Env: []corev1.EnvVar{
+							{Name: "PEERS", Value: "${context.peers}"},
+							{Name: "UNKNOWN", Value: "${context.unknown}"},
+							{Name: "OWNER", Value: "${context.Owner}"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	cls := &appsv1alpha1.CollaSet{
+		Spec: appsv1alpha1.CollaSetSpec{
+			ScaleStrategy: appsv1alpha1.ScaleStrategy{
+				InstanceData: []appsv1alpha1.InstanceIDData{
+					{ID: 2, Data: map[string]string{"shard": "2", "zone": "b"}},
+					{ID: 3, Data: map[string]string{"shard": "1", "zone": "a"}},
+				},
+			},
+		},
+	}
+	contextDetail := &appsv1alpha1.ContextDetail{
+		ID:   3,
+		Data: map[string]string{podcontext.OwnerContextKey: "foo", "shard": "0", "peers": "foo-0,foo-1"},
+	}
+	currentPod, updatedPod := newPod("nginx:v1"), newPod("nginx:v2")
+	collasetutils.ResolvePodTemplateVariables(currentPod, contextDetail, collasetutils.GetInstanceData(cls, contextDetail.ID))
+	collasetutils.ResolvePodTemplateVariables(updatedPod, contextDetail, collasetutils.GetInstanceData(cls, contextDetail.ID))
+
+	// the user data takes precedence over the context data
+	if currentPod.Labels["shard"] != "shard-1" || currentPod.Annotations["zone"] != "a" || currentPod.Annotations["instance"] != "3" {
+		t.Fatalf("expected metadata resolved with user data, got labels %v and annotations %v", currentPod.Labels, currentPod.Annotations)
+	}
+	// the reserved keys are not exposed
+	if env := currentPod.Spec.Containers[0].Env; env[0].Value != "foo-0,foo-1" || env[1].Value != "" || env[2].Value != "" {
+		t.Fatalf("expected env resolved with context, got %v", env)
+	}
+
+	// the variables are resolved with the same context, so that Pods are still able to be updated in-place
+	updater := &InPlaceIfPossibleUpdater{}
+	if inPlaceUpdate, _, reason := updater.diffPod(currentPod, updatedPod); !inPlaceUpdate {
+		t.Fatalf("expected Pod to be updated in-place, got reason: %s", reason)
+	}
+}
+
+func TestReservedContextDataKeys(t *testing.T) {
+	for _, key := range []string{podcontext.OwnerContextKey, podcontext.RevisionContextDataKey, ScaleInContextDataKey, pvccontrol.PvcCollaSetContextDataKey} {
+		if !collasetutils.ReservedContextDataKeys.Has(key) {
+			t.Fatalf("expected context data key %s reserved", key)
+		}
+	}
+}
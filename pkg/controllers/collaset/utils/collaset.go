@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/utils/expectations"
+)
+
+// UpdateCollaSetWithRetry updates CollaSet with the changes made by mutateFn, which is applied to the latest CollaSet
+// again on conflict. It returns the updated CollaSet, whose resource version is expected before next reconciling.
+func UpdateCollaSetWithRetry(c client.Client, cls *appsv1alpha1.CollaSet, mutateFn func(cls *appsv1alpha1.CollaSet)) (*appsv1alpha1.CollaSet, error) {
+	latest := cls
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		updated := latest.DeepCopy()
+		mutateFn(updated)
+
+		err := c.Update(context.TODO(), updated)
+		if err == nil {
+			latest = updated
+			return nil
+		}
+
+		if errors.IsConflict(err) {
+			latest = &appsv1alpha1.CollaSet{}
+			if getErr := c.Get(context.TODO(), types.NamespacedName{Namespace: cls.Namespace, Name: cls.Name}, latest); getErr != nil {
+				return getErr
+			}
+		}
+
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return latest, ActiveExpectations.ExpectUpdate(cls, expectations.CollaSet, cls.Name, latest.ResourceVersion)
+}
@@ -17,18 +17,25 @@ limitations under the License.
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
 	"kusionstack.io/operating/pkg/utils"
 )
 
+// PodTemplateVariableInstanceID is the key of template variable resolved to the Pod instance ID.
+const PodTemplateVariableInstanceID = "ID"
+
 type PodWrapper struct {
 	*corev1.Pod
 	ID            int
@@ -63,12 +70,149 @@ func GetPodInstanceID(pod *corev1.Pod) (int, error) {
 	return int(id), nil
 }
 
-func NewPodFrom(owner metav1.Object, ownerRef *metav1.OwnerReference, revision *appsv1.ControllerRevision) (*corev1.Pod, error) {
-	pod, err := controllerutils.NewPodFrom(owner, ownerRef, revision)
+// NewPodFrom builds a Pod from the revision. If the Pod is assigned to a topology domain, the Pod template patch
+// of the domain recorded in the revision is applied after the template. The template variables are resolved with
+// the context of the Pod instance ID, if it is provided.
+func NewPodFrom(cls *appsv1alpha1.CollaSet, ownerRef *metav1.OwnerReference, revision *appsv1.ControllerRevision, domain string, contextDetail *appsv1alpha1.ContextDetail) (*corev1.Pod, error) {
+	pod, err := controllerutils.NewPodFrom(cls, ownerRef, revision)
 	if err != nil {
 		return nil, err
 	}
 
+	if domain != "" {
+		if pod, err = applyTopologyDomainPatch(pod, revision, domain); err != nil {
+			return nil, fmt.Errorf("fail to apply Pod template patch of topology domain %s: %s", domain, err)
+		}
+	}
+
+	if contextDetail != nil {
+		ResolvePodTemplateVariables(pod, contextDetail, GetInstanceData(cls, contextDetail.ID))
+	}
+
 	utils.ControllByKusionStack(pod)
 	return pod, nil
 }
+
+// ReservedContextDataKeys are the keys of context data used by CollaSet itself, which are neither resolved as
+// template variables nor allowed to be set in ScaleStrategy.InstanceData.
+var ReservedContextDataKeys = sets.NewString("Owner", "Revision", "ScaleIn", "PvcCollaSet")
+
+// podTemplateVariableRegexp matches the template variables like ${context.<key>}.
+var podTemplateVariableRegexp = regexp.MustCompile(`\$\{context\.([-._a-zA-Z0-9]+)\}`)
+
+// podTemplateVariableKeyRegexp matches the keys able to be referred to by template variables.
+var podTemplateVariableKeyRegexp = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// IsValidPodTemplateVariableKey indicates whether the key is able to be referred to by template variables.
+func IsValidPodTemplateVariableKey(key string) bool {
+	return podTemplateVariableKeyRegexp.MatchString(key)
+}
+
+// ReplacePodTemplateVariables replaces the template variables ${context.<key>} in the value with the result of
+// replace on the key.
+func ReplacePodTemplateVariables(value string, replace func(key string) string) string {
+	return podTemplateVariableRegexp.ReplaceAllStringFunc(value, func(variable string) string {
+		return replace(podTemplateVariableRegexp.FindStringSubmatch(variable)[1])
+	})
+}
+
+// GetInstanceData returns the user data set for the Pod instance ID in ScaleStrategy.InstanceData.
+func GetInstanceData(cls *appsv1alpha1.CollaSet, id int) map[string]string {
+	for _, instanceData := range cls.Spec.ScaleStrategy.InstanceData {
+		if instanceData.ID == id {
+			return instanceData.Data
+		}
+	}
+
+	return nil
+}
+
+// ResolvePodTemplateVariables replaces the template variables ${context.<key>} in the env values, label values and
+// annotations of the Pod with the value of key in the user data, or else in the data of the instance ID context.
+// The variable ${context.ID} is resolved to the instance ID, and the ones with keys reserved or not found are
+// resolved to empty.
+func ResolvePodTemplateVariables(pod *corev1.Pod, contextDetail *appsv1alpha1.ContextDetail, data map[string]string) {
+	resolve := func(value string) string {
+		return ReplacePodTemplateVariables(value, func(key string) string {
+			if key == PodTemplateVariableInstanceID {
+				return strconv.Itoa(contextDetail.ID)
+			}
+			if ReservedContextDataKeys.Has(key) {
+				return ""
+			}
+			if value, exist := data[key]; exist {
+				return value
+			}
+
+			return contextDetail.Data[key]
+		})
+	}
+
+	for k, v := range pod.Labels {
+		pod.Labels[k] = resolve(v)
+	}
+	for k, v := range pod.Annotations {
+		pod.Annotations[k] = resolve(v)
+	}
+
+	resolveEnv := func(containers []corev1.Container) {
+		for i := range containers {
+			for j := range containers[i].Env {
+				containers[i].Env[j].Value = resolve(containers[i].Env[j].Value)
+			}
+		}
+	}
+	resolveEnv(pod.Spec.InitContainers)
+	resolveEnv(pod.Spec.Containers)
+}
+
+// revisionTopologyData is the topology recorded in ControllerRevision of CollaSet, with the Pod template patches only.
+type revisionTopologyData struct {
+	Spec struct {
+		Topology *struct {
+			Domains []appsv1alpha1.CollaSetTopologyDomain `json:"domains,omitempty"`
+		} `json:"topology,omitempty"`
+	} `json:"spec"`
+}
+
+// applyTopologyDomainPatch applies the Pod template patch of the topology domain recorded in the revision to the Pod.
+func applyTopologyDomainPatch(pod *corev1.Pod, revision *appsv1.ControllerRevision, domain string) (*corev1.Pod, error) {
+	data := &revisionTopologyData{}
+	if err := json.Unmarshal(revision.Data.Raw, data); err != nil {
+		return nil, err
+	}
+
+	if data.Spec.Topology == nil {
+		return pod, nil
+	}
+
+	for _, d := range data.Spec.Topology.Domains {
+		if d.Name != domain || d.PodTemplatePatch == nil || len(d.PodTemplatePatch.Raw) == 0 {
+			continue
+		}
+
+		return ApplyPodTemplatePatch(pod, d.PodTemplatePatch.Raw)
+	}
+
+	return pod, nil
+}
+
+// ApplyPodTemplatePatch applies the strategic merge patch of Pod template to the Pod.
+func ApplyPodTemplatePatch(pod *corev1.Pod, patch []byte) (*corev1.Pod, error) {
+	podBytes, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch(podBytes, patch, &corev1.Pod{})
+	if err != nil {
+		return nil, err
+	}
+
+	patchedPod := &corev1.Pod{}
+	if err := json.Unmarshal(patched, patchedPod); err != nil {
+		return nil, err
+	}
+
+	return patchedPod, nil
+}
@@ -53,7 +53,7 @@ func Add(mgr manager.Manager) error {
 
 func AddToMgr(mgr manager.Manager, r reconcile.Reconciler) error {
 	c, err := controller.New(controllerName, mgr, controller.Options{
-		MaxConcurrentReconciles: 5,
+		MaxConcurrentReconciles: controllerutils.MaxConcurrentReconciles,
 		Reconciler:              r,
 	})
 	if err != nil {
@@ -65,7 +65,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 func addToMgr(mgr manager.Manager, r reconcile.Reconciler) (controller.Controller, error) {
 	// Create a new controller
 	c, err := controller.New(controllerName, mgr, controller.Options{
-		MaxConcurrentReconciles: 5,
+		MaxConcurrentReconciles: controllerutils.MaxConcurrentReconciles,
 		Reconciler:              r,
 	})
 	if err != nil {
@@ -18,15 +18,25 @@ package resourcecontext
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
+	controllerutils "kusionstack.io/operating/pkg/controllers/utils"
 	"kusionstack.io/operating/pkg/controllers/utils/expectations"
 	"kusionstack.io/operating/pkg/utils/mixin"
 )
@@ -35,6 +45,10 @@ const (
 	controllerName = "resourcecontext-controller"
 )
 
+// LeakedIDGracePeriod is the duration to wait after the owner of IDs is found not existing, before its IDs are
+// reclaimed as leaked. It tolerates the owner which is just created and not observed yet.
+var LeakedIDGracePeriod = 5 * time.Minute
+
 // ResourceContextReconciler reconciles and reclaims a ResourceContext object
 type ResourceContextReconciler struct {
 	*mixin.ReconcilerMixin
@@ -58,7 +72,7 @@ func NewReconciler(mgr ctrl.Manager) reconcile.Reconciler {
 func AddToMgr(mgr ctrl.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
 	c, err := controller.New(controllerName, mgr, controller.Options{
-		MaxConcurrentReconciles: 5,
+		MaxConcurrentReconciles: controllerutils.MaxConcurrentReconciles,
 		Reconciler:              r,
 	})
 	if err != nil {
@@ -76,14 +90,37 @@ func AddToMgr(mgr ctrl.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// the owners of ResourceContext are checked again when CollaSets are deleted
+	err = c.Watch(&source.Kind{Type: &appsv1alpha1.CollaSet{}}, handler.EnqueueRequestsFromMapFunc(enqueueResourceContextForCollaSet))
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func enqueueResourceContextForCollaSet(obj client.Object) []reconcile.Request {
+	cls, ok := obj.(*appsv1alpha1.CollaSet)
+	if !ok {
+		return nil
+	}
+
+	contextName := cls.Spec.ScaleStrategy.Context
+	if contextName == "" {
+		contextName = cls.Name
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: cls.Namespace, Name: contextName}}}
+}
+
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=resourcecontexts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=resourcecontexts/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=resourcecontexts/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apps.kusionstack.io,resources=collasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
 
 // Reconcile aims to reclaim ResourceContext which is not in used which means the ResourceContext contains no Context.
+// It also records the IDs owned by each owner in status, and reclaims the IDs leaked by owners no longer existing.
 func (r *ResourceContextReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := r.Logger.WithValues("resourceContext", req.String())
 	instance := &appsv1alpha1.ResourceContext{}
@@ -105,6 +142,40 @@ func (r *ResourceContextReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, nil
 	}
 
+	newStatus, err := r.calculateStatus(instance)
+	if err != nil {
+		logger.Error(err, "failed to calculate status")
+		return ctrl.Result{}, err
+	}
+
+	// reclaim the IDs of owners which have been missing for the grace period
+	var requeueAfter time.Duration
+	leakedOwners := sets.NewString()
+	for _, owner := range newStatus.Owners {
+		if owner.MissingSince == nil {
+			continue
+		}
+
+		if remaining := time.Until(owner.MissingSince.Add(LeakedIDGracePeriod)); remaining > 0 {
+			if requeueAfter == 0 || remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			continue
+		}
+
+		leakedOwners.Insert(owner.Name)
+	}
+
+	if leakedOwners.Len() > 0 {
+		logger.Info("try to reclaim IDs of missing owners", "owners", leakedOwners.List())
+		if err := r.reclaimLeakedIDs(instance, leakedOwners); err != nil {
+			logger.Error(err, "failed to reclaim leaked IDs")
+			return ctrl.Result{}, err
+		}
+
+		newStatus = filterOutOwners(newStatus, leakedOwners)
+	}
+
 	// if ResourceContext is empty, delete it
 	if len(instance.Spec.Contexts) == 0 {
 		logger.Info("try to delete empty ResourceContext")
@@ -116,7 +187,164 @@ func (r *ResourceContextReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 			logger.Error(err, "failed to expect deletion after ResourceContext is deleted")
 			return ctrl.Result{}, err
 		}
+
+		return ctrl.Result{}, nil
+	}
+
+	missingOwners := newlyMissingOwners(instance.Status, newStatus)
+	if err := r.updateStatus(ctx, instance, newStatus); err != nil {
+		logger.Error(err, "failed to update status")
+		return ctrl.Result{}, err
+	}
+
+	// report the missing owners once their missing time is recorded
+	for _, owner := range missingOwners {
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, "OwnerMissing", "owner %s of %d IDs is not found, and its IDs will be reclaimed after %s", owner.Name, owner.IDs, LeakedIDGracePeriod)
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// calculateStatus counts the IDs owned by each owner, and records the time when an owner is first found not existing.
+func (r *ResourceContextReconciler) calculateStatus(instance *appsv1alpha1.ResourceContext) (*appsv1alpha1.ResourceContextStatus, error) {
+	missingSince := map[string]*metav1.Time{}
+	for _, owner := range instance.Status.Owners {
+		if owner.MissingSince != nil {
+			missingSince[owner.Name] = owner.MissingSince
+		}
+	}
+
+	newStatus := &appsv1alpha1.ResourceContextStatus{
+		ObservedGeneration: instance.Generation,
+	}
+
+	ownedIDs := map[string]int32{}
+	for _, detail := range instance.Spec.Contexts {
+		owner := detail.Data[podcontext.OwnerContextKey]
+		if owner == "" {
+			newStatus.UnownedIDs++
+			continue
+		}
+
+		ownedIDs[owner]++
+	}
+
+	for _, name := range sets.StringKeySet(ownedIDs).List() {
+		ownerStatus := appsv1alpha1.ContextOwnerStatus{
+			Name: name,
+			IDs:  ownedIDs[name],
+		}
+
+		exist, err := r.ownerExists(instance.Namespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exist {
+			if since, recorded := missingSince[name]; recorded {
+				ownerStatus.MissingSince = since
+			} else {
+				now := metav1.Now()
+				ownerStatus.MissingSince = &now
+			}
+		}
+
+		newStatus.Owners = append(newStatus.Owners, ownerStatus)
+	}
+
+	return newStatus, nil
+}
+
+func (r *ResourceContextReconciler) ownerExists(namespace, name string) (bool, error) {
+	cls := &appsv1alpha1.CollaSet{}
+	if err := r.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, cls); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("fail to get owner %s/%s: %s", namespace, name, err)
+	}
+
+	return true, nil
+}
+
+// reclaimLeakedIDs removes the IDs owned by the indicated owners from ResourceContext. Only the entries of these owners
+// are patched, and the patch fails with conflict if ResourceContext has been changed after observed.
+func (r *ResourceContextReconciler) reclaimLeakedIDs(instance *appsv1alpha1.ResourceContext, owners sets.String) error {
+	reclaimedIDs := map[string][]int{}
+	changes := map[int]*appsv1alpha1.ContextDetail{}
+	var ids []int
+	for _, detail := range instance.Spec.Contexts {
+		if owner := detail.Data[podcontext.OwnerContextKey]; owners.Has(owner) {
+			reclaimedIDs[owner] = append(reclaimedIDs[owner], detail.ID)
+			changes[detail.ID] = nil
+			ids = append(ids, detail.ID)
+		}
+	}
+
+	patched, err := podcontext.PatchContexts(r.Client, instance, changes, ids)
+	if err != nil {
+		return fmt.Errorf("fail to patch ResourceContext %s/%s: %s", instance.Namespace, instance.Name, err)
+	}
+	patched.DeepCopyInto(instance)
+
+	if err := activeExpectations.ExpectUpdate(instance, expectations.ResourceContext, instance.Name, instance.ResourceVersion); err != nil {
+		return err
+	}
+
+	for _, owner := range owners.List() {
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, "LeakedIDReclaimed", "succeed to reclaim IDs %v of missing owner %s", reclaimedIDs[owner], owner)
+	}
+
+	return nil
+}
+
+func (r *ResourceContextReconciler) updateStatus(ctx context.Context, instance *appsv1alpha1.ResourceContext, newStatus *appsv1alpha1.ResourceContextStatus) error {
+	if equality.Semantic.DeepEqual(instance.Status, *newStatus) {
+		return nil
+	}
+
+	instance.Status = *newStatus
+
+	err := r.Client.Status().Update(ctx, instance)
+	if err == nil {
+		if err := activeExpectations.ExpectUpdate(instance, expectations.ResourceContext, instance.Name, instance.ResourceVersion); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// newlyMissingOwners returns the owners found missing in new status, whose missing time is not recorded in the
+// current status.
+func newlyMissingOwners(status appsv1alpha1.ResourceContextStatus, newStatus *appsv1alpha1.ResourceContextStatus) []appsv1alpha1.ContextOwnerStatus {
+	recorded := sets.NewString()
+	for _, owner := range status.Owners {
+		if owner.MissingSince != nil {
+			recorded.Insert(owner.Name)
+		}
+	}
+
+	var owners []appsv1alpha1.ContextOwnerStatus
+	for _, owner := range newStatus.Owners {
+		if owner.MissingSince != nil && !recorded.Has(owner.Name) {
+			owners = append(owners, owner)
+		}
+	}
+
+	return owners
+}
+
+func filterOutOwners(status *appsv1alpha1.ResourceContextStatus, owners sets.String) *appsv1alpha1.ResourceContextStatus {
+	var filtered []appsv1alpha1.ContextOwnerStatus
+	for _, owner := range status.Owners {
+		if owners.Has(owner.Name) {
+			continue
+		}
+		filtered = append(filtered, owner)
 	}
 
-	return ctrl.Result{}, nil
+	status.Owners = filtered
+	return status
 }
@@ -29,10 +29,14 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -45,9 +49,11 @@ import (
 	"kusionstack.io/operating/apis"
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 	"kusionstack.io/operating/pkg/controllers/collaset"
+	"kusionstack.io/operating/pkg/controllers/collaset/podcontext"
 	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
 	"kusionstack.io/operating/pkg/controllers/poddeletion"
 	"kusionstack.io/operating/pkg/utils/inject"
+	"kusionstack.io/operating/pkg/utils/mixin"
 )
 
 var (
@@ -229,6 +235,48 @@ var _ = Describe("ResourceContext controller", func() {
 			return c.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, resourceContext)
 		}, 5*time.Second, 1*time.Second).ShouldNot(BeNil())
 	})
+
+	It("resource context reclaim leaked ids", func() {
+		testcase := "test-rc-leaked-ids"
+		Expect(createNamespace(c, testcase)).Should(BeNil())
+
+		// ID 0 is leaked by an owner not existing, and ID 1 is released without owner
+		resourceContext := &appsv1alpha1.ResourceContext{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.ResourceContextSpec{
+				Contexts: []appsv1alpha1.ContextDetail{
+					{
+						ID: 0,
+						Data: map[string]string{
+							podcontext.OwnerContextKey: "ghost",
+						},
+					},
+					{
+						ID: 1,
+					},
+				},
+			},
+		}
+		Expect(c.Create(context.TODO(), resourceContext)).Should(BeNil())
+
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: testcase, Name: "foo"}, resourceContext)).Should(BeNil())
+			return len(resourceContext.Status.Owners) == 1 && resourceContext.Status.Owners[0].MissingSince != nil
+		}, 5*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(resourceContext.Status.Owners[0].Name).Should(BeEquivalentTo("ghost"))
+		Expect(resourceContext.Status.Owners[0].IDs).Should(BeEquivalentTo(1))
+		Expect(resourceContext.Status.UnownedIDs).Should(BeEquivalentTo(1))
+
+		// the leaked ID is reclaimed after the grace period
+		Eventually(func() bool {
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: testcase, Name: "foo"}, resourceContext)).Should(BeNil())
+			return len(resourceContext.Spec.Contexts) == 1 && len(resourceContext.Status.Owners) == 0
+		}, 10*time.Second, 1*time.Second).Should(BeTrue())
+		Expect(resourceContext.Spec.Contexts[0].ID).Should(BeEquivalentTo(1))
+	})
 })
 
 func expectedStatusReplicas(c client.Client, cls *appsv1alpha1.CollaSet, scheduledReplicas, readyReplicas, availableReplicas, replicas, updatedReplicas, operatingReplicas,
@@ -314,6 +362,81 @@ func testReconcile(inner reconcile.Reconciler) (reconcile.Reconciler, chan recon
 	return fn, requests
 }
 
+func TestReclaimLeakedIDsWithOwnerMissing(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := appsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("fail to build scheme: %s", err)
+	}
+
+	// ID 0 is leaked by an owner not existing, and ID 1 is owned by an existing one
+	resourceContext := &appsv1alpha1.ResourceContext{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test",
+			Name:      "foo",
+		},
+		Spec: appsv1alpha1.ResourceContextSpec{
+			Contexts: []appsv1alpha1.ContextDetail{
+				{ID: 0, Data: map[string]string{podcontext.OwnerContextKey: "ghost"}},
+				{ID: 1, Data: map[string]string{podcontext.OwnerContextKey: "foo"}},
+			},
+		},
+	}
+	owner := &appsv1alpha1.CollaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "foo"}}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(resourceContext, owner).Build()
+	InitExpectations(fakeClient)
+	recorder := record.NewFakeRecorder(10)
+	r := &ResourceContextReconciler{ReconcilerMixin: &mixin.ReconcilerMixin{Client: fakeClient, Logger: logf.Log, Recorder: recorder}}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "test", Name: "foo"}}
+
+	// the missing owner is reported only once its missing time is recorded
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(context.TODO(), req); err != nil {
+			t.Fatalf("fail to reconcile: %s", err)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "OwnerMissing") {
+		t.Fatalf("expected event OwnerMissing, got %s", event)
+	}
+
+	observed := &appsv1alpha1.ResourceContext{}
+	if err := fakeClient.Get(context.TODO(), req.NamespacedName, observed); err != nil {
+		t.Fatalf("fail to get ResourceContext: %s", err)
+	}
+
+	// another owner allocates ID 2 after the ResourceContext is observed
+	latest := observed.DeepCopy()
+	latest.Spec.Contexts = append(latest.Spec.Contexts, appsv1alpha1.ContextDetail{ID: 2, Data: map[string]string{podcontext.OwnerContextKey: "foo"}})
+	if err := fakeClient.Update(context.TODO(), latest); err != nil {
+		t.Fatalf("fail to update ResourceContext: %s", err)
+	}
+
+	// the stale ResourceContext is not written back to drop ID 2
+	if err := r.reclaimLeakedIDs(observed, sets.NewString("ghost")); err == nil {
+		t.Fatalf("expected conflict reclaiming IDs from stale ResourceContext")
+	}
+
+	if err := fakeClient.Get(context.TODO(), req.NamespacedName, observed); err != nil {
+		t.Fatalf("fail to get ResourceContext: %s", err)
+	}
+	if err := r.reclaimLeakedIDs(observed, sets.NewString("ghost")); err != nil {
+		t.Fatalf("fail to reclaim leaked IDs: %s", err)
+	}
+
+	if err := fakeClient.Get(context.TODO(), req.NamespacedName, observed); err != nil {
+		t.Fatalf("fail to get ResourceContext: %s", err)
+	}
+	var ids []int
+	for _, detail := range observed.Spec.Contexts {
+		ids = append(ids, detail.ID)
+	}
+	if !equality.Semantic.DeepEqual(ids, []int{1, 2}) {
+		t.Fatalf("expected IDs [1 2] kept, got %v", ids)
+	}
+}
+
 func TestResourceContextController(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "ResourceContext Test Suite")
@@ -347,6 +470,9 @@ var _ = BeforeSuite(func() {
 
 	c = mgr.GetClient()
 
+	// shorten the grace period to reclaim leaked IDs in test
+	LeakedIDGracePeriod = 2 * time.Second
+
 	var r reconcile.Reconciler
 	r, request = testReconcile(NewReconciler(mgr))
 	err = AddToMgr(mgr, r)
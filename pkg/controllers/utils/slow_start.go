@@ -22,6 +22,10 @@ const (
 	SlowStartInitialBatchSize = 1
 )
 
+// SlowStartMaxBatchSize is the default ceiling of the batch size in SlowStartBatch.
+// A non-positive value means the batch size is not limited.
+var SlowStartMaxBatchSize = 0
+
 func intMin(l, r int) int {
 	if l < r {
 		return l
@@ -40,13 +44,28 @@ func intMin(l, r int) int {
 // If there are any failures in a batch, all remaining batches are skipped
 // after waiting for the current batch to complete.
 //
+// The batch size is limited by SlowStartMaxBatchSize.
+//
 // It returns the number of successful calls to the function.
 func SlowStartBatch(count int, initialBatchSize int, shortCircuit bool, fn func(int, error) error) (int, error) {
+	return SlowStartBatchWithLimit(count, initialBatchSize, SlowStartMaxBatchSize, shortCircuit, fn)
+}
+
+// SlowStartBatchWithLimit works like SlowStartBatch, but the batch size never exceeds maxBatchSize,
+// which limits the number of concurrent calls. A non-positive maxBatchSize means no limit.
+func SlowStartBatchWithLimit(count int, initialBatchSize int, maxBatchSize int, shortCircuit bool, fn func(int, error) error) (int, error) {
+	nextBatchSize := func(batchSize int) int {
+		if maxBatchSize > 0 {
+			batchSize = intMin(batchSize, maxBatchSize)
+		}
+		return batchSize
+	}
+
 	remaining := count
 	successes := 0
 	index := 0
 	var gotErr error
-	for batchSize := intMin(remaining, initialBatchSize); batchSize > 0; batchSize = intMin(2*batchSize, remaining) {
+	for batchSize := nextBatchSize(intMin(remaining, initialBatchSize)); batchSize > 0; batchSize = nextBatchSize(intMin(2*batchSize, remaining)) {
 		errCh := make(chan error, batchSize)
 		var wg sync.WaitGroup
 		wg.Add(batchSize)
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlowStartBatchWithLimit(t *testing.T) {
+	testCases := map[string]struct {
+		count           int
+		maxBatchSize    int
+		expectedMaxSize int
+	}{
+		"no-limit": {
+			count:           20,
+			maxBatchSize:    0,
+			expectedMaxSize: 8,
+		},
+		"limited": {
+			count:           20,
+			maxBatchSize:    3,
+			expectedMaxSize: 3,
+		},
+	}
+
+	for name, tc := range testCases {
+		var lock sync.Mutex
+		running, maxRunning := 0, 0
+		succCount, err := SlowStartBatchWithLimit(tc.count, SlowStartInitialBatchSize, tc.maxBatchSize, false, func(_ int, _ error) error {
+			lock.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			lock.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			lock.Lock()
+			running--
+			lock.Unlock()
+			return nil
+		})
+
+		if err != nil || succCount != tc.count {
+			t.Fatalf("case %s: expected %d successes, got %d with error %v", name, tc.count, succCount, err)
+		}
+		if maxRunning > tc.expectedMaxSize {
+			t.Fatalf("case %s: expected at most %d concurrent calls, got %d", name, tc.expectedMaxSize, maxRunning)
+		}
+	}
+}
@@ -70,6 +70,7 @@ func (h *MutatingHandler) Handle(ctx context.Context, req admission.Request) (re
 func (h *MutatingHandler) setDetaultCollaSet(cls *appsv1alpha1.CollaSet) {
 	h.setDefaultPodSpec(cls)
 	h.setDefaultCollaSetUpdateStrategy(cls)
+	h.setDefaultCollaSetScaleStrategy(cls)
 }
 
 func (h *MutatingHandler) setDefaultPodSpec(in *appsv1alpha1.CollaSet) {
@@ -299,6 +300,20 @@ func (h *MutatingHandler) setDefaultCollaSetUpdateStrategy(cls *appsv1alpha1.Col
 	}
 }
 
+func (h *MutatingHandler) setDefaultCollaSetScaleStrategy(cls *appsv1alpha1.CollaSet) {
+	if cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy == nil {
+		cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy = &appsv1alpha1.PersistentVolumeClaimRetentionPolicy{}
+	}
+
+	if cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenDeleted == "" {
+		cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenDeleted = appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType
+	}
+
+	if cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenScaled == "" {
+		cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenScaled = appsv1alpha1.RetainPersistentVolumeClaimRetentionPolicyType
+	}
+}
+
 var _ inject.Client = &MutatingHandler{}
 
 func (h *MutatingHandler) InjectClient(c client.Client) error {
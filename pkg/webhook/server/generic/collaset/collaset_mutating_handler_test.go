@@ -47,4 +47,18 @@ func TestMutatingCollaSet(t *testing.T) {
 	if cls.Spec.UpdateStrategy.RollingUpdate.ByPartition == nil {
 		t.Fatalf("expected default byPartition, got nil")
 	}
+
+	if cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy == nil {
+		t.Fatalf("expected default persistentVolumeClaimRetentionPolicy, got nil")
+	}
+
+	if cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenDeleted != appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType {
+		t.Fatalf("expected default value is %s, got %s", appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType,
+			cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenDeleted)
+	}
+
+	if cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenScaled != appsv1alpha1.RetainPersistentVolumeClaimRetentionPolicyType {
+		t.Fatalf("expected default value is %s, got %s", appsv1alpha1.RetainPersistentVolumeClaimRetentionPolicyType,
+			cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy.WhenScaled)
+	}
 }
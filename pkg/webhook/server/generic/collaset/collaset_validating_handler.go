@@ -18,13 +18,20 @@ package collaset
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/kubernetes/pkg/apis/core"
 	k8scorev1 "k8s.io/kubernetes/pkg/apis/core/v1"
@@ -34,6 +41,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
+	collasetutils "kusionstack.io/operating/pkg/controllers/collaset/utils"
 	commonutils "kusionstack.io/operating/pkg/utils"
 	"kusionstack.io/operating/pkg/utils/mixin"
 	"kusionstack.io/operating/pkg/webhook/server/generic/utils"
@@ -87,10 +95,19 @@ func (h *ValidatingHandler) validate(cls, oldCls *appsv1alpha1.CollaSet) error {
 	if fieldErr := h.validateReplicas(cls, fSpec); fieldErr != nil {
 		allErrs = append(allErrs, fieldErr)
 	}
+	if cls.Spec.MinReadySeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fSpec.Child("minReadySeconds"), cls.Spec.MinReadySeconds,
+			"minReadySeconds should not be smaller than 0"))
+	}
+	allErrs = append(allErrs, validatePodManagementPolicyType(cls.Spec.PodManagementPolicy, fSpec.Child("podManagementPolicy"))...)
 	allErrs = append(allErrs, h.validatePodTemplateSpec(cls, fSpec)...)
 	allErrs = append(allErrs, h.validateSelector(cls, fSpec)...)
 	allErrs = append(allErrs, h.validateScaleStrategy(cls, oldCls, fSpec)...)
 	allErrs = append(allErrs, h.validateUpdateStrategy(cls, fSpec)...)
+	allErrs = append(allErrs, h.validateVolumeClaimTemplates(cls, fSpec)...)
+	if cls.Spec.Topology != nil {
+		allErrs = append(allErrs, validateTopology(cls.Spec.Topology, &cls.Spec.Template, fSpec.Child("topology"))...)
+	}
 
 	return allErrs.ToAggregate()
 }
@@ -103,13 +120,203 @@ func (h *ValidatingHandler) validateScaleStrategy(cls, oldCls *appsv1alpha1.Coll
 			*cls.Spec.ScaleStrategy.OperationDelaySeconds, "operationDelaySeconds should not be smaller than 0"))
 	}
 
+	if cls.Spec.ScaleStrategy.MaxConcurrency != nil && *cls.Spec.ScaleStrategy.MaxConcurrency <= 0 {
+		allErrs = append(allErrs, field.Invalid(fSpec.Child("scaleStrategy", "maxConcurrency"),
+			*cls.Spec.ScaleStrategy.MaxConcurrency, "maxConcurrency should be larger than 0"))
+	}
+
 	if oldCls != nil && oldCls.Spec.ScaleStrategy.Context != cls.Spec.ScaleStrategy.Context {
 		allErrs = append(allErrs, field.Forbidden(fSpec.Child("scaleStrategy", "context"), "scaleStrategy.context is not allowed to be changed"))
 	}
 
+	if len(cls.Spec.ScaleStrategy.PodToExclude) > 0 && len(cls.Spec.VolumeClaimTemplates) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fSpec.Child("scaleStrategy", "podToExclude"), "podToExclude is not supported for CollaSet with volumeClaimTemplates"))
+	}
+
+	podToExclude := sets.NewString(cls.Spec.ScaleStrategy.PodToExclude...)
+	for i, podName := range cls.Spec.ScaleStrategy.PodToInclude {
+		if podToExclude.Has(podName) {
+			allErrs = append(allErrs, field.Invalid(fSpec.Child("scaleStrategy", "podToInclude").Index(i), podName, "pod should not be included and excluded at the same time"))
+		}
+	}
+
+	for i, podName := range cls.Spec.ScaleStrategy.PodToDelete {
+		if podToExclude.Has(podName) {
+			allErrs = append(allErrs, field.Invalid(fSpec.Child("scaleStrategy", "podToDelete").Index(i), podName, "pod should not be deleted and excluded at the same time"))
+		}
+	}
+
+	if strategy := cls.Spec.ScaleStrategy.IDAllocationStrategy; strategy != nil {
+		allErrs = append(allErrs, validateIDAllocationStrategy(strategy, fSpec.Child("scaleStrategy", "idAllocationStrategy"))...)
+	}
+
+	idToRelease := sets.NewInt()
+	for i, id := range cls.Spec.ScaleStrategy.IDToRelease {
+		fID := fSpec.Child("scaleStrategy", "idToRelease").Index(i)
+		if id < 0 {
+			allErrs = append(allErrs, field.Invalid(fID, id, "id should not be smaller than 0"))
+		}
+		if idToRelease.Has(id) {
+			allErrs = append(allErrs, field.Duplicate(fID, id))
+		}
+		idToRelease.Insert(id)
+	}
+
+	idToAdopt := sets.NewInt()
+	for i, id := range cls.Spec.ScaleStrategy.IDToAdopt {
+		fID := fSpec.Child("scaleStrategy", "idToAdopt").Index(i)
+		if id < 0 {
+			allErrs = append(allErrs, field.Invalid(fID, id, "id should not be smaller than 0"))
+		}
+		if idToAdopt.Has(id) {
+			allErrs = append(allErrs, field.Duplicate(fID, id))
+		}
+		if idToRelease.Has(id) {
+			allErrs = append(allErrs, field.Invalid(fID, id, "id should not be released and adopted at the same time"))
+		}
+		idToAdopt.Insert(id)
+	}
+
+	instanceDataIDs := sets.NewInt()
+	for i, instanceData := range cls.Spec.ScaleStrategy.InstanceData {
+		fData := fSpec.Child("scaleStrategy", "instanceData").Index(i)
+		if instanceData.ID < 0 {
+			allErrs = append(allErrs, field.Invalid(fData.Child("id"), instanceData.ID, "id should not be smaller than 0"))
+		}
+		if instanceDataIDs.Has(instanceData.ID) {
+			allErrs = append(allErrs, field.Duplicate(fData.Child("id"), instanceData.ID))
+		}
+		instanceDataIDs.Insert(instanceData.ID)
+
+		for key := range instanceData.Data {
+			fKey := fData.Child("data").Key(key)
+			if key == collasetutils.PodTemplateVariableInstanceID || collasetutils.ReservedContextDataKeys.Has(key) {
+				allErrs = append(allErrs, field.Invalid(fKey, key, "key is reserved"))
+			} else if !collasetutils.IsValidPodTemplateVariableKey(key) {
+				allErrs = append(allErrs, field.Invalid(fKey, key, "key should consist of alphanumeric characters, '-', '_' or '.'"))
+			}
+		}
+	}
+
+	if policy := cls.Spec.ScaleStrategy.PersistentVolumeClaimRetentionPolicy; policy != nil {
+		fPolicy := fSpec.Child("scaleStrategy", "persistentVolumeClaimRetentionPolicy")
+		allErrs = append(allErrs, validatePvcRetentionPolicyType(policy.WhenDeleted, fPolicy.Child("whenDeleted"))...)
+		allErrs = append(allErrs, validatePvcRetentionPolicyType(policy.WhenScaled, fPolicy.Child("whenScaled"))...)
+	}
+
+	scaleInPolicies := sets.NewString()
+	for i, policy := range cls.Spec.ScaleStrategy.ScaleInPolicies {
+		fPolicy := fSpec.Child("scaleStrategy", "scaleInPolicies").Index(i)
+		allErrs = append(allErrs, validateScaleInPolicyType(policy, fPolicy)...)
+		if scaleInPolicies.Has(string(policy)) {
+			allErrs = append(allErrs, field.Duplicate(fPolicy, policy))
+		}
+		scaleInPolicies.Insert(string(policy))
+	}
+
+	fNamingPolicy := fSpec.Child("scaleStrategy", "podNamingPolicy")
+	allErrs = append(allErrs, validatePodNamingPolicyType(cls.Spec.ScaleStrategy.PodNamingPolicy, fNamingPolicy)...)
+	if cls.Spec.ScaleStrategy.PodNamingPolicy == appsv1alpha1.InstanceIDPodNamingPolicyType {
+		// the Pods are named as <collaset>-<instance ID>
+		for _, msg := range apimachineryvalidation.NameIsDNSSubdomain(fmt.Sprintf("%s-", cls.Name), true) {
+			allErrs = append(allErrs, field.Invalid(fNamingPolicy, cls.Spec.ScaleStrategy.PodNamingPolicy,
+				fmt.Sprintf("CollaSet name is not able to be the prefix of Pod names: %s", msg)))
+		}
+	}
+
+	return allErrs
+}
+
+func validateIDAllocationStrategy(strategy *appsv1alpha1.IDAllocationStrategy, fPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	ids := sets.NewInt()
+	for i, id := range strategy.IDs {
+		if id < 0 {
+			allErrs = append(allErrs, field.Invalid(fPath.Child("ids").Index(i), id, "id should not be smaller than 0"))
+		}
+		if ids.Has(id) {
+			allErrs = append(allErrs, field.Duplicate(fPath.Child("ids").Index(i), id))
+		}
+		ids.Insert(id)
+	}
+
+	var minID int32
+	if strategy.MinID != nil {
+		minID = *strategy.MinID
+		if minID < 0 {
+			allErrs = append(allErrs, field.Invalid(fPath.Child("minID"), minID, "minID should not be smaller than 0"))
+		}
+	}
+
+	if strategy.MaxID != nil && *strategy.MaxID < minID {
+		allErrs = append(allErrs, field.Invalid(fPath.Child("maxID"), *strategy.MaxID, "maxID should not be smaller than minID"))
+	}
+
+	if cooldown := strategy.ReleasedIDCooldownSeconds; cooldown != nil && (*cooldown < 0 || *cooldown > appsv1alpha1.MaxReleasedIDCooldownSeconds) {
+		allErrs = append(allErrs, field.Invalid(fPath.Child("releasedIDCooldownSeconds"), *cooldown,
+			fmt.Sprintf("releasedIDCooldownSeconds should be between 0 and %d", appsv1alpha1.MaxReleasedIDCooldownSeconds)))
+	}
+
 	return allErrs
 }
 
+func validatePodManagementPolicyType(policyType appsv1alpha1.PodManagementPolicyType, fPath *field.Path) field.ErrorList {
+	switch policyType {
+	case "", appsv1alpha1.ParallelPodManagementPolicyType,
+		appsv1alpha1.OrderedReadyPodManagementPolicyType:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(fPath, policyType, []string{
+			string(appsv1alpha1.ParallelPodManagementPolicyType),
+			string(appsv1alpha1.OrderedReadyPodManagementPolicyType)})}
+	}
+}
+
+func validatePodNamingPolicyType(policyType appsv1alpha1.PodNamingPolicyType, fPath *field.Path) field.ErrorList {
+	switch policyType {
+	case "", appsv1alpha1.DefaultPodNamingPolicyType,
+		appsv1alpha1.InstanceIDPodNamingPolicyType:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(fPath, policyType, []string{
+			string(appsv1alpha1.DefaultPodNamingPolicyType),
+			string(appsv1alpha1.InstanceIDPodNamingPolicyType)})}
+	}
+}
+
+func validateScaleInPolicyType(policyType appsv1alpha1.ScaleInPolicyType, fPath *field.Path) field.ErrorList {
+	switch policyType {
+	case appsv1alpha1.NotReadyScaleInPolicyType,
+		appsv1alpha1.HighestInstanceIDScaleInPolicyType,
+		appsv1alpha1.SpreadByNodeScaleInPolicyType,
+		appsv1alpha1.SpreadByZoneScaleInPolicyType,
+		appsv1alpha1.OldestRevisionScaleInPolicyType,
+		appsv1alpha1.DeletionCostScaleInPolicyType:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(fPath, policyType, []string{
+			string(appsv1alpha1.NotReadyScaleInPolicyType),
+			string(appsv1alpha1.HighestInstanceIDScaleInPolicyType),
+			string(appsv1alpha1.SpreadByNodeScaleInPolicyType),
+			string(appsv1alpha1.SpreadByZoneScaleInPolicyType),
+			string(appsv1alpha1.OldestRevisionScaleInPolicyType),
+			string(appsv1alpha1.DeletionCostScaleInPolicyType)})}
+	}
+}
+
+func validatePvcRetentionPolicyType(policyType appsv1alpha1.PersistentVolumeClaimRetentionPolicyType, fPath *field.Path) field.ErrorList {
+	switch policyType {
+	case "", appsv1alpha1.RetainPersistentVolumeClaimRetentionPolicyType,
+		appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(fPath, policyType, []string{
+			string(appsv1alpha1.RetainPersistentVolumeClaimRetentionPolicyType),
+			string(appsv1alpha1.DeletePersistentVolumeClaimRetentionPolicyType)})}
+	}
+}
+
 func (h *ValidatingHandler) validateUpdateStrategy(cls *appsv1alpha1.CollaSet, fSpec *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 	switch cls.Spec.UpdateStrategy.PodUpdatePolicy {
@@ -131,11 +338,182 @@ func (h *ValidatingHandler) validateUpdateStrategy(cls *appsv1alpha1.CollaSet, f
 			"partition should not be smaller than 0"))
 	}
 
+	if cls.Spec.UpdateStrategy.RollingUpdate != nil && cls.Spec.UpdateStrategy.RollingUpdate.MaxSurge != nil {
+		allErrs = append(allErrs, validateNonNegativeIntOrPercent(cls.Spec.UpdateStrategy.RollingUpdate.MaxSurge,
+			fSpec.Child("updateStrategy", "rollingUpdate", "maxSurge"))...)
+	}
+
+	if cls.Spec.UpdateStrategy.RollingUpdate != nil && cls.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable != nil {
+		allErrs = append(allErrs, validateNonNegativeIntOrPercent(cls.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable,
+			fSpec.Child("updateStrategy", "rollingUpdate", "maxUnavailable"))...)
+	}
+
+	if cls.Spec.UpdateStrategy.RollingUpdate != nil && len(cls.Spec.UpdateStrategy.RollingUpdate.Batches) > 0 {
+		allErrs = append(allErrs, validateBatches(cls.Spec.UpdateStrategy.RollingUpdate,
+			fSpec.Child("updateStrategy", "rollingUpdate"))...)
+	}
+
+	if value, exist := cls.Annotations[appsv1alpha1.CollaSetApprovedBatchAnnotationKey]; exist {
+		if approved, err := strconv.Atoi(value); err != nil || approved < 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "annotations").Key(appsv1alpha1.CollaSetApprovedBatchAnnotationKey),
+				value, "approved batch should be a non-negative integer"))
+		}
+	}
+
+	if cls.Spec.UpdateStrategy.RollbackTo != nil {
+		allErrs = append(allErrs, validateRollbackRevision(cls.Spec.UpdateStrategy.RollbackTo.Revision,
+			fSpec.Child("updateStrategy", "rollbackTo", "revision"))...)
+	}
+
+	if value, exist := cls.Annotations[appsv1alpha1.CollaSetRollbackToAnnotationKey]; exist {
+		allErrs = append(allErrs, validateRollbackRevision(intstr.Parse(value),
+			field.NewPath("metadata", "annotations").Key(appsv1alpha1.CollaSetRollbackToAnnotationKey))...)
+	}
+
 	if cls.Spec.UpdateStrategy.OperationDelaySeconds != nil && *cls.Spec.UpdateStrategy.OperationDelaySeconds < 0 {
 		allErrs = append(allErrs, field.Invalid(fSpec.Child("updateStrategy", "operationDelaySeconds"),
 			*cls.Spec.UpdateStrategy.OperationDelaySeconds, "operationDelaySeconds should not be smaller than 0"))
 	}
 
+	if cls.Spec.UpdateStrategy.MaxConcurrency != nil && *cls.Spec.UpdateStrategy.MaxConcurrency <= 0 {
+		allErrs = append(allErrs, field.Invalid(fSpec.Child("updateStrategy", "maxConcurrency"),
+			*cls.Spec.UpdateStrategy.MaxConcurrency, "maxConcurrency should be larger than 0"))
+	}
+
+	if cls.Spec.UpdateStrategy.ProgressDeadlineSeconds != nil && *cls.Spec.UpdateStrategy.ProgressDeadlineSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fSpec.Child("updateStrategy", "progressDeadlineSeconds"),
+			*cls.Spec.UpdateStrategy.ProgressDeadlineSeconds, "progressDeadlineSeconds should not be smaller than 0"))
+	}
+
+	if cls.Spec.UpdateStrategy.FailurePolicy != nil && cls.Spec.UpdateStrategy.FailurePolicy.FailureThreshold != nil {
+		allErrs = append(allErrs, validateNonNegativeIntOrPercent(cls.Spec.UpdateStrategy.FailurePolicy.FailureThreshold,
+			fSpec.Child("updateStrategy", "failurePolicy", "failureThreshold"))...)
+	}
+
+	return allErrs
+}
+
+func validateRollbackRevision(revision intstr.IntOrString, fPath *field.Path) field.ErrorList {
+	if revision.Type == intstr.Int && revision.IntVal <= 0 {
+		return field.ErrorList{field.Invalid(fPath, revision.String(), "revision number should be greater than 0")}
+	}
+
+	if revision.Type == intstr.String && revision.StrVal == "" {
+		return field.ErrorList{field.Invalid(fPath, revision.String(), "revision name should not be empty")}
+	}
+
+	return nil
+}
+
+func validateBatches(rollingUpdate *appsv1alpha1.RollingUpdateCollaSetStrategy, fPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if rollingUpdate.ByPartition != nil || rollingUpdate.ByLabel != nil {
+		allErrs = append(allErrs, field.Forbidden(fPath.Child("batches"), "batches is not allowed to be used together with byPartition or byLabel"))
+	}
+
+	for i := range rollingUpdate.Batches {
+		batch := &rollingUpdate.Batches[i]
+		fBatch := fPath.Child("batches").Index(i)
+		allErrs = append(allErrs, validateNonNegativeIntOrPercent(&batch.Replicas, fBatch.Child("replicas"))...)
+		if batch.PauseSeconds != nil && *batch.PauseSeconds < 0 {
+			allErrs = append(allErrs, field.Invalid(fBatch.Child("pauseSeconds"), *batch.PauseSeconds, "pauseSeconds should not be smaller than 0"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateTopology(topology *appsv1alpha1.CollaSetTopology, template *corev1.PodTemplateSpec, fPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if topology.TopologyKey == "" {
+		allErrs = append(allErrs, field.Required(fPath.Child("topologyKey"), "topologyKey should not be empty"))
+	}
+
+	if len(topology.Domains) == 0 {
+		allErrs = append(allErrs, field.Required(fPath.Child("domains"), "domains should not be empty"))
+	}
+
+	names := sets.String{}
+	for i, domain := range topology.Domains {
+		fDomain := fPath.Child("domains").Index(i)
+		if domain.Name == "" {
+			allErrs = append(allErrs, field.Required(fDomain.Child("name"), "name should not be empty"))
+		} else if names.Has(domain.Name) {
+			allErrs = append(allErrs, field.Duplicate(fDomain.Child("name"), domain.Name))
+		}
+		names.Insert(domain.Name)
+
+		if len(domain.Values) == 0 {
+			allErrs = append(allErrs, field.Required(fDomain.Child("values"), "values should not be empty"))
+		}
+
+		if domain.Replicas != nil && *domain.Replicas < 0 {
+			allErrs = append(allErrs, field.Invalid(fDomain.Child("replicas"), *domain.Replicas, "replicas should not be smaller than 0"))
+		}
+
+		if domain.Weight != nil && *domain.Weight < 0 {
+			allErrs = append(allErrs, field.Invalid(fDomain.Child("weight"), *domain.Weight, "weight should not be smaller than 0"))
+		}
+
+		if domain.PodTemplatePatch != nil {
+			if err := validatePodTemplatePatch(template, domain.PodTemplatePatch.Raw); err != nil {
+				allErrs = append(allErrs, field.Invalid(fDomain.Child("podTemplatePatch"), string(domain.PodTemplatePatch.Raw),
+					fmt.Sprintf("should be a strategic merge patch of Pod template: %s", err)))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validatePodTemplatePatch(template *corev1.PodTemplateSpec, patch []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(patch, &raw); err != nil {
+		return err
+	}
+
+	templateBytes, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch(templateBytes, patch, &corev1.PodTemplateSpec{})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(patched, &corev1.PodTemplateSpec{})
+}
+
+func validateNonNegativeIntOrPercent(value *intstr.IntOrString, fPath *field.Path) field.ErrorList {
+	scaled, err := intstr.GetScaledValueFromIntOrPercent(value, 100, true)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fPath, value.String(), fmt.Sprintf("should be an integer or a percentage: %s", err))}
+	}
+
+	if scaled < 0 {
+		return field.ErrorList{field.Invalid(fPath, value.String(), "should not be smaller than 0")}
+	}
+
+	return nil
+}
+
+func (h *ValidatingHandler) validateVolumeClaimTemplates(cls *appsv1alpha1.CollaSet, fSpec *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := sets.String{}
+	for i, pvcTmp := range cls.Spec.VolumeClaimTemplates {
+		fName := fSpec.Child("volumeClaimTemplates").Index(i).Child("metadata", "name")
+		if pvcTmp.Name == "" {
+			allErrs = append(allErrs, field.Required(fName, "name of volumeClaimTemplate is required"))
+			continue
+		}
+
+		if names.Has(pvcTmp.Name) {
+			allErrs = append(allErrs, field.Duplicate(fName, pvcTmp.Name))
+		}
+		names.Insert(pvcTmp.Name)
+	}
+
 	return allErrs
 }
 
@@ -149,13 +527,55 @@ func (h *ValidatingHandler) validateReplicas(cls *appsv1alpha1.CollaSet, fSpec *
 }
 
 func (h *ValidatingHandler) validatePodTemplateSpec(cls *appsv1alpha1.CollaSet, fSpec *field.Path) field.ErrorList {
-	allErrs := field.ErrorList{}
+	allErrs := validatePodTemplateVariables(&cls.Spec.Template, fSpec.Child("template"))
+
+	// the template variables in label values are resolved when Pods are created, so the labels are validated
+	// with the variables resolved to a sample value
+	template := cls.Spec.Template.DeepCopy()
+	for k, v := range template.Labels {
+		template.Labels[k] = collasetutils.ReplacePodTemplateVariables(v, func(string) string { return "0" })
+	}
+
 	podTemplateSpec := &core.PodTemplateSpec{}
-	if err := k8scorev1.Convert_v1_PodTemplateSpec_To_core_PodTemplateSpec(cls.Spec.Template.DeepCopy(), podTemplateSpec, nil); err != nil {
+	if err := k8scorev1.Convert_v1_PodTemplateSpec_To_core_PodTemplateSpec(template, podTemplateSpec, nil); err != nil {
 		return append(allErrs, field.Invalid(fSpec.Child("template"), cls.Spec.Template, fmt.Sprintf("fail to convert to core PodTemplateSpec: %s", err)))
 	}
 
-	return corevalidation.ValidatePodTemplateSpec(podTemplateSpec, fSpec, utils.PodValidationOptions)
+	return append(allErrs, corevalidation.ValidatePodTemplateSpec(podTemplateSpec, fSpec, utils.PodValidationOptions)...)
+}
+
+// validatePodTemplateVariables checks the template variables in env values, label values and annotations do not
+// refer to the context data keys reserved by CollaSet.
+func validatePodTemplateVariables(template *corev1.PodTemplateSpec, fPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	validate := func(value string, fValue *field.Path) {
+		collasetutils.ReplacePodTemplateVariables(value, func(key string) string {
+			if collasetutils.ReservedContextDataKeys.Has(key) {
+				allErrs = append(allErrs, field.Invalid(fValue, value, fmt.Sprintf("template variable key %s is reserved", key)))
+			}
+			return ""
+		})
+	}
+
+	fMeta := fPath.Child("metadata")
+	for k, v := range template.Labels {
+		validate(v, fMeta.Child("labels").Key(k))
+	}
+	for k, v := range template.Annotations {
+		validate(v, fMeta.Child("annotations").Key(k))
+	}
+
+	validateEnv := func(containers []corev1.Container, fContainers *field.Path) {
+		for i := range containers {
+			for j, env := range containers[i].Env {
+				validate(env.Value, fContainers.Index(i).Child("env").Index(j).Child("value"))
+			}
+		}
+	}
+	validateEnv(template.Spec.InitContainers, fPath.Child("spec", "initContainers"))
+	validateEnv(template.Spec.Containers, fPath.Child("spec", "containers"))
+
+	return allErrs
 }
 
 func (h *ValidatingHandler) validateSelector(cls *appsv1alpha1.CollaSet, fSpec *field.Path) field.ErrorList {
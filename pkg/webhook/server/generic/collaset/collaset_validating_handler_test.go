@@ -22,6 +22,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	appsv1alpha1 "kusionstack.io/operating/apis/apps/v1alpha1"
 )
 
@@ -63,6 +65,84 @@ func TestValidatingCollaSet(t *testing.T) {
 				},
 			},
 		},
+		{
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(3),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					Topology: &appsv1alpha1.CollaSetTopology{
+						TopologyKey: "topology.kubernetes.io/zone",
+						Domains: []appsv1alpha1.CollaSetTopologyDomain{
+							{Name: "zone-a", Values: []string{"a"}},
+							{Name: "zone-b", Values: []string{"b"}, PodTemplatePatch: &runtime.RawExtension{
+								Raw: []byte(`{"spec":{"containers":[{"name":"foo","image":"mirror/image:v1"}]}}`),
+							}},
+						},
+					},
+				},
+			},
+		},
+		{
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app":   "foo",
+								"shard": "shard-${context.shard}",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+									Env: []corev1.EnvVar{
+										{Name: "INSTANCE", Value: "${context.ID}"},
+									},
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						InstanceData: []appsv1alpha1.InstanceIDData{
+							{ID: 0, Data: map[string]string{"shard": "0"}},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	validatingHandler := NewValidatingHandler()
@@ -331,13 +411,14 @@ func TestValidatingCollaSet(t *testing.T) {
 				},
 			},
 		},
-		"context-change-forbidden": {
-			messageKeyWords: "scaleStrategy.context is not allowed to be changed",
+		"delete-and-exclude-pod": {
+			messageKeyWords: "pod should not be deleted and excluded at the same time",
 			cls: &appsv1alpha1.CollaSet{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: "foo",
 				},
 				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
 					Selector: &metav1.LabelSelector{
 						MatchLabels: map[string]string{
 							"app": "foo",
@@ -359,15 +440,20 @@ func TestValidatingCollaSet(t *testing.T) {
 						},
 					},
 					ScaleStrategy: appsv1alpha1.ScaleStrategy{
-						Context: "context",
+						PodToExclude: []string{"foo-abcde"},
+						PodToDelete:  []string{"foo-abcde"},
 					},
 				},
 			},
-			old: &appsv1alpha1.CollaSet{
+		},
+		"batches-with-partition": {
+			messageKeyWords: "batches is not allowed to be used together with byPartition or byLabel",
+			cls: &appsv1alpha1.CollaSet{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: "foo",
 				},
 				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
 					Selector: &metav1.LabelSelector{
 						MatchLabels: map[string]string{
 							"app": "foo",
@@ -388,26 +474,1044 @@ func TestValidatingCollaSet(t *testing.T) {
 							},
 						},
 					},
-					ScaleStrategy: appsv1alpha1.ScaleStrategy{
-						Context: "context-old",
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+							ByPartition: &appsv1alpha1.ByPartition{},
+							Batches: []appsv1alpha1.RollingUpdateBatch{
+								{Replicas: intstr.FromInt(1)},
+							},
+						},
 					},
 				},
 			},
 		},
-	}
-
-	for key, tc := range failureCases {
-		mutatingHandler.setDetaultCollaSet(tc.cls)
-		err := validatingHandler.validate(tc.cls, tc.old)
-		if err == nil {
-			t.Fatalf("expected err, got nil in case %s", key)
-		}
-		if !strings.Contains(err.Error(), tc.messageKeyWords) {
-			t.Fatalf("can not find message key words [%s] in case %s, got %s", tc.messageKeyWords, key, err)
-		}
-	}
-}
-
-func int32Pointer(val int32) *int32 {
+		"invalid-batch-pause-seconds": {
+			messageKeyWords: "pauseSeconds should not be smaller than 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+							Batches: []appsv1alpha1.RollingUpdateBatch{
+								{Replicas: intstr.FromString("20%"), PauseSeconds: int32Pointer(-1)},
+							},
+						},
+					},
+				},
+			},
+		},
+		"invalid-min-ready-seconds": {
+			messageKeyWords: "minReadySeconds should not be smaller than 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas:        int32Pointer(1),
+					MinReadySeconds: -1,
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"invalid-topology-key": {
+			messageKeyWords: "topologyKey should not be empty",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					Topology: &appsv1alpha1.CollaSetTopology{
+						Domains: []appsv1alpha1.CollaSetTopologyDomain{
+							{Name: "zone-a", Values: []string{"a"}},
+						},
+					},
+				},
+			},
+		},
+		"duplicated-topology-domain": {
+			messageKeyWords: "Duplicate value: \"zone-a\"",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					Topology: &appsv1alpha1.CollaSetTopology{
+						TopologyKey: "topology.kubernetes.io/zone",
+						Domains: []appsv1alpha1.CollaSetTopologyDomain{
+							{Name: "zone-a", Values: []string{"a"}},
+							{Name: "zone-a", Values: []string{"b"}},
+						},
+					},
+				},
+			},
+		},
+		"invalid-topology-domain-weight": {
+			messageKeyWords: "weight should not be smaller than 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					Topology: &appsv1alpha1.CollaSetTopology{
+						TopologyKey: "topology.kubernetes.io/zone",
+						Domains: []appsv1alpha1.CollaSetTopologyDomain{
+							{Name: "zone-a", Values: []string{"a"}, Weight: int32Pointer(-1)},
+						},
+					},
+				},
+			},
+		},
+		"invalid-topology-domain-patch": {
+			messageKeyWords: "should be a strategic merge patch of Pod template",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					Topology: &appsv1alpha1.CollaSetTopology{
+						TopologyKey: "topology.kubernetes.io/zone",
+						Domains: []appsv1alpha1.CollaSetTopologyDomain{
+							{Name: "zone-a", Values: []string{"a"}, PodTemplatePatch: &runtime.RawExtension{Raw: []byte(`["foo"]`)}},
+						},
+					},
+				},
+			},
+		},
+		"invalid-progress-deadline-seconds": {
+			messageKeyWords: "progressDeadlineSeconds should not be smaller than 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						ProgressDeadlineSeconds: int32Pointer(-1),
+					},
+				},
+			},
+		},
+		"invalid-failure-threshold": {
+			messageKeyWords: "failureThreshold",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						FailurePolicy: &appsv1alpha1.UpdateFailurePolicy{
+							FailureThreshold: &intstr.IntOrString{Type: intstr.String, StrVal: "abc"},
+							Rollback:         true,
+						},
+					},
+				},
+			},
+		},
+		"invalid-scale-in-policy": {
+			messageKeyWords: "Unsupported value: \"Random\"",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						ScaleInPolicies: []appsv1alpha1.ScaleInPolicyType{
+							appsv1alpha1.NotReadyScaleInPolicyType,
+							"Random",
+						},
+					},
+				},
+			},
+		},
+		"invalid-max-concurrency": {
+			messageKeyWords: "maxConcurrency should be larger than 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						MaxConcurrency: int32Pointer(0),
+					},
+				},
+			},
+		},
+		"id-released-and-adopted": {
+			messageKeyWords: "id should not be released and adopted at the same time",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						IDToRelease: []int{1},
+						IDToAdopt:   []int{1},
+					},
+				},
+			},
+		},
+		"invalid-id-range": {
+			messageKeyWords: "maxID should not be smaller than minID",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						IDAllocationStrategy: &appsv1alpha1.IDAllocationStrategy{
+							MinID: int32Pointer(5),
+							MaxID: int32Pointer(3),
+						},
+					},
+				},
+			},
+		},
+		"invalid-pod-management-policy": {
+			messageKeyWords: "Unsupported value: \"Random\"",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas:            int32Pointer(1),
+					PodManagementPolicy: "Random",
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"invalid-pod-naming-policy": {
+			messageKeyWords: "Unsupported value: \"Random\"",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						PodNamingPolicy: "Random",
+					},
+				},
+			},
+		},
+		"duplicated-scale-in-policy": {
+			messageKeyWords: "Duplicate value: \"NotReadyFirst\"",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						ScaleInPolicies: []appsv1alpha1.ScaleInPolicyType{
+							appsv1alpha1.NotReadyScaleInPolicyType,
+							appsv1alpha1.NotReadyScaleInPolicyType,
+						},
+					},
+				},
+			},
+		},
+		"context-change-forbidden": {
+			messageKeyWords: "scaleStrategy.context is not allowed to be changed",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						Context: "context",
+					},
+				},
+			},
+			old: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						Context: "context-old",
+					},
+				},
+			},
+		},
+		"duplicated-volume-claim-template": {
+			messageKeyWords: "Duplicate value: \"data\"",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "data",
+							},
+						},
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "data",
+							},
+						},
+					},
+				},
+			},
+		},
+		"invalid-pvc-retention-policy": {
+			messageKeyWords: "supported values: \"Retain\", \"Delete\"",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						PersistentVolumeClaimRetentionPolicy: &appsv1alpha1.PersistentVolumeClaimRetentionPolicy{
+							WhenScaled: "Recycle",
+						},
+					},
+				},
+			},
+		},
+		"include-and-exclude-same-pod": {
+			messageKeyWords: "pod should not be included and excluded at the same time",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						PodToExclude: []string{"foo-0"},
+						PodToInclude: []string{"foo-0"},
+					},
+				},
+			},
+		},
+		"exclude-pod-with-volume-claim-templates": {
+			messageKeyWords: "podToExclude is not supported for CollaSet with volumeClaimTemplates",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						PodToExclude: []string{"foo-0"},
+					},
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "data",
+							},
+						},
+					},
+				},
+			},
+		},
+		"invalid-max-surge": {
+			messageKeyWords: "should not be smaller than 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+							MaxSurge: intOrStrPointer(intstr.FromInt(-1)),
+						},
+					},
+				},
+			},
+		},
+		"malformed-max-surge": {
+			messageKeyWords: "should be an integer or a percentage",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+							MaxSurge: intOrStrPointer(intstr.FromString("abc")),
+						},
+					},
+				},
+			},
+		},
+		"invalid-max-unavailable": {
+			messageKeyWords: "maxUnavailable: Invalid value",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						RollingUpdate: &appsv1alpha1.RollingUpdateCollaSetStrategy{
+							MaxUnavailable: intOrStrPointer(intstr.FromString("-10%")),
+						},
+					},
+				},
+			},
+		},
+		"invalid-rollback-revision": {
+			messageKeyWords: "revision number should be greater than 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					UpdateStrategy: appsv1alpha1.UpdateStrategy{
+						RollbackTo: &appsv1alpha1.RollbackConfig{
+							Revision: intstr.FromInt(0),
+						},
+					},
+				},
+			},
+		},
+		"reserved-template-variable": {
+			messageKeyWords: "template variable key Owner is reserved",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+									Env: []corev1.EnvVar{
+										{Name: "OWNER", Value: "${context.Owner}"},
+									},
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						InstanceData: []appsv1alpha1.InstanceIDData{
+							{ID: 0, Data: map[string]string{"shard": "0"}},
+						},
+					},
+				},
+			},
+		},
+		"reserved-instance-data-key": {
+			messageKeyWords: "key is reserved",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						InstanceData: []appsv1alpha1.InstanceIDData{
+							{ID: 0, Data: map[string]string{"Revision": "foo-1"}},
+						},
+					},
+				},
+			},
+		},
+		"duplicate-instance-data-id": {
+			messageKeyWords: "Duplicate value: 0",
+			cls: &appsv1alpha1.CollaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Spec: appsv1alpha1.CollaSetSpec{
+					Replicas: int32Pointer(1),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"app": "foo",
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"app": "foo",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "foo",
+									Image: "image:v1",
+								},
+							},
+						},
+					},
+					ScaleStrategy: appsv1alpha1.ScaleStrategy{
+						InstanceData: []appsv1alpha1.InstanceIDData{
+							{ID: 0, Data: map[string]string{"shard": "0"}},
+							{ID: 0, Data: map[string]string{"shard": "1"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for key, tc := range failureCases {
+		mutatingHandler.setDetaultCollaSet(tc.cls)
+		err := validatingHandler.validate(tc.cls, tc.old)
+		if err == nil {
+			t.Fatalf("expected err, got nil in case %s", key)
+		}
+		if !strings.Contains(err.Error(), tc.messageKeyWords) {
+			t.Fatalf("can not find message key words [%s] in case %s, got %s", tc.messageKeyWords, key, err)
+		}
+	}
+}
+
+func int32Pointer(val int32) *int32 {
+	return &val
+}
+
+func intOrStrPointer(val intstr.IntOrString) *intstr.IntOrString {
 	return &val
 }